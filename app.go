@@ -0,0 +1,941 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// postTimeout is the timeout of posting reports (e.g. to -slack-webhook)
+const postTimeout = 30 * time.Second
+
+// App is the go2xunit command line application
+type App struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	log    *log.Logger
+
+	progressInterval time.Duration
+	watchInterval    time.Duration
+	// stop ends -watch (nil to watch until killed)
+	stop <-chan struct{}
+	// interrupt stops reading input, the report of the tests read so far is
+	// written (nil to read all input)
+	interrupt <-chan struct{}
+	// lister lists the packages of -import-graph
+	lister packageLister
+}
+
+// Option is an App option
+type Option func(*App)
+
+// WithIO sets the standard input, output and error of the App
+func WithIO(in io.Reader, out, err io.Writer) Option {
+	return func(app *App) {
+		app.stdin, app.stdout, app.stderr = in, out, err
+	}
+}
+
+// WithInterrupt sets a channel that is closed to stop reading input, e.g. on
+// SIGTERM. The tests read so far are reported, marked incomplete, and Run
+// returns exitInterrupted.
+func WithInterrupt(interrupt <-chan struct{}) Option {
+	return func(app *App) {
+		app.interrupt = interrupt
+	}
+}
+
+// NewApp returns a new App, by default it uses os.Stdin, os.Stdout and
+// os.Stderr
+func NewApp(options ...Option) *App {
+	app := &App{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+
+		progressInterval: lib.DefaultProgressInterval,
+		watchInterval:    500 * time.Millisecond,
+		lister:           goList{},
+	}
+	for _, option := range options {
+		option(app)
+	}
+	// No time ... prefix for error messages
+	app.log = log.New(app.stderr, "", 0)
+	return app
+}
+
+// Run runs the application with command line arguments (without the program
+// name) and returns the exit code
+func (app *App) Run(argv []string) int {
+	lib.Options.Warnf = func(format string, args ...interface{}) {
+		app.log.Printf("warning: "+format, args...)
+	}
+	defer func() { lib.Options.Warnf = nil }()
+
+	if len(argv) > 0 && argv[0] == "merge" {
+		return app.runMerge(argv[1:])
+	}
+
+	var args cmdArgs
+	flags := newFlagSet(&args, app.stderr)
+	if err := flags.Parse(argv); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitError
+	}
+
+	if args.showVersion {
+		fmt.Fprintf(app.stdout, "go2xunit %s\n", Version)
+		return exitOK
+	}
+
+	if err := applyEnv(flags, os.LookupEnv); err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+	if err := loadConfig(&args, flags); err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+	if err := validateArgs(&args, flags); err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+
+	if args.validate {
+		return app.validate(&args)
+	}
+	if args.serve != "" {
+		return app.serve(&args)
+	}
+	if args.watch {
+		return app.watch(&args)
+	}
+
+	code, err := app.run(&args)
+	if err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+	return code
+}
+
+// getInput return input reader of -input, if it's - it will return the App
+// standard input and if it's an HTTP(S) URL the response body
+func (app *App) getInput(args *cmdArgs) (io.Reader, error) {
+	filename := args.inFile
+	if filename == "-" || filename == "" {
+		return app.stdin, nil
+	}
+	if isURL(filename) {
+		return fetchInput(filename, args.headers, args.fetchTimeout)
+	}
+
+	return os.Open(filename)
+}
+
+// getOutput return output writer from file name, if file name is - it will
+// return the App standard output. Files are written atomically, the output
+// should be committed with commitOutput once it's complete.
+func (app *App) getOutput(filename string) (io.Writer, error) {
+	if filename == "-" || filename == "" {
+		return app.stdout, nil
+	}
+
+	return createAtomic(filename)
+}
+
+// postJSON posts a JSON body to url, responses other than 2xx are errors
+func postJSON(url string, body []byte) error {
+	client := &http.Client{Timeout: postTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can't post to %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post to %s: %s %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// getIO returns the input stream of args and output stream of outFile
+func (app *App) getIO(args *cmdArgs, outFile string) (io.Reader, io.Writer, error) {
+	input, err := app.getInput(args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open %s for reading: %s", args.inFile, err)
+	}
+
+	output, err := app.getOutput(outFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open %s for writing: %s", outFile, err)
+	}
+
+	return input, output, nil
+}
+
+// inputTime returns the modification time of input if it's a file, otherwise
+// the current time
+func inputTime(input io.Reader) time.Time {
+	if file, ok := input.(*os.File); ok {
+		if stat, err := file.Stat(); err == nil {
+			return stat.ModTime()
+		}
+	}
+	return time.Now()
+}
+
+// isTerminal returns true if w is a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// color returns true if -format pretty output to w is colored: with -color
+// auto if w is stdout, stdout is a terminal and NO_COLOR is not set
+func (app *App) color(args *cmdArgs, w io.Writer) bool {
+	switch args.color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return w == app.stdout && isTerminal(app.stdout) && os.Getenv("NO_COLOR") == ""
+}
+
+// printShuffleSeeds prints the "go test -shuffle" seed of shuffled suites to
+// stderr
+func (app *App) printShuffleSeeds(suites lib.Suites) {
+	for _, suite := range suites {
+		if suite.ShuffleSeed != "" {
+			fmt.Fprintf(app.stderr, "shuffle seed: %s %s\n", suite.Name, suite.ShuffleSeed)
+		}
+	}
+}
+
+// reportFlaky prints flaky tests to stderr
+func (app *App) reportFlaky(suites lib.Suites) {
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.Flaky {
+				fmt.Fprintf(app.stderr, "flaky: %s\n", test.Name)
+			}
+		}
+	}
+}
+
+// printProgress prints parsing progress to stderr, on a terminal the line is
+// updated in place until final is set
+func (app *App) printProgress(stats lib.ProgressStats, final, terminal bool) {
+	line := fmt.Sprintf("go2xunit: parsed %d tests (%d failed) in %d packages, %d lines, %d bytes",
+		stats.Tests, stats.Failures, stats.Packages, stats.Lines, stats.Bytes)
+	switch {
+	case terminal && final:
+		fmt.Fprintf(app.stderr, "\r%s\x1b[K\n", line)
+	case terminal:
+		fmt.Fprintf(app.stderr, "\r%s...\x1b[K", line)
+	case final:
+		fmt.Fprintln(app.stderr, line)
+	default:
+		fmt.Fprintf(app.stderr, "%s...\n", line)
+	}
+}
+
+// parse parses input, printing progress to stderr if -progress is set and
+// stderr is a terminal (or -progress=force). Only input stops after
+// -max-failures, not the baseline or previous runs.
+func (app *App) parse(parse lib.ParseFunc, input io.Reader, args *cmdArgs) (lib.Suites, error) {
+	lib.Options.MaxFailures = args.maxFailures
+	defer func() { lib.Options.MaxFailures = -1 }()
+
+	terminal := isTerminal(app.stderr)
+	if args.progress == "" || (args.progress != progressForce && !terminal) {
+		return parse(input, args.suitePrefix)
+	}
+
+	progress := &lib.Progress{Interval: app.progressInterval}
+	progress.Notify = func(stats lib.ProgressStats) {
+		app.printProgress(stats, false, terminal)
+	}
+	opts := lib.ParseOptions{Progress: progress}
+	suites, err := lib.ParseContext(context.Background(), parse, input, args.suitePrefix, opts)
+	app.printProgress(progress.Stats(), true, terminal)
+	return suites, err
+}
+
+// validate checks the "go test -json" input and prints diagnostics to stdout,
+// the exit code is exitFailures if the input is not well formed
+func (app *App) validate(args *cmdArgs) int {
+	input, err := app.getInput(args)
+	if err != nil {
+		app.log.Printf("error: can't open %s for reading: %s", args.inFile, err)
+		return exitError
+	}
+	if closer, ok := input.(io.Closer); ok && input != app.stdin {
+		defer closer.Close()
+	}
+
+	v, err := lib.ValidateTest2JSON(input)
+	if err == nil {
+		err = lib.WriteValidation(app.stdout, v)
+	}
+	if err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+	if !v.OK() {
+		return exitFailures
+	}
+	return exitOK
+}
+
+// watch converts the input file whenever it changes (polling its size and
+// modification time), until app.stop is closed
+func (app *App) watch(args *cmdArgs) int {
+	ticker := time.NewTicker(app.watchInterval)
+	defer ticker.Stop()
+
+	var last os.FileInfo
+	for {
+		info, err := os.Stat(args.inFile)
+		switch {
+		case err != nil:
+			app.log.Printf("error: %s", err)
+		case last == nil || info.Size() != last.Size() || !info.ModTime().Equal(last.ModTime()):
+			last = info
+			code, err := app.run(args)
+			if err != nil {
+				app.log.Printf("error: %s", err)
+			} else {
+				app.log.Printf("%s converted (exit code %d)", args.inFile, code)
+			}
+		}
+
+		select {
+		case <-app.stop:
+			return exitOK
+		case <-app.interrupt:
+			return exitInterrupted
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadConfig sets flags that are not on the command line from args.configFile,
+// or from lib.ConfigFile if it exists
+func loadConfig(args *cmdArgs, flags *flag.FlagSet) error {
+	name := args.configFile
+	if name == "" {
+		if _, err := os.Stat(lib.ConfigFile); err != nil {
+			return nil
+		}
+		name = lib.ConfigFile
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("can't open %s for reading: %s", name, err)
+	}
+	defer file.Close()
+
+	config, err := lib.ReadConfig(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	if err := applyConfig(flags, config); err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	return nil
+}
+
+// writeReport writes the XML report of suites to w, in the format selected
+// by args
+func writeReport(w io.Writer, args *cmdArgs, suites lib.Suites, testTime time.Time) error {
+	xmlTemplate := lib.XUnitTemplate
+	if args.xunitnetOut {
+		xmlTemplate = lib.XUnitNetTemplate
+	} else if args.bambooOut || args.versionInfo || (len(suites) > 1) {
+		xmlTemplate = lib.XMLMultiTemplate
+	}
+
+	if args.tags != nil {
+		var buf bytes.Buffer
+		if err := lib.WriteTaggedXML(&buf, lib.GroupByTag(suites, args.tags), testTime); err != nil {
+			return err
+		}
+		data := buf.Bytes()
+		if args.indent != "" || args.compact {
+			var err error
+			if data, err = lib.Reindent(data, args.indent, args.compact); err != nil {
+				return err
+			}
+		}
+		_, err := w.Write(data)
+		return err
+	}
+
+	if args.indent == "" && !args.compact {
+		return lib.WriteXMLTemplate(w, suites, xmlTemplate, testTime)
+	}
+
+	var buf bytes.Buffer
+	if err := lib.WriteXMLTemplate(&buf, suites, xmlTemplate, testTime); err != nil {
+		return err
+	}
+	data, err := lib.Reindent(buf.Bytes(), args.indent, args.compact)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readTagMap reads the -tag-map file
+func readTagMap(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't open %s for reading: %s", filename, err)
+	}
+	defer file.Close()
+
+	tags, err := lib.ReadTagMap(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return tags, nil
+}
+
+// applySkipPolicy reports skipped tests that are not in -skip-allow as failed
+// (-fail-on-skip) or disabled (-skip-as-disabled)
+func applySkipPolicy(args *cmdArgs, suites lib.Suites) error {
+	if !args.failOnSkip && !args.skipAsDisabled {
+		return nil
+	}
+
+	var allow []string
+	if args.skipAllow != "" {
+		file, err := os.Open(args.skipAllow)
+		if err != nil {
+			return fmt.Errorf("can't open %s for reading: %s", args.skipAllow, err)
+		}
+		defer file.Close()
+		if allow, err = lib.ReadSkipAllowList(file); err != nil {
+			return fmt.Errorf("%s: %s", args.skipAllow, err)
+		}
+	}
+
+	if args.failOnSkip {
+		lib.FailSkipped(suites, allow)
+	} else {
+		lib.DisableSkipped(suites, allow)
+	}
+	return nil
+}
+
+// setOptions sets the lib.Options of args
+func setOptions(args *cmdArgs) {
+	lib.Options.EscapeOutput = !args.cdata
+	lib.Options.Properties = nil
+	lib.Options.Location = args.location
+	if lib.Options.Location == nil {
+		lib.Options.Location = time.UTC
+	}
+	if args.versionInfo {
+		lib.Options.Properties = []lib.Property{
+			{Name: "go2xunit.version", Value: Version},
+			{Name: "go.version", Value: runtime.Version()},
+			{Name: "generated-at", Value: time.Now().In(lib.Options.Location).Format(time.RFC3339)},
+		}
+	}
+}
+
+// run converts the input according to args, it returns the exit code or an
+// error
+func (app *App) run(args *cmdArgs) (code int, err error) {
+	setOptions(args)
+
+	outFile := args.outFile
+	if args.sharded() {
+		// Report is written to numbered files named after outFile
+		outFile = ""
+	}
+	if args.tagMap != "" {
+		if args.tags, err = readTagMap(args.tagMap); err != nil {
+			return exitError, err
+		}
+	}
+	var webhookTemplate *template.Template
+	if args.webhookTemplate != "" {
+		if webhookTemplate, err = readWebhookTemplate(args.webhookTemplate); err != nil {
+			return exitError, err
+		}
+	}
+
+	input, output, err := app.getIO(args, outFile)
+	if err != nil {
+		return exitError, err
+	}
+	if closer, ok := input.(io.Closer); ok && input != app.stdin {
+		defer closer.Close()
+	}
+	if closer, ok := output.(io.Closer); ok && output != app.stdout {
+		defer closer.Close()
+	}
+	if args.gzipped() {
+		if output, err = newGzipOutput(output, args.gzipLevel); err != nil {
+			return exitError, err
+		}
+	}
+	if args.uploadURL != "" {
+		upload, err := newUploadOutput(output, args.uploadURL, args.uploadToken)
+		if err != nil {
+			return exitError, err
+		}
+		defer upload.Close()
+		output = upload
+	}
+
+	// We'd like the test time to be the time of the generated file
+	testTime := inputTime(input).In(lib.Options.Location)
+
+	parse := args.parseFunc()
+	inputParse, input := args.inputParseFunc(input)
+
+	var interrupted *interruptReader
+	if app.interrupt != nil {
+		interrupted = newInterruptReader(input, app.interrupt)
+		input = interrupted
+	}
+
+	var pretty *lib.PrettyWriter
+	if args.format == "pretty" {
+		pretty = lib.NewPrettyWriter(output, app.color(args, output))
+		if args.stream {
+			lib.Options.OnTestEnd = pretty.Test
+			lib.Options.OnSuiteEnd = pretty.Suite
+		}
+	}
+
+	suites, err := app.parse(inputParse, input, args)
+	lib.Options.OnTestEnd, lib.Options.OnSuiteEnd = nil, nil
+	if err != nil {
+		return exitError, err
+	}
+	if len(suites) == 0 {
+		return exitError, fmt.Errorf("no tests found")
+	}
+
+	// historySummary is printed after the summary line
+	var historySummary string
+	if !args.quiet {
+		defer func() {
+			if err == nil {
+				err = lib.WriteSummaryLine(app.stderr, suites, args.summaryFailures)
+			}
+			if err == nil && historySummary != "" {
+				_, err = fmt.Fprintln(app.stderr, historySummary)
+			}
+		}()
+	}
+
+	if args.webhook != "" {
+		// Posted after the report is written
+		defer func() {
+			if err != nil {
+				return
+			}
+			if werr := postWebhook(suites, args, webhookTemplate); werr != nil {
+				if args.webhookRequired {
+					code, err = exitError, werr
+					return
+				}
+				app.log.Printf("warning: %s", werr)
+			}
+		}()
+	}
+
+	if interrupted != nil && interrupted.Interrupted() {
+		app.log.Printf("warning: interrupted, writing a partial report")
+		for _, suite := range suites {
+			suite.Properties = append(suite.Properties, lib.Property{Name: IncompleteProperty, Value: "true"})
+		}
+		defer func() {
+			if err == nil {
+				code = exitInterrupted
+			}
+		}()
+	}
+
+	if suites, err = app.process(args, parse, suites); err != nil {
+		return exitError, err
+	}
+
+	if args.summary {
+		if err := lib.WriteSummary(app.stderr, suites, isTerminal(app.stderr)); err != nil {
+			return exitError, err
+		}
+	}
+	if args.histogram {
+		if err := lib.WriteHistogram(app.stderr, suites, lib.DefaultDurationBuckets); err != nil {
+			return exitError, err
+		}
+	}
+
+	if args.slow > 0 {
+		slowest := lib.Slowest(suites, args.slow)
+		if args.topRollup {
+			slowest = lib.SlowestRollup(suites, args.slow)
+		}
+		if err := lib.WriteSlowest(app.stderr, suites, slowest, args.slowFormat == "json"); err != nil {
+			return exitError, err
+		}
+		lib.AddSlowestProperties(suites, slowest)
+	}
+
+	tooSlow := false
+	if args.maxTestTime > 0 {
+		for _, test := range lib.ExceedingTime(suites, args.maxTestTime) {
+			app.log.Printf("%s took %s (max %s)", test.Name, test.Elapsed(), args.maxTestTime)
+			tooSlow = true
+		}
+	}
+	if args.maxSuiteTime > 0 {
+		for _, suite := range lib.SuitesExceedingTime(suites, args.maxSuiteTime) {
+			app.log.Printf("%s took %s (max %s)", suite.Name, suite.Elapsed(), args.maxSuiteTime)
+			tooSlow = true
+		}
+	}
+
+	if args.metricsFile != "" {
+		var buf bytes.Buffer
+		if err := lib.WritePrometheus(&buf, suites, testTime, args.props, args.metricsPerTest); err != nil {
+			return exitError, err
+		}
+		if err := writeFileAtomic(args.metricsFile, buf.Bytes()); err != nil {
+			return exitError, err
+		}
+	}
+	if args.importGraph != "" {
+		if err := app.writeImportGraph(args, suites); err != nil {
+			return exitError, err
+		}
+	}
+	if args.history != "" {
+		if historySummary, err = app.updateHistory(args, suites, testTime); err != nil {
+			return exitError, err
+		}
+	}
+
+	var baseline lib.Suites
+	if args.baseline != "" {
+		if baseline, err = parseFile(parse, args.baseline, args.suitePrefix); err != nil {
+			return exitError, err
+		}
+	}
+
+	var cmp *lib.BaselineComparison
+	if baseline != nil {
+		cmp = lib.CompareBaseline(baseline, suites)
+		cmp.AddProperties(suites)
+		if err := lib.WriteBaseline(app.stderr, cmp); err != nil {
+			return exitError, err
+		}
+		regressions := lib.CompareRuns(baseline, suites, args.regressionThreshold)
+		if err := lib.WriteRegressions(app.stderr, regressions); err != nil {
+			return exitError, err
+		}
+	}
+
+	if write, ok := suitesWriters[args.format]; ok {
+		if err := write(output, args, suites, baseline, testTime); err != nil {
+			return exitError, err
+		}
+		if err := commitOutput(output); err != nil {
+			return exitError, err
+		}
+	} else if err := app.writeTestReport(output, args, suites, testTime, pretty); err != nil {
+		return exitError, err
+	}
+
+	if code := app.failuresExitCode(args, suites, cmp); code != exitOK {
+		return code, nil
+	}
+	if tooSlow {
+		return exitTooSlow, nil
+	}
+	return exitOK, nil
+}
+
+// process applies the options of args to the parsed suites (adding -property,
+// -filter, -rerun-policy, -skip-policy, -collapse-subtests ...), it's the part
+// of run shared with -serve
+func (app *App) process(args *cmdArgs, parse lib.ParseFunc, suites lib.Suites) (lib.Suites, error) {
+	for _, suite := range suites {
+		suite.Properties = append(suite.Properties, args.props...)
+	}
+
+	if args.normalizeTS {
+		lib.NormalizeTimestamps(suites)
+	}
+	if n := suites.NumIncomplete(); n > 0 {
+		app.log.Printf("warning: %d test(s) with no result recorded", n)
+	}
+	if n := suites.NumOutputTruncated(); n > 0 {
+		app.log.Printf("warning: output of %d test(s) truncated", n)
+	}
+	if suites.StoppedEarly() {
+		app.log.Printf("stopped after more than %d failed test(s)", args.maxFailures)
+	}
+
+	if !args.filter.IsEmpty() {
+		var removed int
+		suites, removed = args.filter.Apply(suites)
+		app.log.Printf("filtered out %d test(s)", removed)
+		if len(suites) == 0 {
+			return nil, fmt.Errorf("no tests left after filtering")
+		}
+	}
+
+	if err := lib.ApplyRerunPolicy(suites, args.rerunPolicy); err != nil {
+		return nil, err
+	}
+	if args.flakyRuns != "" {
+		files := strings.Split(args.flakyRuns, ",")
+		if err := markFlaky(parse, files, args.suitePrefix, suites); err != nil {
+			return nil, err
+		}
+	}
+	if err := lib.DisambiguateRuns(suites, args.repeated); err != nil {
+		return nil, err
+	}
+	app.reportFlaky(suites)
+	if args.printShuffleSeed {
+		app.printShuffleSeeds(suites)
+	}
+	if err := applySkipPolicy(args, suites); err != nil {
+		return nil, err
+	}
+	if args.collapseSubtests {
+		suites.CollapseSubtests()
+	}
+
+	if args.statusTimes {
+		suites.AddDurationProperties()
+	}
+
+	return suites, nil
+}
+
+// suitesWriter writes a report of all the tests in suites to w, baseline is
+// the -baseline suites (nil if not set)
+type suitesWriter func(w io.Writer, args *cmdArgs, suites, baseline lib.Suites, testTime time.Time) error
+
+// suitesWriters are the writers of formats that report all the tests, other
+// formats are written by writeTestReport
+var suitesWriters = map[string]suitesWriter{
+	"diff": func(w io.Writer, args *cmdArgs, suites, baseline lib.Suites, testTime time.Time) error {
+		return lib.WriteDiff(w, lib.Diff(baseline, suites, args.regressionThreshold))
+	},
+	"bench-diff": func(w io.Writer, args *cmdArgs, suites, baseline lib.Suites, testTime time.Time) error {
+		return lib.WriteBenchmarkDiff(w, lib.BenchmarkDiff(baseline, suites), args.benchThreshold)
+	},
+	"cobertura": func(w io.Writer, args *cmdArgs, suites, baseline lib.Suites, testTime time.Time) error {
+		return lib.WriteCobertura(w, suites, testTime)
+	},
+	"lcov": func(w io.Writer, args *cmdArgs, suites, baseline lib.Suites, testTime time.Time) error {
+		return lib.WriteLCOV(w, suites)
+	},
+	"coveralls": func(w io.Writer, args *cmdArgs, suites, baseline lib.Suites, testTime time.Time) error {
+		return lib.WriteCoveralls(w, suites, args.coverallsToken)
+	},
+	"slack": writeSlack,
+}
+
+// writeSlack writes the Slack payload of suites to w, and posts it to
+// -slack-webhook if set
+func writeSlack(w io.Writer, args *cmdArgs, suites, baseline lib.Suites, testTime time.Time) error {
+	var payload bytes.Buffer
+	if err := lib.WriteSlack(&payload, suites); err != nil {
+		return err
+	}
+	if args.slackWebhook != "" {
+		if err := postJSON(args.slackWebhook, payload.Bytes()); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(payload.Bytes())
+	return err
+}
+
+// writeTestReport writes the report of suites to w (or to files, see
+// -output-dir and -max-cases-per-file) in the format selected by args, pretty
+// is the writer of -format pretty
+func (app *App) writeTestReport(w io.Writer, args *cmdArgs, suites lib.Suites, testTime time.Time, pretty *lib.PrettyWriter) error {
+	// The exit code is by all tests, -max-tests limits only the report
+	report := suites.TruncateLeaves(args.maxTests)
+	if args.appendOutput {
+		var err error
+		if report, err = appendReport(args.outFile, report); err != nil {
+			return err
+		}
+	}
+	if args.format == "xunit" && !args.rawNames {
+		report.SanitizeNames(args.maxNameLength)
+	}
+	if args.attachmentsDir != "" {
+		if err := lib.WriteAttachments(report, args.attachmentsDir, args.attachmentsAll); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch {
+	case args.outputDir != "":
+		err = writeSplit(args, report, testTime)
+	case args.sharded():
+		err = writeShards(args, report, testTime)
+	case args.format == "csv":
+		err = lib.WriteCSV(w, report, !args.csvNoHeader)
+	case args.format == "yaml":
+		err = lib.WriteYAML(w, report)
+	case args.format == "pretty" && args.stream:
+		// Tests and packages were written while parsing
+		err = pretty.Finish(report)
+	case args.format == "pretty":
+		err = lib.WritePretty(w, report, app.color(args, w))
+	default:
+		err = writeReport(w, args, report, testTime)
+	}
+	if err != nil {
+		return err
+	}
+	if err := commitOutput(w); err != nil {
+		return err
+	}
+	if args.splitByStatus != "" {
+		return writeByStatus(args.splitByStatus, args, report, testTime)
+	}
+	return nil
+}
+
+// failuresExitCode returns the exit code for failed tests in suites according
+// to args, cmp is the comparison to -baseline (nil if not set)
+func (app *App) failuresExitCode(args *cmdArgs, suites lib.Suites, cmp *lib.BaselineComparison) int {
+	if args.noExitCode {
+		return exitOK
+	}
+	if suites.StoppedEarly() {
+		return exitFailures
+	}
+	if args.failOn == "new-failures" {
+		if len(cmp.NewFailures) > 0 {
+			return exitFailures
+		}
+		return exitOK
+	}
+	if args.fail && suites.HasFailures() {
+		return exitFailures
+	}
+	if err := lib.CheckFailUnder(suites, args.failUnder); err != nil {
+		app.log.Printf("error: %s", err)
+		return exitFailures
+	}
+	return exitOK
+}
+
+// runMerge runs the "merge" sub command, which merges several reports (xunit
+// XML or test output) to one
+func (app *App) runMerge(argv []string) int {
+	var args mergeArgs
+	flags := newMergeFlagSet(&args, app.stderr)
+	if err := flags.Parse(argv); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitError
+	}
+
+	if err := app.merge(&args, flags.Args()); err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// merge merges files and writes the merged report
+func (app *App) merge(args *mergeArgs, files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("merge requires at least one input file")
+	}
+	if args.bambooOut && args.xunitnetOut {
+		return fmt.Errorf("-bamboo and -xunitnet are mutually exclusive")
+	}
+	if args.isGocheck && args.isJSON {
+		return fmt.Errorf("-gocheck and -json are mutually exclusive")
+	}
+
+	parse := lib.ParseGotest
+	switch {
+	case args.isGocheck:
+		parse = lib.ParseGocheck
+	case args.isJSON:
+		parse = lib.ParseTest2JSON
+	}
+
+	var shards []lib.Suites
+	for _, name := range files {
+		suites, err := parseFile(parse, name, args.suitePrefix)
+		if err != nil {
+			return err
+		}
+		shards = append(shards, suites)
+	}
+
+	suites, warnings := lib.MergeShards(shards)
+	for _, warning := range warnings {
+		app.log.Printf("warning: %s", warning)
+	}
+	if len(suites) == 0 {
+		return fmt.Errorf("no tests found")
+	}
+
+	output, err := app.getOutput(args.outFile)
+	if err != nil {
+		return fmt.Errorf("can't open %s for writing: %s", args.outFile, err)
+	}
+	if closer, ok := output.(io.Closer); ok && output != app.stdout {
+		defer closer.Close()
+	}
+
+	xmlTemplate := lib.XUnitTemplate
+	if args.xunitnetOut {
+		xmlTemplate = lib.XUnitNetTemplate
+	} else if args.bambooOut || (len(suites) > 1) {
+		xmlTemplate = lib.XMLMultiTemplate
+	}
+	if err := lib.WriteXMLTemplate(output, suites, xmlTemplate, time.Now().UTC()); err != nil {
+		return err
+	}
+	return commitOutput(output)
+}
@@ -0,0 +1,1705 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+func runApp(t *testing.T, input string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	app := NewApp(WithIO(strings.NewReader(input), &stdout, &stderr))
+	code := app.Run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestAppDetectJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := dataPath + "/in/test2json-gotest.out"
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, expected, stderr := runApp(t, string(data), "-json", "-format", "csv")
+	if code == exitError {
+		t.Fatalf("-json: %s", stderr)
+	}
+	for _, input := range []string{string(data), "\n  " + string(data)} {
+		code2, out, stderr := runApp(t, input, "-format", "csv")
+		if code2 != code || out != expected {
+			t.Fatalf("exit code %d (expected %d), output:\n%s\nexpected:\n%s\n%s", code2, code, out, expected, stderr)
+		}
+	}
+
+	// go test output starting with a JSON log line
+	const plain = `{"level":"info","msg":"starting"}` + "\n=== RUN   TestA\n--- PASS: TestA (0.00s)\nPASS\nok  \tpkg\t0.01s\n"
+	if code, out, stderr := runApp(t, plain, "-format", "csv"); code != exitOK || !strings.Contains(out, "pkg,TestA,pass") {
+		t.Fatalf("exit code %d, output:\n%s\n%s", code, out, stderr)
+	}
+	plainFile := filepath.Join(dir, "plain.out")
+	if err := ioutil.WriteFile(plainFile, []byte(plain), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if suites, err := parseFile(lib.ParseGotest, plainFile, ""); err != nil || suites[0].Tests[0].Name != "TestA" {
+		t.Fatalf("%s: %v", plainFile, err)
+	}
+
+	fromJSON, err := lib.ParseTest2JSON(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	suites, err := parseFile(lib.ParseGotest, name, "")
+	if err != nil || len(suites) != len(fromJSON) || suites[0].Len() != fromJSON[0].Len() {
+		t.Fatalf("%s: parsed %d suites (%v), expected %d", name, len(suites), err, len(fromJSON))
+	}
+}
+
+func TestAppPipeline(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-fail.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, _ := runApp(t, string(data))
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if !strings.Contains(out, "<testsuite ") || !strings.Contains(out, "<failure ") {
+		t.Fatalf("bad output:\n%s", out)
+	}
+
+	code, _, _ = runApp(t, string(data), "-fail")
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+
+	code, out, _ = runApp(t, string(data), "-xunitnet")
+	if code != exitFailures || !strings.Contains(out, "<assembly ") {
+		t.Fatalf("bad xunit.net output (exit code %d):\n%s", code, out)
+	}
+}
+
+func TestAppErrors(t *testing.T) {
+	code, _, stderr := runApp(t, "")
+	if code != exitError || !strings.Contains(stderr, "no tests found") {
+		t.Fatalf("empty input: exit code %d, stderr %q", code, stderr)
+	}
+
+	code, _, stderr = runApp(t, "", "-no-such-flag")
+	if code != exitError || stderr == "" {
+		t.Fatalf("bad flag: exit code %d, stderr %q", code, stderr)
+	}
+
+	code, _, stderr = runApp(t, "", "-bamboo", "-xunitnet")
+	if code != exitError || !strings.Contains(stderr, "mutually exclusive") {
+		t.Fatalf("bad args: exit code %d, stderr %q", code, stderr)
+	}
+
+	code, out, _ := runApp(t, "", "-version")
+	if code != exitOK || !strings.Contains(out, Version) {
+		t.Fatalf("version: exit code %d, output %q", code, out)
+	}
+}
+
+// slowReader returns one byte per read, with a delay
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (sr *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(sr.delay)
+	return sr.r.Read(p[:1])
+}
+
+func TestAppProgress(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-multi.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	input := &slowReader{bytes.NewReader(data), 10 * time.Microsecond}
+	app := NewApp(WithIO(input, &stdout, &stderr))
+	app.progressInterval = time.Millisecond
+	// stderr is not a terminal
+	if code := app.Run([]string{"-progress", "-quiet"}); code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr.String())
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("progress printed to a non terminal:\n%s", stderr.String())
+	}
+
+	input = &slowReader{bytes.NewReader(data), 10 * time.Microsecond}
+	app = NewApp(WithIO(input, &stdout, &stderr))
+	app.progressInterval = time.Millisecond
+	if code := app.Run([]string{"-progress=force", "-quiet"}); code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("no progress lines:\n%s", stderr.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "go2xunit: parsed ") {
+			t.Fatalf("bad progress line: %q", line)
+		}
+	}
+	last := lines[len(lines)-1]
+	if strings.HasSuffix(last, "...") {
+		t.Fatalf("no final progress line: %q", last)
+	}
+	expected := fmt.Sprintf("go2xunit: parsed 4 tests (0 failed) in 1 packages, %d lines, %d bytes",
+		bytes.Count(data, []byte("\n")), len(data))
+	if last != expected {
+		t.Fatalf("final progress line: %q, expected %q", last, expected)
+	}
+}
+
+// errWriter is a writer that always fails
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+func TestAppWriteError(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-pass.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"-indent", "  "},
+		{"merge", dataPath + "/in/gotest-pass.out"},
+	} {
+		var stderr bytes.Buffer
+		app := NewApp(WithIO(bytes.NewReader(data), errWriter{}, &stderr))
+		if code := app.Run(args); code != exitError {
+			t.Fatalf("%v: exit code %d, should be %d", args, code, exitError)
+		}
+		if !strings.Contains(stderr.String(), "disk full") {
+			t.Fatalf("%v: no write error in %q", args, stderr.String())
+		}
+	}
+}
+
+func TestAppMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// One shard as XML report and one as raw test output
+	xmlFile := filepath.Join(dir, "shard1.xml")
+	if code, _, stderr := runApp(t, "", "-input", dataPath+"/in/gotest-pass.out", "-output", xmlFile); code != exitOK {
+		t.Fatalf("can't create report (exit code %d): %s", code, stderr)
+	}
+	outFile := filepath.Join(dir, "merged.xml")
+	code, _, stderr := runApp(t, "", "merge", "-output", outFile, xmlFile, dataPath+"/in/gotest-fail.out")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	data, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if n := strings.Count(out, "<testsuite "); n != 2 {
+		t.Fatalf("%d suites in merged report:\n%s", n, out)
+	}
+	if !strings.Contains(out, "<testsuites ") || !strings.Contains(out, "<failure ") {
+		t.Fatalf("bad merged report:\n%s", out)
+	}
+
+	if code, _, _ := runApp(t, "", "merge"); code != exitError {
+		t.Fatalf("merge without files: exit code %d", code)
+	}
+
+	// "go test -json" shards
+	code, _, stderr = runApp(t, "", "merge", "-json", "-output", outFile,
+		dataPath+"/in/test2json-gotest.out", dataPath+"/in/test2json-subtests.out")
+	if code != exitOK {
+		t.Fatalf("-json: exit code %d: %s", code, stderr)
+	}
+	if data, err = ioutil.ReadFile(outFile); err != nil {
+		t.Fatal(err)
+	}
+	if out := string(data); !strings.Contains(out, `name="TestParent/b"`) || strings.Count(out, "<testsuite ") != 2 {
+		t.Fatalf("bad merged -json report:\n%s", out)
+	}
+}
+
+func TestAppBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const (
+		before = "=== RUN   TestOld\n--- FAIL: TestOld (0.00s)\n=== RUN   TestA\n--- PASS: TestA (0.00s)\nFAIL\nFAIL\tpkg\t0.01s\n"
+		broken = "=== RUN   TestOld\n--- FAIL: TestOld (0.00s)\n=== RUN   TestA\n--- FAIL: TestA (0.00s)\nFAIL\nFAIL\tpkg\t0.01s\n"
+	)
+	baseline := filepath.Join(dir, "baseline.xml")
+	if code, _, stderr := runApp(t, before, "-output", baseline); code != exitFailures {
+		t.Fatalf("can't create baseline (exit code %d): %s", code, stderr)
+	}
+
+	code, out, stderr := runApp(t, before, "-baseline", baseline, "-fail-on", "new-failures")
+	if code != exitOK {
+		t.Fatalf("exit code %d with no new failures", code)
+	}
+	if !strings.Contains(stderr, "0 new failures, 0 fixed, 1 still failing") {
+		t.Fatalf("bad baseline report: %q", stderr)
+	}
+	if !strings.Contains(out, `<property name="baseline.still-failing" value="1"/>`) {
+		t.Fatalf("no baseline properties:\n%s", out)
+	}
+
+	code, _, stderr = runApp(t, broken, "-baseline", baseline, "-fail-on", "new-failures")
+	if code != exitFailures || !strings.Contains(stderr, "new failure: pkg/TestA") {
+		t.Fatalf("exit code %d with new failures: %q", code, stderr)
+	}
+
+	if code, _, _ := runApp(t, before, "-fail-on", "new-failures"); code != exitError {
+		t.Fatalf("-fail-on without -baseline: exit code %d", code)
+	}
+}
+
+func TestAppDiffThreshold(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const (
+		before = "=== RUN   TestA\n--- PASS: TestA (1.00s)\nPASS\nok  \tpkg\t1.01s\n"
+		slower = "=== RUN   TestA\n--- PASS: TestA (1.50s)\nPASS\nok  \tpkg\t1.51s\n"
+	)
+	baseline := filepath.Join(dir, "baseline.out")
+	if err := ioutil.WriteFile(baseline, []byte(before), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, stderr := runApp(t, slower, "-format", "diff", "-compare", baseline)
+	if code != exitOK || out != "" {
+		t.Fatalf("diff below the default threshold (exit code %d): %q %s", code, out, stderr)
+	}
+	code, out, stderr = runApp(t, slower, "-format", "diff", "-compare", baseline, "-regression-threshold", "1.2")
+	if code != exitOK || out != "slower: TestA (1.5s)\n" {
+		t.Fatalf("bad diff (exit code %d): %q %s", code, out, stderr)
+	}
+	if !strings.Contains(stderr, "regression: pkg/TestA 1s -> 1.5s") {
+		t.Fatalf("no regression in %q", stderr)
+	}
+}
+
+func TestAppMaxTestTime(t *testing.T) {
+	const input = "=== RUN   TestSlow\n--- FAIL: TestSlow (2.00s)\n=== RUN   TestEqual\n--- PASS: TestEqual (1.00s)\nFAIL\nFAIL\tpkg\t3.01s\n"
+
+	code, out, stderr := runApp(t, input, "-max-test-time", "1s", "-no-exit-code")
+	if code != exitTooSlow {
+		t.Fatalf("exit code %d, expected %d", code, exitTooSlow)
+	}
+	if !strings.Contains(stderr, "TestSlow took 2s") || strings.Contains(stderr, "TestEqual") {
+		t.Fatalf("bad slow tests report: %q", stderr)
+	}
+	if strings.Count(out, `name="time.exceeded"`) != 1 {
+		t.Fatalf("bad properties:\n%s", out)
+	}
+
+	if code, _, _ := runApp(t, input, "-max-test-time", "1s", "-no-exit-code"); code != exitTooSlow {
+		t.Fatalf("-no-exit-code: exit code %d, expected %d", code, exitTooSlow)
+	}
+	if code, _, _ := runApp(t, input, "-max-test-time", "1s", "-fail"); code != exitFailures {
+		t.Fatalf("-fail: exit code %d, expected %d", code, exitFailures)
+	}
+	if code, _, _ := runApp(t, input, "-max-suite-time", "3s", "-no-exit-code"); code != exitTooSlow {
+		t.Fatalf("-max-suite-time: exit code %d, expected %d", code, exitTooSlow)
+	}
+	if code, _, _ := runApp(t, input, "-max-test-time", "2s", "-max-suite-time", "4s", "-no-exit-code"); code != exitOK {
+		t.Fatalf("exit code %d, expected %d", code, exitOK)
+	}
+}
+
+func TestAppXMLNamespace(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-pass.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const ns = "http://example.com/ns/junit"
+	code, out, _ := runApp(t, string(data), "-bamboo", "-xmlns", ns)
+	if code != exitOK {
+		t.Fatalf("exit code %d, expected %d", code, exitOK)
+	}
+	if !strings.Contains(out, `<testsuites xmlns="`+ns+`" tests=`) {
+		t.Fatalf("no namespace in root element:\n%s", out)
+	}
+
+	var root struct {
+		XMLName xml.Name `xml:"testsuites"`
+	}
+	if err := xml.Unmarshal([]byte(out), &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.XMLName.Space != ns {
+		t.Fatalf("namespace %q, expected %q", root.XMLName.Space, ns)
+	}
+
+	if code, _, _ := runApp(t, string(data), "-xmlns", "not a uri"); code != exitError {
+		t.Fatalf("bad -xmlns: exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestAppSkipPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const input = "=== RUN   TestDocker\n    docker_test.go:9: needs docker\n--- SKIP: TestDocker (0.00s)\n=== RUN   TestOK\n--- PASS: TestOK (0.01s)\nPASS\nok  \tpkg\t0.012s\n"
+	allow := filepath.Join(dir, "allow.txt")
+	if err := ioutil.WriteFile(allow, []byte("# needs docker\npkg/TestDock*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, _ := runApp(t, input, "-fail-on-skip", "-fail")
+	if code != exitFailures {
+		t.Fatalf("-fail-on-skip: exit code %d, expected %d", code, exitFailures)
+	}
+	if !strings.Contains(out, `failures="1" skip="0"`) || !strings.Contains(out, `<failure type="go.error" message="needs docker">`) {
+		t.Fatalf("-fail-on-skip: bad output:\n%s", out)
+	}
+
+	code, out, _ = runApp(t, input, "-skip-as-disabled", "-fail")
+	if code != exitOK {
+		t.Fatalf("-skip-as-disabled: exit code %d, expected %d", code, exitOK)
+	}
+	if !strings.Contains(out, `failures="0" skip="0" disabled="1"`) {
+		t.Fatalf("-skip-as-disabled: bad output:\n%s", out)
+	}
+
+	code, out, _ = runApp(t, input, "-fail-on-skip", "-skip-allow", allow, "-fail")
+	if code != exitOK {
+		t.Fatalf("-skip-allow: exit code %d, expected %d", code, exitOK)
+	}
+	if !strings.Contains(out, `failures="0" skip="1"`) {
+		t.Fatalf("-skip-allow: bad output:\n%s", out)
+	}
+
+	if code, _, _ := runApp(t, input, "-fail-on-skip", "-skip-as-disabled"); code != exitError {
+		t.Fatalf("-fail-on-skip with -skip-as-disabled: exit code %d", code)
+	}
+}
+
+func TestAppFilter(t *testing.T) {
+	const input = "=== RUN   TestUnit\n--- PASS: TestUnit (0.00s)\n=== RUN   TestIntegration\n--- FAIL: TestIntegration (0.00s)\nFAIL\nFAIL\tpkg\t0.01s\n"
+
+	code, out, stderr := runApp(t, input, "-exclude", "^TestIntegration", "-fail")
+	if code != exitOK {
+		t.Fatalf("exit code %d, expected %d", code, exitOK)
+	}
+	if !strings.Contains(out, `tests="1" errors="0" failures="0"`) {
+		t.Fatalf("bad output:\n%s", out)
+	}
+	if !strings.Contains(stderr, "filtered out 1 test(s)") {
+		t.Fatalf("no filtered out count: %q", stderr)
+	}
+
+	if code, _, _ := runApp(t, input, "-include-pkg", "^other$"); code != exitError {
+		t.Fatalf("all filtered out: exit code %d, expected %d", code, exitError)
+	}
+	if code, _, _ := runApp(t, input, "-include", "("); code != exitError {
+		t.Fatalf("bad regexp: exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestAppTimeZone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "test.out")
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-pass.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 7, 1, 2, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(input, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, _ := runApp(t, "", "-input", input, "-xunitnet", "-tz", "America/New_York")
+	if code != exitOK {
+		t.Fatalf("exit code %d, expected %d", code, exitOK)
+	}
+	// 02:30 UTC is 22:30 EDT on the previous day
+	if !strings.Contains(out, `run-date="2020-06-30" run-time="22:30:00"`) {
+		t.Fatalf("bad run date/time:\n%s", out)
+	}
+
+	// UTC by default
+	_, out, _ = runApp(t, "", "-input", input, "-xunitnet")
+	if !strings.Contains(out, `run-date="2020-07-01" run-time="02:30:00"`) {
+		t.Fatalf("bad default run date/time:\n%s", out)
+	}
+
+	if code, _, _ := runApp(t, "", "-input", input, "-timezone", "Local"); code != exitOK {
+		t.Fatalf("-timezone Local: exit code %d, expected %d", code, exitOK)
+	}
+	if code, _, _ := runApp(t, "", "-input", input, "-tz", "Nowhere/Special"); code != exitError {
+		t.Fatalf("bad -tz: exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestAppVersionInfo(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-pass.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, _ := runApp(t, string(data), "-version-info")
+	if code != exitOK {
+		t.Fatalf("exit code %d, expected %d", code, exitOK)
+	}
+
+	var report struct {
+		Properties []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"properties>property"`
+	}
+	if err := xml.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	props := make(map[string]string)
+	for _, prop := range report.Properties {
+		props[prop.Name] = prop.Value
+	}
+	for _, name := range []string{"go2xunit.version", "go.version", "generated-at"} {
+		if props[name] == "" {
+			t.Fatalf("%s property missing in:\n%s", name, out)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, props["generated-at"]); err != nil {
+		t.Fatalf("bad generated-at: %s", err)
+	}
+}
+
+func TestAppConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const input = "=== RUN   TestA\n--- FAIL: TestA (0.00s)\nFAIL\nFAIL\tpkg\t0.01s\n"
+	baseline := filepath.Join(dir, "baseline.out")
+	if err := ioutil.WriteFile(baseline, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "ci.toml")
+	data := "format = \"diff\"\nbaseline = " + strconv.Quote(baseline) + "\n"
+	if err := ioutil.WriteFile(config, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, stderr := runApp(t, input, "-config", config)
+	if code != exitFailures {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if strings.Contains(out, "<testsuite") {
+		t.Fatalf("format from config ignored:\n%s", out)
+	}
+
+	// Command line overrides the configuration file
+	_, out, _ = runApp(t, input, "-config", config, "-format", "xunit")
+	if !strings.Contains(out, "<testsuite") {
+		t.Fatalf("-format ignored:\n%s", out)
+	}
+
+	// go2xunit.toml in the current directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile("go2xunit.toml", []byte("fail = true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, _ := runApp(t, input); code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+
+	// Aliases of options given on the command line are not changed
+	if err := ioutil.WriteFile(config, []byte("fail = false\nslow = 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, _, stderr = runApp(t, input, "-config", config, "-exit-code", "-top", "0")
+	if code != exitFailures || strings.Contains(stderr, "TEST") {
+		t.Fatalf("exit code %d, expected %d:\n%s", code, exitFailures, stderr)
+	}
+
+	if err := ioutil.WriteFile(config, []byte("no-such-option = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, _ := runApp(t, input, "-config", config); code != exitError {
+		t.Fatalf("unknown option: exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestConfigFields(t *testing.T) {
+	fields := make(map[string]bool)
+	typ := reflect.TypeOf(lib.Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		if name := typ.Field(i).Tag.Get("toml"); name != "" {
+			fields[name] = true
+		}
+	}
+
+	var args cmdArgs
+	flags := newFlagSet(&args, ioutil.Discard)
+	flags.VisitAll(func(f *flag.Flag) {
+		alias := false
+		flags.VisitAll(func(other *flag.Flag) {
+			alias = alias || (other.Name != f.Name && sameFlagValue(other.Value, f.Value) && fields[other.Name])
+		})
+		if f.Name == "config" || f.Name == "version" || alias {
+			return
+		}
+		if !fields[f.Name] {
+			t.Errorf("no lib.Config field for -%s", f.Name)
+		}
+	})
+	for name := range fields {
+		if flags.Lookup(name) == nil {
+			t.Errorf("lib.Config field %q is not a flag", name)
+		}
+	}
+}
+
+func TestAppEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const input = "=== RUN   TestA\n--- FAIL: TestA (0.00s)\nFAIL\nFAIL\tpkg\t0.01s\n"
+	baseline := filepath.Join(dir, "baseline.out")
+	if err := ioutil.WriteFile(baseline, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"GO2XUNIT_FORMAT":   "diff",
+		"GO2XUNIT_BASELINE": baseline,
+		"GO2XUNIT_FAIL":     "true",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	code, out, stderr := runApp(t, input)
+	if code != exitFailures {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if strings.Contains(out, "<testsuite") {
+		t.Fatalf("GO2XUNIT_FORMAT ignored:\n%s", out)
+	}
+
+	// Command line overrides the environment
+	code, out, _ = runApp(t, input, "-format", "xunit")
+	if !strings.Contains(out, "<testsuite") || code != exitFailures {
+		t.Fatalf("exit code %d, output:\n%s", code, out)
+	}
+
+	os.Setenv("GO2XUNIT_SLOW", "many")
+	defer os.Unsetenv("GO2XUNIT_SLOW")
+	if code, _, _ := runApp(t, input); code != exitError {
+		t.Fatalf("bad GO2XUNIT_SLOW: exit code %d, expected %d", code, exitError)
+	}
+	// Aliases of options given on the command line are not changed
+	os.Setenv("GO2XUNIT_FAIL", "false")
+	if code, _, stderr := runApp(t, input, "-top", "0", "-exit-code"); code != exitFailures {
+		t.Fatalf("exit code %d, expected %d: %s", code, exitFailures, stderr)
+	}
+}
+
+func TestAppInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGTERM on windows")
+	}
+	// The test binary runs go2xunit when called by the test below
+	if outFile := os.Getenv("GO2XUNIT_INTERRUPT_OUTPUT"); outFile != "" {
+		os.Args = []string{"go2xunit", "-progress=force", "-output", outFile}
+		main()
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outFile := filepath.Join(dir, "tests.xml")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAppInterrupt$")
+	cmd.Env = append(os.Environ(), "GO2XUNIT_INTERRUPT_OUTPUT="+outFile)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	// The input pipe is never closed, TestB is running when interrupted
+	io.WriteString(stdin, "=== RUN   TestA\n--- PASS: TestA (0.01s)\n=== RUN   TestB\n")
+	// Progress is printed once the input is read
+	if _, err := stderr.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(ioutil.Discard, stderr)
+
+	err = cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != exitInterrupted {
+		t.Fatalf("exit error %v, expected exit code %d", err, exitInterrupted)
+	}
+
+	data, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if !strings.Contains(out, `<property name="incomplete" value="true"/>`) {
+		t.Fatalf("report not marked incomplete:\n%s", out)
+	}
+	if !strings.Contains(out, `name="TestA"`) || !strings.Contains(out, "no result recorded") {
+		t.Fatalf("bad partial report:\n%s", out)
+	}
+}
+
+func TestAppWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "test.out")
+	output := filepath.Join(dir, "tests.xml")
+	const first = "=== RUN   TestA\n--- PASS: TestA (0.00s)\nPASS\nok  \tpkg\t0.01s\n"
+	if err := ioutil.WriteFile(input, []byte(first), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	stop := make(chan struct{})
+	app := NewApp(WithIO(strings.NewReader(""), ioutil.Discard, &stderr))
+	app.watchInterval = 10 * time.Millisecond
+	app.stop = stop
+	done := make(chan int)
+	go func() { done <- app.Run([]string{"-watch", "-input", input, "-output", output}) }()
+
+	// waitFor waits for the output to contain text
+	waitFor := func(text string) {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			data, _ := ioutil.ReadFile(output)
+			if strings.Contains(string(data), text) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%q not found in output:\n%s", text, data)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(`name="TestA"`)
+
+	file, err := os.OpenFile(input, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteString("=== RUN   TestB\n--- PASS: TestB (0.00s)\nPASS\nok  \tpkg2\t0.01s\n")
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(`name="TestB"`)
+
+	close(stop)
+	select {
+	case code := <-done:
+		if code != exitOK {
+			t.Fatalf("exit code %d, expected %d", code, exitOK)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch didn't stop")
+	}
+
+	if code, _, _ := runApp(t, "", "-watch"); code != exitError {
+		t.Fatalf("-watch without -input: exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestAppCoveralls(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-cover.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, _ := runApp(t, string(data), "-format", "coveralls", "-coveralls-token", "s3cr3t")
+	if code != exitOK {
+		t.Fatalf("exit code %d, expected %d", code, exitOK)
+	}
+	if !strings.Contains(out, `"repo_token": "s3cr3t"`) || !strings.Contains(out, `"source_files"`) {
+		t.Fatalf("bad coveralls output:\n%s", out)
+	}
+
+	if code, _, _ = runApp(t, string(data), "-coveralls-token", "s3cr3t"); code != exitError {
+		t.Fatalf("-coveralls-token without -format coveralls: exit code %d", code)
+	}
+}
+
+func TestAppSlack(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-fail.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var posted []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted, _ = ioutil.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	code, out, _ := runApp(t, string(data), "-format", "slack", "-slack-webhook", server.URL)
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if !strings.Contains(out, `"blocks"`) || !strings.Contains(out, "TestSubFail") {
+		t.Fatalf("bad slack output:\n%s", out)
+	}
+	if string(posted) != out {
+		t.Fatalf("posted payload differs from output:\n%s", posted)
+	}
+
+	if code, _, _ = runApp(t, string(data), "-slack-webhook", server.URL); code != exitError {
+		t.Fatalf("-slack-webhook without -format slack: exit code %d", code)
+	}
+}
+
+func TestAppTimePrecision(t *testing.T) {
+	input := "=== RUN   TestA\n--- PASS: TestA (0.04s)\nPASS\nok  \texample.com/pkg\t0.042s\n"
+
+	code, out, _ := runApp(t, input, "-time-precision", "1")
+	if code != exitOK {
+		t.Fatalf("exit code %d, expected %d", code, exitOK)
+	}
+	if !strings.Contains(out, `name="TestA" time="0.0"`) || !strings.Contains(out, `tests="1" errors="0" failures="0" skip="0" time="0.0"`) {
+		t.Fatalf("bad times:\n%s", out)
+	}
+
+	if code, _, _ = runApp(t, input, "-time-precision", "10"); code != exitError {
+		t.Fatalf("-time-precision 10: exit code %d", code)
+	}
+}
+
+func TestAppNames(t *testing.T) {
+	input := "=== RUN   TestA\x01\n--- PASS: TestA\x01 (0.00s)\nPASS\nok  \texample.com/pkg\t0.010s\n"
+
+	_, out, _ := runApp(t, input)
+	if !strings.Contains(out, `name="TestA_"`) || !strings.Contains(out, `<property name="name.original" value="TestA`) {
+		t.Fatalf("name not sanitized:\n%s", out)
+	}
+
+	_, out, _ = runApp(t, input, "-raw-names")
+	if strings.Contains(out, `name="TestA_"`) {
+		t.Fatalf("name sanitized with -raw-names:\n%s", out)
+	}
+
+	if code, _, _ := runApp(t, input, "-max-name-length", "3"); code != exitError {
+		t.Fatalf("-max-name-length 3: exit code %d", code)
+	}
+}
+
+func TestAppAttachments(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-fail.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	code, out, _ := runApp(t, string(data), "-attachments-dir", dir)
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("bad attachments: %v (%v)", files, err)
+	}
+	if !strings.Contains(out, "[[ATTACHMENT|"+files[0]+"]]") {
+		t.Fatalf("no attachment marker:\n%s", out)
+	}
+
+	if code, _, _ := runApp(t, string(data), "-attachments-all"); code != exitError {
+		t.Fatalf("-attachments-all without -attachments-dir: exit code %d", code)
+	}
+}
+
+func TestAppAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "report.xml")
+
+	first := "=== RUN   TestA\n--- FAIL: TestA (0.01s)\n=== RUN   TestB\n--- PASS: TestB (0.01s)\nFAIL\nFAIL\texample.com/a\t0.020s\n"
+	if code, _, stderr := runApp(t, first, "-append", "-output", output); code != exitFailures {
+		t.Fatalf("first run: exit code %d: %s", code, stderr)
+	}
+
+	// Rerun of the failed test and a new package
+	rerun := "=== RUN   TestA\n--- PASS: TestA (0.01s)\nPASS\nok  \texample.com/a\t0.010s\n" +
+		"=== RUN   TestC\n--- PASS: TestC (0.01s)\nPASS\nok  \texample.com/b\t0.010s\n"
+	if code, _, stderr := runApp(t, rerun, "-append", "-output", output); code != exitOK {
+		t.Fatalf("append: exit code %d: %s", code, stderr)
+	}
+
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, name := range []string{`<testsuite name="example.com/a" tests="2" errors="0" failures="0"`, `<testsuite name="example.com/b"`, `name="TestB"`, `name="TestC"`} {
+		if !strings.Contains(out, name) {
+			t.Fatalf("%s not in report:\n%s", name, out)
+		}
+	}
+
+	if code, _, _ := runApp(t, first, "-append"); code != exitError {
+		t.Fatalf("-append without -output: exit code %d", code)
+	}
+}
+
+func TestAppShuffleSeed(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/test2json-shuffle.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, stderr := runApp(t, string(data), "-json", "-print-shuffle-seed")
+	if code != exitFailures {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !strings.Contains(out, `<property name="test.shuffle" value="1234567890"/>`) {
+		t.Fatalf("no shuffle seed property:\n%s", out)
+	}
+	if !strings.Contains(stderr, "shuffle seed: example.com/fz 1234567890\n") {
+		t.Fatalf("shuffle seed not printed:\n%s", stderr)
+	}
+}
+
+func TestAppMaxFailures(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&input, "=== RUN   Test%d\n--- FAIL: Test%d (0.00s)\n", i, i)
+	}
+	input.WriteString("FAIL\nFAIL\texample.com/pkg\t0.010s\n")
+
+	// Exits with the failure code without -fail
+	code, out, _ := runApp(t, input.String(), "-max-failures", "0")
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if n := strings.Count(out, "<testcase "); n != 1 {
+		t.Fatalf("got %d test cases instead of 1:\n%s", n, out)
+	}
+	if !strings.Contains(out, `<property name="stopped.early" value="true"/>`) {
+		t.Fatalf("report not marked as stopped early:\n%s", out)
+	}
+
+	code, out, _ = runApp(t, input.String(), "-max-failures", "20")
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if n := strings.Count(out, "<testcase "); n != 20 {
+		t.Fatalf("got %d test cases instead of 20:\n%s", n, out)
+	}
+
+	code, out, _ = runApp(t, input.String(), "-fail-fast")
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if n := strings.Count(out, "<testcase "); n != 1 {
+		t.Fatalf("got %d test cases instead of 1:\n%s", n, out)
+	}
+	if code, _, _ := runApp(t, input.String(), "-fail-fast", "-max-failures", "3"); code != exitError {
+		t.Fatalf("exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestAppHistogram(t *testing.T) {
+	input := "=== RUN   TestA\n--- PASS: TestA (0.01s)\n=== RUN   TestB\n--- PASS: TestB (2.00s)\n" +
+		"PASS\nok  \texample.com/pkg\t2.010s\n"
+	code, _, stderr := runApp(t, input, "-histogram", "-quiet")
+	if code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	expected := []string{"TIME TESTS", "<100ms 1 " + strings.Repeat("#", 40), "100ms-1s 0",
+		"1s-10s 1 " + strings.Repeat("#", 40), ">10s 0"}
+	if len(lines) != len(expected) {
+		t.Fatalf("bad histogram:\n%s", stderr)
+	}
+	for i, line := range lines {
+		if fields := strings.Join(strings.Fields(line), " "); fields != expected[i] {
+			t.Fatalf("histogram line %q, expected %q", fields, expected[i])
+		}
+	}
+}
+
+func TestAppValidate(t *testing.T) {
+	code, out, stderr := runApp(t, "", "-validate", "-input", dataPath+"/in/validate-ok.json")
+	if code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !strings.HasSuffix(out, "well formed\n") || strings.Contains(out, "<testsuite") {
+		t.Fatalf("bad output:\n%s", out)
+	}
+
+	code, out, _ = runApp(t, "", "-validate", "-input", dataPath+"/in/validate-unterminated.json")
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if !strings.Contains(out, "TestB: test has no pass, fail or skip record") {
+		t.Fatalf("bad output:\n%s", out)
+	}
+
+	if code, _, _ := runApp(t, "", "-validate", "-input", dataPath+"/in/no-such-file.json"); code != exitError {
+		t.Fatalf("exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestAppGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, args := range [][]string{
+		{"-output-gz", "-output", filepath.Join(dir, "tests.xml")},
+		{"-gzip-level", "9", "-output", filepath.Join(dir, "tests.xml.gz")},
+	} {
+		args = append(args, "-input", dataPath+"/in/gotest-fail.out")
+		if code, _, stderr := runApp(t, "", args...); code != exitFailures {
+			t.Fatalf("%v: exit code %d\n%s", args, code, stderr)
+		}
+
+		file, err := os.Open(args[len(args)-3])
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("%v: %s", args, err)
+		}
+		suites, err := lib.ParseXUnit(gz, "")
+		file.Close()
+		if err != nil {
+			t.Fatalf("%v: bad XML - %s", args, err)
+		}
+		if len(suites) == 0 {
+			t.Fatalf("%v: no suites", args)
+		}
+	}
+
+	if code, _, _ := runApp(t, "", "-output-gz", "-gzip-level", "10"); code != exitError {
+		t.Fatalf("exit code %d, expected %d", code, exitError)
+	}
+}
+
+func TestAppSummaryLine(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&input, "=== RUN   Test%d\n--- FAIL: Test%d (0.00s)\n", i, i)
+	}
+	input.WriteString("FAIL\nFAIL\texample.com/pkg\t0.010s\n")
+
+	code, out, stderr := runApp(t, input.String(), "-summary-failures", "3")
+	if code != exitFailures {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	expected := "go2xunit: 5 tests, 5 failed, 0 errors, 0 skipped in 1 packages (10ms)\n" +
+		"  FAIL example.com/pkg/Test0\n" +
+		"  FAIL example.com/pkg/Test1\n" +
+		"  FAIL example.com/pkg/Test2\n" +
+		"  and 2 more\n"
+	if stderr != expected {
+		t.Fatalf("bad summary:\n%s\nexpected:\n%s", stderr, expected)
+	}
+	if strings.Contains(out, "go2xunit:") {
+		t.Fatalf("summary in report:\n%s", out)
+	}
+
+	if _, _, stderr := runApp(t, input.String(), "-quiet"); stderr != "" {
+		t.Fatalf("summary with -quiet:\n%s", stderr)
+	}
+}
+
+func TestAppMaxTests(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&input, "=== RUN   Test%d\n--- FAIL: Test%d (0.00s)\n", i, i)
+	}
+	input.WriteString("FAIL\nFAIL\texample.com/pkg\t0.010s\n")
+
+	code, out, _ := runApp(t, input.String(), "-max-tests", "5", "-fail")
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if n := strings.Count(out, "<testcase "); n != 5 {
+		t.Fatalf("got %d test cases instead of 5:\n%s", n, out)
+	}
+	if !strings.Contains(out, `name="Test4"`) || strings.Contains(out, `name="Test5"`) {
+		t.Fatalf("wrong tests in report:\n%s", out)
+	}
+}
+
+func TestAppCSV(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-fail.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, _ := runApp(t, string(data), "-format", "csv", "-fail")
+	if code != exitFailures {
+		t.Fatalf("exit code %d, expected %d", code, exitFailures)
+	}
+	if !strings.HasPrefix(out, "package,name,status,time,message\n") {
+		t.Fatalf("missing CSV header:\n%s", out)
+	}
+
+	_, noHeader, _ := runApp(t, string(data), "-format", "csv", "-csv-no-header")
+	if noHeader != strings.SplitN(out, "\n", 2)[1] {
+		t.Fatalf("bad -csv-no-header output:\n%s", noHeader)
+	}
+}
+
+func TestAppPretty(t *testing.T) {
+	input := "=== RUN   TestA\n--- PASS: TestA (0.01s)\n=== RUN   TestB\n--- FAIL: TestB (0.00s)\n" +
+		"    b_test.go:3: oops\nFAIL\nFAIL\texample.com/pkg\t0.010s\n"
+	for _, stream := range []bool{false, true} {
+		args := []string{"-format", "pretty", "-color", "never", "-quiet"}
+		if stream {
+			args = append(args, "-stream")
+		}
+		code, out, stderr := runApp(t, input, args...)
+		if code != exitFailures {
+			t.Fatalf("exit code %d\n%s", code, stderr)
+		}
+		if !strings.Contains(out, "  ✗ TestB (0s)\nexample.com/pkg: 2 tests, 1 failed") ||
+			!strings.Contains(out, "--- example.com/pkg TestB\n    b_test.go:3: oops\n") ||
+			strings.Contains(out, "\x1b[") {
+			t.Fatalf("bad output (stream %v):\n%s", stream, out)
+		}
+	}
+
+	_, out, _ := runApp(t, input, "-format", "pretty", "-color", "always", "-quiet")
+	if !strings.Contains(out, "\x1b[") {
+		t.Fatalf("no colors:\n%s", out)
+	}
+
+	code, _, _ := runApp(t, input, "-stream")
+	if code != exitError {
+		t.Fatalf("-stream without -format pretty: exit code %d", code)
+	}
+}
+
+func TestAppUpload(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-fail.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backoff := sendBackoff
+	sendBackoff = time.Millisecond
+	defer func() { sendBackoff = backoff }()
+
+	var method, contentType, auth string
+	var body []byte
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		method, contentType, auth = r.Method, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	code, out, stderr := runApp(t, string(data), "-upload-url", server.URL, "-upload-token", "s3cr3t", "-quiet")
+	if code != exitFailures {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if requests != 2 {
+		t.Fatalf("%d requests, expected 2", requests)
+	}
+	if method != http.MethodPut || contentType != "application/xml" || auth != "Bearer s3cr3t" {
+		t.Fatalf("bad request: %s %q %q", method, contentType, auth)
+	}
+	if string(body) != out || !strings.Contains(out, "<testsuite") {
+		t.Fatalf("uploaded body differs from output:\n%s", body)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if code, _, stderr = runApp(t, string(data), "-upload-url", failing.URL); code != exitError {
+		t.Fatalf("failed upload: exit code %d", code)
+	}
+	if !strings.Contains(stderr, "502") {
+		t.Fatalf("bad error:\n%s", stderr)
+	}
+
+	if code, _, _ = runApp(t, string(data), "-upload-token", "s3cr3t"); code != exitError {
+		t.Fatalf("-upload-token without -upload-url: exit code %d", code)
+	}
+}
+
+func TestAppMetrics(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "go2xunit-metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	input := "=== RUN   TestA\n--- PASS: TestA (0.01s)\n=== RUN   TestB\n--- FAIL: TestB (0.00s)\n" +
+		"FAIL\nFAIL\texample.com/pkg\t0.010s\n"
+	metricsFile := filepath.Join(tmpDir, "go2xunit.prom")
+	code, out, stderr := runApp(t, input, "-format", "csv", "-metrics-file", metricsFile,
+		"-metrics-per-test", "-prop", "job=ci", "-quiet")
+	if code != exitFailures {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !strings.HasPrefix(out, "package,name") {
+		t.Fatalf("bad output:\n%s", out)
+	}
+	data, err := ioutil.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`go_tests_total{package="example.com/pkg",status="fail",job="ci"} 1`,
+		`go_test_case_duration_seconds{package="example.com/pkg",test="TestA",status="pass",job="ci"} 0.01`,
+	} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Fatalf("%s not in:\n%s", line, data)
+		}
+	}
+
+	_, out, _ = runApp(t, input, "-prop", "job=ci", "-quiet")
+	if !strings.Contains(out, `<property name="job" value="ci"`) {
+		t.Fatalf("no property:\n%s", out)
+	}
+
+	if code, _, _ = runApp(t, input, "-metrics-file", metricsFile, "-prop", "status=x"); code != exitError {
+		t.Fatalf("reserved label: exit code %d", code)
+	}
+	if code, _, _ = runApp(t, input, "-metrics-per-test"); code != exitError {
+		t.Fatalf("-metrics-per-test without -metrics-file: exit code %d", code)
+	}
+}
+
+// stubLister is a packageLister of "go list -json" output
+type stubLister string
+
+func (s stubLister) List(root string) ([]lib.GoPackage, error) {
+	return decodeGoList(strings.NewReader(string(s)))
+}
+
+func TestAppImportGraph(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "go2xunit-graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	goListOutput := `{"ImportPath": "example.com/pkg", "Imports": ["example.com/pkg/util", "strings"]}
+{"ImportPath": "example.com/pkg/util", "Imports": ["fmt"]}
+`
+	input := "=== RUN   TestA\n--- FAIL: TestA (0.01s)\nFAIL\nFAIL\texample.com/pkg\t0.010s\n"
+	graphFile := filepath.Join(tmpDir, "imports.dot")
+
+	var stdout, stderr bytes.Buffer
+	app := NewApp(WithIO(strings.NewReader(input), &stdout, &stderr))
+	app.lister = stubLister(goListOutput)
+	if code := app.Run([]string{"-import-graph", graphFile, "-quiet"}); code != exitFailures {
+		t.Fatalf("exit code %d\n%s", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(graphFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`"example.com/pkg" [label="example.com/pkg", fillcolor=red];`,
+		`"example.com/pkg/util" [label="example.com/pkg/util", fillcolor=grey];`,
+		`"example.com/pkg" -> "example.com/pkg/util";`,
+	} {
+		if !strings.Contains(string(data), line) {
+			t.Fatalf("%s not in:\n%s", line, data)
+		}
+	}
+
+	app = NewApp(WithIO(strings.NewReader(input), &stdout, &stderr))
+	app.lister = stubLister("{bad")
+	if code := app.Run([]string{"-import-graph", graphFile}); code != exitError {
+		t.Fatalf("bad go list output: exit code %d", code)
+	}
+}
+
+func TestAppHistory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "go2xunit-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	history := filepath.Join(tmpDir, "history.jsonl")
+	failing := "=== RUN   TestA\n--- FAIL: TestA (0.01s)\n=== RUN   TestB\n--- PASS: TestB (0.00s)\n" +
+		"FAIL\nFAIL\texample.com/pkg\t0.010s\n"
+	passing := "=== RUN   TestA\n--- PASS: TestA (0.01s)\n=== RUN   TestB\n--- FAIL: TestB (0.00s)\n" +
+		"FAIL\nFAIL\texample.com/pkg\t0.010s\n"
+
+	code, _, stderr := runApp(t, failing, "-history", history)
+	if code != exitFailures || strings.Contains(stderr, "regressed") {
+		t.Fatalf("first run: exit code %d\n%s", code, stderr)
+	}
+
+	// A corrupted line is skipped
+	file, err := os.OpenFile(history, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{\"time\n")
+	file.Close()
+
+	code, _, stderr = runApp(t, passing, "-history", history)
+	if code != exitFailures {
+		t.Fatalf("second run: exit code %d\n%s", code, stderr)
+	}
+	if !strings.Contains(stderr, "history.jsonl:2: skipped corrupted history entry") ||
+		!strings.Contains(stderr, "go2xunit: 1 regressed, 1 fixed since ") {
+		t.Fatalf("bad stderr:\n%s", stderr)
+	}
+
+	if code, _, stderr = runApp(t, passing, "-history", history, "-history-limit", "2"); code != exitFailures {
+		t.Fatalf("third run: exit code %d\n%s", code, stderr)
+	}
+	if !strings.Contains(stderr, "0 regressed, 0 fixed") {
+		t.Fatalf("bad stderr:\n%s", stderr)
+	}
+	data, err := ioutil.ReadFile(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"failed":["example.com/pkg/TestB"]`) {
+		t.Fatalf("bad history:\n%s", data)
+	}
+}
+
+func TestAppHistoryConcurrent(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "go2xunit-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The history is full of records of an older run, every run trims it
+	const limit, runs = 10, 5
+	history := filepath.Join(tmpDir, "history.jsonl")
+	old := lib.Suites{{Name: "example.com/old", Tests: []*lib.Test{{Name: "TestOld", Status: lib.Failed}}}}
+	var buf bytes.Buffer
+	for i := 0; i < limit; i++ {
+		data, err := lib.NewHistoryEntry(old, time.Now()).MarshalLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(data)
+	}
+	if err := ioutil.WriteFile(history, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "=== RUN   TestA\n--- FAIL: TestA (0.01s)\nFAIL\nFAIL\texample.com/pkg\t0.010s\n"
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if code, _, stderr := runApp(t, input, "-history", history, "-history-limit", fmt.Sprint(limit)); code != exitFailures {
+				t.Errorf("exit code %d\n%s", code, stderr)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := ioutil.ReadFile(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != limit || strings.Count(string(data), "example.com/pkg/TestA") != runs {
+		t.Fatalf("records of concurrent runs lost:\n%s", data)
+	}
+	if _, err := os.Stat(history + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lock file not removed: %v", err)
+	}
+}
+
+func TestAppIndent(t *testing.T) {
+	input := "=== RUN   TestA\n--- PASS: TestA (0.01s)\nPASS\nok  \texample.com/pkg\t0.010s\n"
+	_, out, _ := runApp(t, input)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="example.com/pkg" tests="1" errors="0" failures="0" skip="0" time="0.010">
+	<testcase classname="example.com/pkg" name="TestA" time="0.010"/>
+</testsuite>
+`
+	if out != expected {
+		t.Fatalf("bad default indentation:\n%s", out)
+	}
+
+	if _, out, _ = runApp(t, input, "-compact"); strings.Count(out, "\n") != 1 {
+		t.Fatalf("bad compact output:\n%s", out)
+	}
+	if _, out, _ = runApp(t, input, "-indent", ""); !strings.Contains(out, "\n  <testsuite ") {
+		t.Fatalf("output re-indented with -indent \"\":\n%s", out)
+	}
+	if code, _, _ := runApp(t, input, "-compact", "-indent", "  "); code != exitError {
+		t.Fatalf("-compact with -indent: exit code %d", code)
+	}
+}
+
+func TestAppTagMap(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "go2xunit-tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tagMap := filepath.Join(tmpDir, "tags.json")
+	if err := ioutil.WriteFile(tagMap, []byte(`{"example.com/pkg": "unit"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := "=== RUN   TestA\n--- PASS: TestA (0.01s)\nPASS\nok  \texample.com/pkg\t0.010s\n"
+	code, out, stderr := runApp(t, input, "-tag-map", tagMap, "-indent", "  ", "-quiet")
+	if code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !strings.Contains(out, "<testsuites>\n  <testsuites name=\"unit\" tests=\"1\"") ||
+		!strings.Contains(out, "\n    <testsuite name=\"example.com/pkg\"") {
+		t.Fatalf("bad output:\n%s", out)
+	}
+
+	if code, _, _ = runApp(t, input, "-tag-map", filepath.Join(tmpDir, "missing.json")); code != exitError {
+		t.Fatalf("missing tag map: exit code %d", code)
+	}
+	if code, _, _ = runApp(t, input, "-tag-map", tagMap, "-format", "csv"); code != exitError {
+		t.Fatalf("-tag-map with -format csv: exit code %d", code)
+	}
+}
+
+func TestAppInputURL(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-fail.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(data)
+	gz.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/log", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			http.Error(w, "no token", http.StatusUnauthorized)
+			return
+		}
+		w.Write(data)
+	})
+	mux.HandleFunc("/gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped.Bytes())
+	})
+	mux.Handle("/redirect", http.RedirectHandler("/gz", http.StatusFound))
+	mux.HandleFunc("/truncated", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data[:100])
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, expected, _ := runApp(t, string(data), "-quiet")
+	code, out, stderr := runApp(t, "", "-input", server.URL+"/log", "-header", "Authorization: Bearer s3cr3t", "-quiet")
+	if code != exitFailures || out != expected {
+		t.Fatalf("exit code %d\n%s\n%s", code, stderr, out)
+	}
+	// Accept-Encoding given, the response isn't decompressed by the transport
+	code, out, stderr = runApp(t, "", "-input", server.URL+"/redirect", "-header", "Accept-Encoding: gzip", "-quiet")
+	if code != exitFailures || out != expected {
+		t.Fatalf("gzip: exit code %d\n%s\n%s", code, stderr, out)
+	}
+
+	code, _, stderr = runApp(t, "", "-input", server.URL+"/log")
+	if code != exitError || !strings.Contains(stderr, server.URL+"/log: 401 Unauthorized") {
+		t.Fatalf("no token: exit code %d\n%s", code, stderr)
+	}
+	code, _, stderr = runApp(t, "", "-input", server.URL+"/truncated")
+	if code != exitError || !strings.Contains(stderr, server.URL+"/truncated: read failed after 100 bytes") {
+		t.Fatalf("truncated: exit code %d\n%s", code, stderr)
+	}
+
+	if code, _, _ = runApp(t, string(data), "-header", "Authorization: Bearer s3cr3t"); code != exitError {
+		t.Fatalf("-header without URL: exit code %d", code)
+	}
+}
+
+func TestAppBenchDiff(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-bench.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpDir, err := ioutil.TempDir("", "go2xunit-bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	baseline := filepath.Join(tmpDir, "baseline.out")
+	slower := strings.Replace(string(data), "243.5 ns/op", "487.0 ns/op", 1)
+	if err := ioutil.WriteFile(baseline, []byte(slower), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, stderr := runApp(t, string(data), "-format", "bench-diff", "-baseline", baseline, "-quiet")
+	if code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	expected := "| Benchmark | Baseline ns/op | Current ns/op | Change |\n|---|---:|---:|---:|\n" +
+		"| example.com/mmath/BenchmarkJoin | 487 | 244 | -50.0% |\n"
+	if out != expected {
+		t.Fatalf("bad output:\n%s", out)
+	}
+
+	if code, _, _ = runApp(t, string(data), "-format", "bench-diff"); code != exitError {
+		t.Fatalf("-format bench-diff without -baseline: exit code %d", code)
+	}
+	if code, _, _ = runApp(t, string(data), "-format", "bench-diff", "-baseline", baseline, "-bench-threshold", "0.5"); code != exitError {
+		t.Fatalf("-bench-threshold 0.5: exit code %d", code)
+	}
+}
+
+func TestAppCobertura(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-cover.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, out, stderr := runApp(t, string(data), "-format", "cobertura", "-quiet")
+	if code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !strings.Contains(out, "<coverage line-rate=") || !strings.Contains(out, "<class name=") {
+		t.Fatalf("bad output:\n%s", out)
+	}
+}
+
+func TestAppWebhook(t *testing.T) {
+	backoff := sendBackoff
+	sendBackoff = time.Millisecond
+	defer func() { sendBackoff = backoff }()
+
+	input := "=== RUN   TestA\n--- PASS: TestA (0.01s)\n=== RUN   TestB\n--- FAIL: TestB (0.00s)\n" +
+		"FAIL\nFAIL\texample.com/pkg\t0.010s\n"
+
+	var bodies []string
+	failFirst := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failFirst {
+			failFirst = false
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	code, _, stderr := runApp(t, input, "-webhook", server.URL, "-prop", "job=ci", "-quiet")
+	if code != exitFailures || len(bodies) != 1 {
+		t.Fatalf("exit code %d, %d posts\n%s", code, len(bodies), stderr)
+	}
+	var run lib.RunSummary
+	if err := json.Unmarshal([]byte(bodies[0]), &run); err != nil {
+		t.Fatalf("bad payload: %s\n%s", err, bodies[0])
+	}
+	if run.Tests != 2 || run.Failed != 1 || len(run.Failures) != 1 || run.Failures[0] != "example.com/pkg/TestB" ||
+		run.Properties["job"] != "ci" {
+		t.Fatalf("bad payload: %s", bodies[0])
+	}
+
+	// 500 then success
+	failFirst = true
+	if code, _, stderr = runApp(t, input, "-webhook", server.URL, "-webhook-required", "-quiet"); code != exitFailures || len(bodies) != 2 {
+		t.Fatalf("retry: exit code %d, %d posts\n%s", code, len(bodies), stderr)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "go2xunit-webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tmplFile := filepath.Join(tmpDir, "payload.tmpl")
+	if err := ioutil.WriteFile(tmplFile, []byte(`{"text": "{{.Failed}} of {{.Tests}} failed"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr = runApp(t, input, "-webhook", server.URL, "-webhook-template", tmplFile, "-quiet"); code != exitFailures {
+		t.Fatalf("template: exit code %d\n%s", code, stderr)
+	}
+	if bodies[2] != `{"text": "1 of 2 failed"}` {
+		t.Fatalf("bad template payload: %s", bodies[2])
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	code, out, stderr := runApp(t, input, "-webhook", slow.URL, "-webhook-timeout", "20ms", "-quiet")
+	if code != exitFailures || !strings.Contains(stderr, "warning: can't send to "+slow.URL) || !strings.Contains(out, "<testsuite") {
+		t.Fatalf("timeout: exit code %d\n%s", code, stderr)
+	}
+	if code, _, _ = runApp(t, input, "-webhook", slow.URL, "-webhook-timeout", "20ms", "-webhook-required"); code != exitError {
+		t.Fatalf("timeout with -webhook-required: exit code %d", code)
+	}
+}
+
+func TestAppFormatsExitCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const input = "=== RUN   TestSlow\n--- PASS: TestSlow (2.00s)\n=== RUN   TestA\n--- FAIL: TestA (0.00s)\nFAIL\nFAIL\tpkg\t2.01s\n"
+	baseline := filepath.Join(dir, "baseline.out")
+	if err := ioutil.WriteFile(baseline, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for format := range suitesWriters {
+		args := []string{"-format", format, "-baseline", baseline, "-quiet"}
+		if code, _, stderr := runApp(t, input, args...); code != exitFailures {
+			t.Fatalf("%s: exit code %d, expected %d\n%s", format, code, exitFailures, stderr)
+		}
+		args = append(args, "-no-exit-code", "-max-test-time", "1s")
+		if code, _, stderr := runApp(t, input, args...); code != exitTooSlow {
+			t.Fatalf("%s: exit code %d, expected %d\n%s", format, code, exitTooSlow, stderr)
+		}
+	}
+}
+
+func TestAppLCOV(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-cover.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, out, stderr := runApp(t, string(data), "-format", "lcov", "-quiet")
+	if code != exitOK {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !strings.HasPrefix(out, "TN:") || !strings.HasSuffix(out, "end_of_record\n") {
+		t.Fatalf("bad output:\n%s", out)
+	}
+}
+
+func TestAppCollapseSubtests(t *testing.T) {
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-subtests-mixed.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, out, stderr := runApp(t, string(data), "-collapse-subtests", "-fail")
+	if code != exitFailures {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !strings.Contains(out, `tests="3" errors="0" failures="1" skip="0"`) {
+		t.Fatalf("bad counts:\n%s", out)
+	}
+	if strings.Contains(out, `name="TestTable/`) || !strings.Contains(out, `name="subtests.collapsed" value="6"`) {
+		t.Fatalf("subtests not collapsed:\n%s", out)
+	}
+}
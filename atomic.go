@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is a file that is written to a temporary file in the same
+// directory and renamed to its name on Commit, so readers never see a partial
+// file. Close without Commit removes the temporary file.
+type atomicFile struct {
+	name string
+	tmp  *os.File
+	err  error // First write error
+	done bool
+}
+
+// createAtomic creates an atomicFile that will be called name
+func createAtomic(name string) (*atomicFile, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{name: name, tmp: tmp}, nil
+}
+
+// Write implements io.Writer, after an error all writes fail
+func (f *atomicFile) Write(data []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	n, err := f.tmp.Write(data)
+	if err != nil {
+		f.err = err
+	}
+	return n, err
+}
+
+// Commit syncs the temporary file and renames it to the file name. On error
+// the temporary file is removed and an existing file is not changed.
+func (f *atomicFile) Commit() error {
+	if f.done {
+		return nil
+	}
+	if f.err != nil {
+		f.Close()
+		return f.err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(f.name); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	for _, step := range []func() error{
+		f.tmp.Sync,
+		func() error { return f.tmp.Chmod(mode) },
+		f.tmp.Close,
+		func() error { return os.Rename(f.tmp.Name(), f.name) },
+	} {
+		if err := step(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	f.done = true
+	return nil
+}
+
+// Close removes the temporary file if the file wasn't committed
+func (f *atomicFile) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.tmp.Close()
+	return os.Remove(f.tmp.Name())
+}
+
+// writeFileAtomic writes data to the file called name using an atomicFile
+func writeFileAtomic(name string, data []byte) error {
+	file, err := createAtomic(name)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Commit()
+}
+
+// commitOutput commits w if it's an atomicFile, gzipOutput or uploadOutput
+func commitOutput(w io.Writer) error {
+	switch out := w.(type) {
+	case *atomicFile:
+		return out.Commit()
+	case *gzipOutput:
+		return out.Commit()
+	case *uploadOutput:
+		return out.Commit()
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "report.xml")
+	if err := ioutil.WriteFile(name, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write error partway through
+	file, err := createAtomic(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("<testsuite>")); err != nil {
+		t.Fatal(err)
+	}
+	file.tmp.Close()
+	if _, err := file.Write([]byte("</testsuite>")); err == nil {
+		t.Fatal("no error writing to closed file")
+	}
+	if err := file.Commit(); err == nil {
+		t.Fatal("no error committing after write error")
+	}
+	assertFiles(t, dir, name, "previous")
+
+	// Parse error before writing (Close without Commit)
+	code, _, _ := runApp(t, "no tests here\n", "-output", name)
+	if code != exitError {
+		t.Fatalf("exit code %d, expected %d", code, exitError)
+	}
+	assertFiles(t, dir, name, "previous")
+
+	if err := writeFileAtomic(name, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, dir, name, "new")
+}
+
+// assertFiles checks that the only file in dir is name, with content data
+func assertFiles(t *testing.T, dir, name, data string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != filepath.Base(name) {
+		t.Fatalf("bad files in %s: %v", dir, files)
+	}
+
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != data {
+		t.Fatalf("%s: got %q, expected %q", name, content, data)
+	}
+}
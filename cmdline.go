@@ -2,50 +2,841 @@
 package main
 
 import (
+	"compress/gzip"
 	"flag"
 	"fmt"
-	"os"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/tebeka/go2xunit/lib"
 )
 
-var args struct {
-	inFile      string
-	outFile     string
-	fail        bool
-	showVersion bool
-	bambooOut   bool
-	xunitnetOut bool
-	isGocheck   bool
-	suitePrefix string
+// cmdArgs are the command line arguments
+type cmdArgs struct {
+	inFile              string
+	outFile             string
+	outputGz            bool
+	gzipLevel           int
+	fail                bool
+	showVersion         bool
+	validate            bool
+	bambooOut           bool
+	xunitnetOut         bool
+	isGocheck           bool
+	isJSON              bool
+	suitePrefix         string
+	normalizeTS         bool
+	flakyRuns           string
+	format              string
+	baseline            string
+	failUnder           int
+	noExitCode          bool
+	slow                int
+	slowFormat          string
+	statusTimes         bool
+	collapseSubtests    bool
+	printShuffleSeed    bool
+	rerunPolicy         string
+	repeated            string
+	progress            string
+	summary             bool
+	quiet               bool
+	summaryFailures     int
+	histogram           bool
+	failOn              string
+	cdata               bool
+	topRollup           bool
+	indent              string
+	compact             bool
+	maxTestTime         time.Duration
+	maxSuiteTime        time.Duration
+	failOnSkip          bool
+	skipAsDisabled      bool
+	skipAllow           string
+	filter              lib.TestFilter
+	location            *time.Location
+	outputDir           string
+	split               string
+	versionInfo         bool
+	maxCases            int
+	maxBytes            int
+	splitByStatus       string
+	configFile          string
+	watch               bool
+	coverallsToken      string
+	regressionThreshold float64
+	benchThreshold      float64
+	maxTests            int
+	maxFailures         int
+	failFast            bool
+	csvNoHeader         bool
+	color               string
+	stream              bool
+	slackWebhook        string
+	uploadURL           string
+	uploadToken         string
+	webhook             string
+	webhookTimeout      time.Duration
+	webhookRequired     bool
+	webhookTemplate     string
+	props               []lib.Property
+	metricsFile         string
+	metricsPerTest      bool
+	importGraph         string
+	moduleRoot          string
+	history             string
+	historyLimit        int
+	tagMap              string
+	tags                map[string]string // Read from tagMap
+	serve               string
+	headers             http.Header
+	fetchTimeout        time.Duration
+	rawNames            bool
+	appendOutput        bool
+	attachmentsDir      string
+	attachmentsAll      bool
+	maxNameLength       int
+}
+
+// parseFunc returns the parser of the input, by -gocheck and -json
+func (args *cmdArgs) parseFunc() lib.ParseFunc {
+	switch {
+	case args.isGocheck:
+		return lib.ParseGocheck
+	case args.isJSON:
+		return lib.ParseTest2JSON
+	}
+	return lib.ParseGotest
+}
+
+// inputParseFunc returns the parser of the -input stream input, which is
+// parseFunc unless neither -json nor -gocheck is set and input is "go test
+// -json" output, and a reader of all of input
+func (args *cmdArgs) inputParseFunc(input io.Reader) (lib.ParseFunc, io.Reader) {
+	if args.isJSON || args.isGocheck {
+		return args.parseFunc(), input
+	}
+	isJSON, input := detectJSON(input)
+	if isJSON {
+		return lib.ParseTest2JSON, input
+	}
+	return args.parseFunc(), input
+}
+
+// sharded returns true if the report is split to several files by size
+func (args *cmdArgs) sharded() bool {
+	return args.maxCases > 0 || args.maxBytes > 0
+}
+
+// regexpFlag is a flag.Value of a regular expression
+type regexpFlag struct {
+	re **regexp.Regexp
+}
+
+func (f regexpFlag) String() string {
+	if f.re == nil || *f.re == nil {
+		return ""
+	}
+	return (*f.re).String()
+}
+
+func (f regexpFlag) Set(value string) error {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return err
+	}
+	*f.re = re
+	return nil
+}
+
+// propertyFlag is a flag.Value of NAME=VALUE properties, it can be given more
+// than once
+type propertyFlag struct {
+	props *[]lib.Property
+}
+
+func (f propertyFlag) String() string {
+	if f.props == nil {
+		return ""
+	}
+	var pairs []string
+	for _, prop := range *f.props {
+		pairs = append(pairs, prop.Name+"="+prop.Value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f propertyFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 1 {
+		return fmt.Errorf("should be NAME=VALUE")
+	}
+	*f.props = append(*f.props, lib.Property{Name: value[:i], Value: value[i+1:]})
+	return nil
+}
+
+// headerFlag is a flag.Value of "Name: value" HTTP headers, it can be given
+// more than once
+type headerFlag struct {
+	header *http.Header
+}
+
+func (f headerFlag) String() string {
+	if f.header == nil || *f.header == nil {
+		return ""
+	}
+	var headers []string
+	for name, values := range *f.header {
+		for _, value := range values {
+			headers = append(headers, name+": "+value)
+		}
+	}
+	return strings.Join(headers, ", ")
+}
+
+func (f headerFlag) Set(value string) error {
+	i := strings.Index(value, ":")
+	if i < 1 {
+		return fmt.Errorf("should be \"Name: value\"")
+	}
+	if *f.header == nil {
+		*f.header = make(http.Header)
+	}
+	f.header.Add(strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:]))
+	return nil
+}
+
+// progressForce is the -progress value to report progress even if stderr is
+// not a terminal
+const progressForce = "force"
+
+// progressFlag is a flag.Value of -progress, it's a boolean flag that also
+// accepts "force"
+type progressFlag struct {
+	value *string
 }
 
-func init() {
-	flag.StringVar(&args.inFile, "input", "", "input file (default to stdin)")
-	flag.StringVar(&args.outFile, "output", "", "output file (default to stdout)")
-	flag.BoolVar(&args.fail, "fail", false, "fail (non zero exit) if any test failed")
-	flag.BoolVar(&args.showVersion, "version", false, "print version and exit")
-	flag.BoolVar(&args.bambooOut, "bamboo", false,
+func (f progressFlag) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return *f.value
+}
+
+func (f progressFlag) Set(value string) error {
+	if value == progressForce {
+		*f.value = value
+		return nil
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("must be a boolean or %q", progressForce)
+	}
+	*f.value = ""
+	if on {
+		*f.value = "true"
+	}
+	return nil
+}
+
+func (f progressFlag) IsBoolFlag() bool { return true }
+
+// output formats
+var formats = map[string]bool{
+	"xunit":      true,
+	"diff":       true,
+	"coveralls":  true,
+	"csv":        true,
+	"yaml":       true,
+	"slack":      true,
+	"pretty":     true,
+	"bench-diff": true,
+	"cobertura":  true,
+	"lcov":       true,
+}
+
+// -color values
+var colorModes = map[string]bool{
+	"auto":   true,
+	"always": true,
+	"never":  true,
+}
+
+// locationFlag is a flag.Value of a time zone name
+type locationFlag struct {
+	loc **time.Location
+}
+
+func (f locationFlag) String() string {
+	if f.loc == nil || *f.loc == nil {
+		return ""
+	}
+	return (*f.loc).String()
+}
+
+func (f locationFlag) Set(value string) error {
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return err
+	}
+	*f.loc = loc
+	return nil
+}
+
+// newFlagSet returns a flag set that parses command line arguments into args,
+// errors and usage are printed to stderr
+func newFlagSet(args *cmdArgs, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("go2xunit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.StringVar(&args.configFile, "config", "",
+		"TOML configuration file (default "+lib.ConfigFile+" if found)")
+	fs.StringVar(&args.inFile, "input", "", "input file or HTTP(S) URL (default to stdin)")
+	fs.StringVar(&args.outFile, "output", "", "output file (default to stdout)")
+	fs.BoolVar(&args.outputGz, "output-gz", false, "gzip compress the output (default if -output ends with .gz)")
+	fs.IntVar(&args.gzipLevel, "gzip-level", 6, "gzip compression level (1 fastest to 9 best)")
+	fs.StringVar(&args.outputDir, "output-dir", "",
+		"write report files to this directory (with -split)")
+	fs.StringVar(&args.split, "split", "",
+		"with package, write a report file per package to -output-dir")
+	fs.StringVar(&args.splitByStatus, "split-by-status", "",
+		"also write passed.xml, failed.xml and skipped.xml reports to this directory")
+	fs.IntVar(&args.maxTests, "max-tests", 0,
+		"write only the first N tests (and their parent tests) to the report, 0 for all")
+	fs.IntVar(&args.maxFailures, "max-failures", -1,
+		"stop reading input after more than N failed tests (0 stops on the first failure), -1 for no limit")
+	fs.BoolVar(&args.failFast, "fail-fast", false,
+		"stop reading input after the first failed test (same as -max-failures=0)")
+	fs.IntVar(&args.maxCases, "max-cases-per-file", 0,
+		"split the report to numbered files (e.g. report-001.xml) of at most N tests")
+	fs.IntVar(&args.maxBytes, "max-bytes-per-file", 0,
+		"split the report to numbered files (e.g. report-001.xml) of about N bytes")
+	fs.BoolVar(&args.fail, "fail", true, "fail (non zero exit) if any test failed")
+	fs.BoolVar(&args.fail, "exit-code", true, "same as -fail")
+	fs.BoolVar(&args.noExitCode, "no-exit-code", false,
+		"exit with 0 on failed tests, even with -fail-under")
+	fs.StringVar(&args.failOn, "fail-on", "",
+		"with new-failures, fail (non zero exit) only if tests failing now passed in -baseline")
+	fs.IntVar(&args.failUnder, "fail-under", 0,
+		"fail (non zero exit) if pass rate is below N percent")
+	fs.Var(regexpFlag{&args.filter.Include}, "include",
+		"report only tests matching regexp (e.g. TestA/sub)")
+	fs.Var(regexpFlag{&args.filter.Exclude}, "exclude",
+		"don't report tests matching regexp")
+	fs.Var(regexpFlag{&args.filter.IncludePkg}, "include-pkg",
+		"report only packages matching regexp")
+	fs.Var(regexpFlag{&args.filter.ExcludePkg}, "exclude-pkg",
+		"don't report packages matching regexp")
+	fs.BoolVar(&args.failOnSkip, "fail-on-skip", false,
+		"report skipped tests as failed")
+	fs.BoolVar(&args.skipAsDisabled, "skip-as-disabled", false,
+		"report skipped tests as disabled")
+	fs.StringVar(&args.skipAllow, "skip-allow", "",
+		"file with tests (package/TestName globs, one per line) exempt from -fail-on-skip and -skip-as-disabled")
+	fs.BoolVar(&args.watch, "watch", false,
+		"convert -input again whenever it changes")
+	fs.BoolVar(&args.watch, "follow", false, "same as -watch")
+	fs.Var(headerFlag{&args.headers}, "header",
+		"\"Name: value\" HTTP header of an -input URL (can be repeated)")
+	fs.DurationVar(&args.fetchTimeout, "fetch-timeout", defaultFetchTimeout,
+		"timeout of reading an -input URL")
+	fs.StringVar(&args.serve, "serve", "",
+		"serve the report over HTTP on this address (e.g. :8080) instead of writing it")
+	fs.Var(progressFlag{&args.progress}, "progress", "report parsing progress to stderr if it's a terminal (\"force\" to always report)")
+	fs.BoolVar(&args.showVersion, "version", false, "print version and exit")
+	fs.BoolVar(&args.validate, "validate", false,
+		"check that the input is a well formed \"go test -json\" stream and print diagnostics instead of a report")
+	fs.BoolVar(&args.bambooOut, "bamboo", false,
 		"xml compatible with Atlassian's Bamboo")
-	flag.BoolVar(&args.xunitnetOut, "xunitnet", false, "xml compatible with xunit.net")
-	flag.BoolVar(&args.isGocheck, "gocheck", false, "parse gocheck output")
-	flag.BoolVar(&lib.Options.FailOnRace, "fail-on-race", false,
-		"mark test as failing if it exposes a data race")
-	flag.StringVar(&args.suitePrefix, "suite-name-prefix", "",
+	fs.BoolVar(&args.xunitnetOut, "xunitnet", false, "xml compatible with xunit.net")
+	fs.BoolVar(&args.isGocheck, "gocheck", false, "parse gocheck output")
+	fs.BoolVar(&args.isJSON, "json", false, "parse go test -json (or go tool test2json) output")
+	fs.StringVar(&lib.Options.PackageName, "package-name", "",
+		"package name of output without a package summary line (e.g. of a test binary)")
+	fs.BoolVar(&lib.Options.FailOnRace, "fail-on-race", true,
+		"mark tests that expose a data race as errored (-fail-on-race=false to report them as is)")
+	fs.BoolVar(&lib.Options.ShowEmptyPackages, "show-empty-packages", false,
+		"report packages without test files as empty suites")
+	fs.IntVar(&lib.Options.MaxOutputBytes, "max-output-bytes", 0,
+		"truncate output of each test to about N bytes (0 for no limit)")
+	fs.StringVar(&lib.Options.OutputFor, "output-for", "all",
+		"keep test output for fail, fail+skip or all tests")
+	fs.BoolVar(&lib.Options.IgnoreBuildOutput, "ignore-build-output", false,
+		"ignore go tool output (e.g. \"go: downloading ...\") in input, and non JSON lines with -json")
+	fs.IntVar(&lib.Options.ScannerBuffer, "scanner-buffer", lib.DefaultScannerBuffer,
+		"maximal input line length in bytes")
+	fs.BoolVar(&args.printShuffleSeed, "print-shuffle-seed", false,
+		"print the \"go test -shuffle\" seed of each package to stderr")
+	fs.BoolVar(&args.statusTimes, "status-times", false,
+		"add total time of tests by status as suite properties")
+	fs.BoolVar(&args.collapseSubtests, "collapse-subtests", false,
+		"report every top level test as one test case, with the worst status of its subtests")
+	fs.BoolVar(&args.cdata, "cdata", true,
+		"put test output in CDATA sections (XML escape it if false)")
+	fs.StringVar(&args.indent, "indent", "\t",
+		"indentation of XML output, \"\" writes it without re-indenting")
+	fs.BoolVar(&args.compact, "compact", false, "XML output without indentation")
+	fs.StringVar(&lib.Options.DurationFormat, "duration-format", "",
+		"format of times in XML output: seconds, ms, human or iso8601 (default seconds)")
+	fs.IntVar(&lib.Options.TimePrecision, "time-precision", lib.DefaultTimePrecision,
+		"digits after the decimal point of times in seconds")
+	fs.StringVar(&lib.Options.TotalTime, "total-time", "sum",
+		"<testsuites> time: sum of package times or wall clock time (only with -json)")
+	fs.BoolVar(&args.appendOutput, "append", false,
+		"merge results into the existing -output report (e.g. of a rerun of failed tests)")
+	fs.BoolVar(&args.rawNames, "raw-names", false,
+		"don't sanitize test and package names in XML output")
+	fs.IntVar(&args.maxNameLength, "max-name-length", lib.DefaultMaxNameLength,
+		"truncate longer test and package names in XML output, 0 for no limit")
+	fs.StringVar(&args.attachmentsDir, "attachments-dir", "",
+		"write output of failed tests to files in this directory and attach them (Jenkins JUnit Attachments)")
+	fs.BoolVar(&args.attachmentsAll, "attachments-all", false,
+		"with -attachments-dir, attach output of tests of all statuses")
+	fs.BoolVar(&args.versionInfo, "version-info", false,
+		"add go2xunit and Go versions and generation time as <testsuites> properties")
+	fs.StringVar(&lib.Options.XMLNamespace, "xmlns", "",
+		"default namespace (absolute URI) of the XML output")
+	fs.DurationVar(&args.maxTestTime, "max-test-time", 0,
+		"exit with 3 if a test took longer (e.g. 30s)")
+	fs.DurationVar(&args.maxSuiteTime, "max-suite-time", 0,
+		"exit with 3 if a package took longer (e.g. 5m)")
+	fs.StringVar(&args.suitePrefix, "suite-name-prefix", "",
 		"prefix to include before all suite names")
+	fs.BoolVar(&args.normalizeTS, "normalize-timestamps", false,
+		"convert package and test start times parsed from the input to UTC")
+	fs.Var(locationFlag{&args.location}, "timezone",
+		"report timestamps in this time zone: Local, UTC or Area/City (default UTC)")
+	fs.Var(locationFlag{&args.location}, "tz", "same as -timezone")
+	fs.StringVar(&args.format, "format", "xunit",
+		"output format (xunit, diff, coveralls, cobertura, lcov, csv, yaml, slack, pretty or bench-diff)")
+	fs.BoolVar(&args.csvNoHeader, "csv-no-header", false,
+		"don't write the header row of -format csv output")
+	fs.StringVar(&args.color, "color", "auto",
+		"color -format pretty output: auto (if stdout is a terminal and NO_COLOR isn't set), always or never")
+	fs.BoolVar(&args.stream, "stream", false,
+		"write -format pretty output as tests finish instead of after parsing")
+	fs.StringVar(&args.coverallsToken, "coveralls-token", "",
+		"Coveralls repo_token of -format coveralls output")
+	fs.StringVar(&args.slackWebhook, "slack-webhook", "",
+		"Slack incoming webhook URL to post -format slack output to")
+	fs.StringVar(&args.uploadURL, "upload-url", "",
+		"URL to upload the XML output to with HTTP PUT")
+	fs.StringVar(&args.uploadToken, "upload-token", "",
+		"Bearer token of -upload-url")
+	fs.StringVar(&args.webhook, "webhook", "",
+		"URL to post a JSON summary of the run to after the report is written")
+	fs.DurationVar(&args.webhookTimeout, "webhook-timeout", postTimeout,
+		"timeout of posting to -webhook (including retries)")
+	fs.BoolVar(&args.webhookRequired, "webhook-required", false,
+		"exit with an error if posting to -webhook fails (default is a warning)")
+	fs.StringVar(&args.webhookTemplate, "webhook-template", "",
+		"text/template file of the -webhook payload, executed with the run summary")
+	fs.Var(propertyFlag{&args.props}, "prop",
+		"NAME=VALUE property of every package in the report and label of -metrics-file series (can be repeated)")
+	fs.StringVar(&args.metricsFile, "metrics-file", "",
+		"write Prometheus metrics of the tests to this file (node_exporter textfile format)")
+	fs.BoolVar(&args.metricsPerTest, "metrics-per-test", false,
+		"add a -metrics-file series of the time of every test")
+	fs.StringVar(&args.importGraph, "import-graph", "",
+		"write the package import graph, colored by test status, to this Graphviz DOT file")
+	fs.StringVar(&args.moduleRoot, "module-root", ".",
+		"module root directory of -import-graph")
+	fs.StringVar(&args.history, "history", "",
+		"append a record of the run to this JSON lines file and compare with the previous one")
+	fs.IntVar(&args.historyLimit, "history-limit", 0,
+		"keep only the last N records of -history (0 keeps all)")
+	fs.StringVar(&args.tagMap, "tag-map", "",
+		"JSON file of package path to tag, packages are grouped by tag in <testsuites> elements")
+	fs.StringVar(&args.baseline, "baseline", "",
+		"output (or XML report) of a previous run to compare with")
+	fs.StringVar(&args.baseline, "compare", "", "same as -baseline")
+	fs.Float64Var(&args.regressionThreshold, "regression-threshold", lib.DefaultRegressionThreshold,
+		"report tests slower than this many times their -baseline time")
+	fs.Float64Var(&args.benchThreshold, "bench-threshold", lib.DefaultBenchmarkThreshold,
+		"report benchmarks of -format bench-diff that are this many times slower or faster than in -baseline")
+	fs.BoolVar(&args.summary, "summary", false, "print per package summary to stderr")
+	fs.BoolVar(&args.quiet, "quiet", false, "don't print the summary line to stderr after conversion")
+	fs.IntVar(&args.summaryFailures, "summary-failures", 10,
+		"failed tests listed after the summary line, -1 for all")
+	fs.BoolVar(&args.histogram, "histogram", false, "print the number of tests by run time to stderr")
+	fs.IntVar(&args.slow, "slow", 0, "print N slowest tests to stderr")
+	fs.IntVar(&args.slow, "top", 0, "same as -slow")
+	fs.BoolVar(&args.topRollup, "top-rollup", false,
+		"report top level tests (with their subtests) in -slow/-top")
+	fs.StringVar(&args.slowFormat, "slow-format", "table",
+		"format of -slow report (table or json)")
+	fs.StringVar(&args.rerunPolicy, "rerun-policy", lib.RerunAll,
+		"report tests that ran more than once: all, last, worst or flaky")
+	fs.StringVar(&args.repeated, "repeated", lib.RepeatSuffix,
+		"report tests that still ran more than once (see -rerun-policy) as suffix (name#N) or aggregate")
+	fs.StringVar(&args.flakyRuns, "flaky", "",
+		"comma separated outputs of previous runs, report flaky tests to stderr")
 
-	flag.Parse()
+	return fs
 }
 
-// validateArgs validates command line arguments
-func validateArgs() error {
-	if flag.NArg() > 0 {
-		return fmt.Errorf("%s does not take parameters (did you mean -input?)", os.Args[0])
+// envPrefix is the prefix of environment variables with flag values
+const envPrefix = "GO2XUNIT_"
+
+// envName returns the name of the environment variable of flag name (e.g.
+// GO2XUNIT_FAIL_UNDER for fail-under)
+func envName(name string) string {
+	name = strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	return envPrefix + strings.ToUpper(name)
+}
+
+// applyEnv sets flags that are not on the command line from environment
+// variables, lookup is os.LookupEnv
+func applyEnv(flags *flag.FlagSet, lookup func(string) (string, bool)) error {
+	set := setFlags(flags)
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %s", name, setErr)
+		}
+	})
+	return err
+}
+
+// applyConfig sets flags from config, flags given on the command line are not
+// changed
+func applyConfig(flags *flag.FlagSet, config *lib.Config) error {
+	set := setFlags(flags)
+	for _, cf := range config.Flags() {
+		if flags.Lookup(cf.Name) == nil {
+			return fmt.Errorf("unknown option: %q", cf.Name)
+		}
+		if set[cf.Name] {
+			continue
+		}
+		if err := flags.Set(cf.Name, cf.Value); err != nil {
+			return fmt.Errorf("%s: %s", cf.Name, err)
+		}
+	}
+	return nil
+}
+
+// setFlags returns the names of the flags given on the command line and of
+// their aliases, which are flags of the same variable (e.g. -top of -slow)
+func setFlags(flags *flag.FlagSet) map[string]bool {
+	var values []flag.Value
+	flags.Visit(func(f *flag.Flag) { values = append(values, f.Value) })
+
+	set := make(map[string]bool)
+	flags.VisitAll(func(f *flag.Flag) {
+		for _, value := range values {
+			if sameFlagValue(f.Value, value) {
+				set[f.Name] = true
+				return
+			}
+		}
+	})
+	return set
+}
+
+// sameFlagValue returns true if a and b set the same variable
+func sameFlagValue(a, b flag.Value) bool {
+	ta := reflect.TypeOf(a)
+	return ta == reflect.TypeOf(b) && ta.Comparable() && a == b
+}
+
+// validateArgs validates command line arguments, flags is the flag set that
+// parsed them
+func validateArgs(args *cmdArgs, flags *flag.FlagSet) error {
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%s does not take parameters (did you mean -input?)", flags.Name())
 	}
 
 	if args.bambooOut && args.xunitnetOut {
 		return fmt.Errorf("-bamboo and -xunitnet are mutually exclusive")
 	}
 
+	if (args.outputDir == "") != (args.split == "") {
+		return fmt.Errorf("-output-dir and -split must be used together")
+	}
+	if args.split != "" && args.split != "package" {
+		return fmt.Errorf("unknown split: %q", args.split)
+	}
+	if args.outputDir != "" && args.outFile != "" {
+		return fmt.Errorf("-output and -output-dir are mutually exclusive")
+	}
+
+	if args.watch && (args.inFile == "" || args.inFile == "-") {
+		return fmt.Errorf("-watch requires -input")
+	}
+	if args.watch && isURL(args.inFile) {
+		return fmt.Errorf("-watch requires an -input file, not a URL")
+	}
+	if args.headers != nil && !isURL(args.inFile) {
+		return fmt.Errorf("-header requires an -input URL")
+	}
+	if args.serve != "" && (args.outFile != "" || args.outputDir != "" || args.validate) {
+		return fmt.Errorf("-serve can't be used with -output, -output-dir or -validate")
+	}
+
+	if args.isGocheck && args.isJSON {
+		return fmt.Errorf("-gocheck and -json are mutually exclusive")
+	}
+
+	if args.failFast {
+		if args.maxFailures > 0 {
+			return fmt.Errorf("-fail-fast and -max-failures are exclusive")
+		}
+		args.maxFailures = 0
+	}
+	if args.maxTests < 0 {
+		return fmt.Errorf("-max-tests must be positive")
+	}
+	if args.maxCases < 0 || args.maxBytes < 0 {
+		return fmt.Errorf("-max-cases-per-file and -max-bytes-per-file must be positive")
+	}
+	if args.sharded() {
+		if args.outFile == "" || args.outFile == "-" {
+			return fmt.Errorf("-max-cases-per-file and -max-bytes-per-file require -output")
+		}
+		if args.outputDir != "" {
+			return fmt.Errorf("-max-cases-per-file and -max-bytes-per-file can't be used with -output-dir")
+		}
+	}
+
+	if args.normalizeTS && args.location != nil {
+		return fmt.Errorf("-normalize-timestamps and -timezone are mutually exclusive")
+	}
+
+	if args.failUnder < 0 || args.failUnder > 100 {
+		return fmt.Errorf("-fail-under must be between 0 and 100")
+	}
+
+	if lib.Options.ScannerBuffer <= 0 {
+		return fmt.Errorf("-scanner-buffer must be positive")
+	}
+
+	switch lib.Options.OutputFor {
+	case "fail", "fail+skip", "all":
+	default:
+		return fmt.Errorf("unknown -output-for value - %q", lib.Options.OutputFor)
+	}
+
+	if args.slow < 0 {
+		return fmt.Errorf("-slow must be positive")
+	}
+
+	if args.slowFormat != "table" && args.slowFormat != "json" {
+		return fmt.Errorf("unknown -slow-format value - %q", args.slowFormat)
+	}
+
+	switch args.rerunPolicy {
+	case lib.RerunAll, lib.RerunLast, lib.RerunWorst, lib.RerunFlaky:
+	default:
+		return fmt.Errorf("unknown -rerun-policy value - %q", args.rerunPolicy)
+	}
+
+	if args.repeated != lib.RepeatSuffix && args.repeated != lib.RepeatAggregate {
+		return fmt.Errorf("unknown -repeated value - %q", args.repeated)
+	}
+
+	if !formats[args.format] {
+		return fmt.Errorf("unknown format - %q", args.format)
+	}
+
+	if args.maxTestTime < 0 || args.maxSuiteTime < 0 {
+		return fmt.Errorf("-max-test-time and -max-suite-time must be positive")
+	}
+
+	if f := lib.Options.DurationFormat; f != "" && !lib.DurationFormats[f] {
+		return fmt.Errorf("unknown duration format: %q", f)
+	}
+
+	if p := lib.Options.TimePrecision; p < 0 || p > lib.MaxTimePrecision {
+		return fmt.Errorf("-time-precision must be between 0 and %d", lib.MaxTimePrecision)
+	}
+
+	if f := lib.Options.TotalTime; !lib.TotalTimeFormats[f] {
+		return fmt.Errorf("unknown total time: %q", f)
+	}
+
+	if ns := lib.Options.XMLNamespace; ns != "" {
+		if u, err := url.ParseRequestURI(ns); err != nil || !u.IsAbs() {
+			return fmt.Errorf("-xmlns: %q is not an absolute URI", ns)
+		}
+	}
+
+	if args.failOnSkip && args.skipAsDisabled {
+		return fmt.Errorf("-fail-on-skip and -skip-as-disabled are mutually exclusive")
+	}
+	if args.skipAllow != "" && !args.failOnSkip && !args.skipAsDisabled {
+		return fmt.Errorf("-skip-allow requires -fail-on-skip or -skip-as-disabled")
+	}
+
+	if args.compact && setFlags(flags)["indent"] {
+		return fmt.Errorf("-compact and -indent are mutually exclusive")
+	}
+
+	if args.failOn != "" && args.failOn != "new-failures" {
+		return fmt.Errorf("unknown -fail-on value - %q", args.failOn)
+	}
+
+	if args.failOn != "" && args.baseline == "" {
+		return fmt.Errorf("-fail-on requires -baseline")
+	}
+
+	if (args.format == "diff" || args.format == "bench-diff") && args.baseline == "" {
+		return fmt.Errorf("-format %s requires -baseline", args.format)
+	}
+	if args.benchThreshold < 1 {
+		return fmt.Errorf("-bench-threshold must be at least 1")
+	}
+
+	if args.regressionThreshold <= 0 {
+		return fmt.Errorf("-regression-threshold must be positive")
+	}
+
+	if n := args.maxNameLength; n != 0 && n < lib.MinMaxNameLength {
+		return fmt.Errorf("-max-name-length must be 0 or at least %d", lib.MinMaxNameLength)
+	}
+
+	if args.appendOutput {
+		switch {
+		case args.outFile == "" || args.outFile == "-":
+			return fmt.Errorf("-append requires -output")
+		case args.format != "xunit" || args.xunitnetOut:
+			return fmt.Errorf("-append requires xunit output")
+		case args.outputDir != "" || args.sharded():
+			return fmt.Errorf("-append can't be used with -output-dir or -max-*-per-file")
+		}
+	}
+
+	if args.gzipLevel < gzip.BestSpeed || args.gzipLevel > gzip.BestCompression {
+		return fmt.Errorf("-gzip-level must be between %d and %d", gzip.BestSpeed, gzip.BestCompression)
+	}
+	if args.gzipped() && (args.appendOutput || args.outputDir != "" || args.sharded()) {
+		return fmt.Errorf("gzip output can't be used with -append, -output-dir or -max-*-per-file")
+	}
+
+	if args.attachmentsAll && args.attachmentsDir == "" {
+		return fmt.Errorf("-attachments-all requires -attachments-dir")
+	}
+	if args.attachmentsDir != "" && args.format != "xunit" {
+		return fmt.Errorf("-attachments-dir requires -format xunit")
+	}
+
+	if args.csvNoHeader && args.format != "csv" {
+		return fmt.Errorf("-csv-no-header requires -format csv")
+	}
+	if !colorModes[args.color] {
+		return fmt.Errorf("bad -color: %q (should be auto, always or never)", args.color)
+	}
+	if args.stream && args.format != "pretty" {
+		return fmt.Errorf("-stream requires -format pretty")
+	}
+	if (args.format == "csv" || args.format == "yaml" || args.format == "pretty") && (args.outputDir != "" || args.sharded()) {
+		return fmt.Errorf("-format %s can't be used with -output-dir or -max-*-per-file", args.format)
+	}
+
+	if args.coverallsToken != "" && args.format != "coveralls" {
+		return fmt.Errorf("-coveralls-token requires -format coveralls")
+	}
+
+	if args.uploadURL != "" {
+		if args.format != "xunit" {
+			return fmt.Errorf("-upload-url requires -format xunit")
+		}
+		if args.outputDir != "" || args.sharded() {
+			return fmt.Errorf("-upload-url can't be used with -output-dir or -max-*-per-file")
+		}
+		if u, err := url.ParseRequestURI(args.uploadURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("-upload-url must be an http(s) URL: %q", args.uploadURL)
+		}
+	}
+	if args.metricsFile != "" {
+		if err := lib.CheckPrometheusLabels(args.props); err != nil {
+			return fmt.Errorf("-prop: %s", err)
+		}
+	} else if args.metricsPerTest {
+		return fmt.Errorf("-metrics-per-test requires -metrics-file")
+	}
+
+	if args.tagMap != "" && (args.format != "xunit" || args.xunitnetOut) {
+		return fmt.Errorf("-tag-map requires -format xunit (not -xunitnet)")
+	}
+
+	if args.historyLimit < 0 {
+		return fmt.Errorf("-history-limit can't be negative")
+	}
+	if args.historyLimit > 0 && args.history == "" {
+		return fmt.Errorf("-history-limit requires -history")
+	}
+
+	if args.webhook != "" {
+		if u, err := url.ParseRequestURI(args.webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("-webhook must be an http(s) URL: %q", args.webhook)
+		}
+		if args.webhookTimeout <= 0 {
+			return fmt.Errorf("-webhook-timeout must be positive")
+		}
+	} else if args.webhookRequired || args.webhookTemplate != "" {
+		return fmt.Errorf("-webhook-required and -webhook-template require -webhook")
+	}
+
+	if args.uploadToken != "" && args.uploadURL == "" {
+		return fmt.Errorf("-upload-token requires -upload-url")
+	}
+
+	if args.slackWebhook != "" {
+		if args.format != "slack" {
+			return fmt.Errorf("-slack-webhook requires -format slack")
+		}
+		if u, err := url.ParseRequestURI(args.slackWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("-slack-webhook must be an http(s) URL: %q", args.slackWebhook)
+		}
+	}
+
 	return nil
 }
+
+// mergeArgs are the "merge" sub command arguments
+type mergeArgs struct {
+	outFile     string
+	isGocheck   bool
+	isJSON      bool
+	bambooOut   bool
+	xunitnetOut bool
+	suitePrefix string
+}
+
+// newMergeFlagSet returns a flag set that parses "merge" sub command
+// arguments into args, errors and usage are printed to stderr
+func newMergeFlagSet(args *mergeArgs, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("go2xunit merge", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: go2xunit merge [options] FILE...\n")
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&args.outFile, "output", "", "output file (default to stdout)")
+	fs.BoolVar(&args.isGocheck, "gocheck", false, "non XML inputs are gocheck output")
+	fs.BoolVar(&args.isJSON, "json", false, "non XML inputs are go test -json (or go tool test2json) output")
+	fs.BoolVar(&args.bambooOut, "bamboo", false,
+		"xml compatible with Atlassian's Bamboo")
+	fs.BoolVar(&args.xunitnetOut, "xunitnet", false, "xml compatible with xunit.net")
+	fs.StringVar(&args.suitePrefix, "suite-name-prefix", "",
+		"prefix to include before all suite names")
+
+	return fs
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultFetchTimeout is the default -fetch-timeout
+const defaultFetchTimeout = 5 * time.Minute
+
+// isURL returns true if the input name is an HTTP(S) URL
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// urlReader is the body of a fetched input, read errors name the URL and the
+// number of bytes read
+type urlReader struct {
+	url  string
+	body io.ReadCloser
+	r    io.Reader
+	n    int64
+}
+
+// Read implements io.Reader
+func (u *urlReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.n += int64(n)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%s: read failed after %d bytes: %s", u.url, u.n, err)
+	}
+	return n, err
+}
+
+// Close closes the response body
+func (u *urlReader) Close() error {
+	return u.body.Close()
+}
+
+// fetchInput returns the body of url as a stream, with -header headers.
+// Redirects are followed and gzip encoded bodies are decompressed.
+func fetchInput(url string, header http.Header, timeout time.Duration) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	u := &urlReader{url: url, body: resp.Body, r: resp.Body}
+	// The transport decompresses only if it asked for gzip, not if
+	// Accept-Encoding is a -header
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: %s", url, err)
+		}
+		u.r = gz
+	}
+	return u, nil
+}
@@ -1,3 +1,8 @@
 module github.com/br3nda/go2xunit
 
 go 1.13
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// gzipOutput is an output that is gzip compressed to w, commitOutput writes
+// the gzip footer before committing w
+type gzipOutput struct {
+	gz *gzip.Writer
+	w  io.Writer
+}
+
+// newGzipOutput returns an output that compresses to w with level
+func newGzipOutput(w io.Writer, level int) (*gzipOutput, error) {
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &gzipOutput{gz: gz, w: w}, nil
+}
+
+// Write implements io.Writer
+func (g *gzipOutput) Write(data []byte) (int, error) {
+	return g.gz.Write(data)
+}
+
+// Commit flushes the compressed data and commits the underlying output
+func (g *gzipOutput) Commit() error {
+	if err := g.gz.Close(); err != nil {
+		return err
+	}
+	return commitOutput(g.w)
+}
+
+// gzipped returns true if the output should be gzip compressed, with
+// -output-gz or an -output file name ending with .gz
+func (args *cmdArgs) gzipped() bool {
+	return args.outputGz || strings.HasSuffix(args.outFile, ".gz")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+const (
+	// historyLockTimeout is how long updateHistory waits for another run to
+	// release the history lock file
+	historyLockTimeout = 10 * time.Second
+	// historyLockPoll is the time between attempts to take the lock file
+	historyLockPoll = 20 * time.Millisecond
+)
+
+// readHistory reads the entries of history file name (none if it doesn't
+// exist), corrupted lines are skipped with a warning
+func (app *App) readHistory(name string) ([]lib.HistoryEntry, error) {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, bad, err := lib.ReadHistory(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	for _, line := range bad {
+		app.log.Printf("warning: %s:%d: skipped corrupted history entry", name, line)
+	}
+	return entries, nil
+}
+
+// updateHistory appends the entry of suites to the -history file, the entry
+// is written with a single append so concurrent runs don't interleave. The
+// file is trimmed to the last -history-limit entries. Trimming rewrites the
+// file, so runs that share it hold its lock file (see lockHistory) while
+// appending and trimming, an entry appended during a rewrite would be lost.
+// It returns a summary of the changes since the previous entry ("" if there's
+// none).
+func (app *App) updateHistory(args *cmdArgs, suites lib.Suites, runTime time.Time) (string, error) {
+	unlock, err := lockHistory(args.history)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	entries, err := app.readHistory(args.history)
+	if err != nil {
+		return "", err
+	}
+
+	entry := lib.NewHistoryEntry(suites, runTime)
+	data, err := entry.MarshalLine()
+	if err != nil {
+		return "", err
+	}
+	file, err := os.OpenFile(args.history, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	if args.historyLimit > 0 && len(entries)+1 > args.historyLimit {
+		if err := app.trimHistory(args.history, args.historyLimit); err != nil {
+			return "", err
+		}
+	}
+
+	if len(entries) == 0 {
+		return "", nil
+	}
+	prev := entries[len(entries)-1]
+	regressed, fixed := lib.CompareHistory(prev, entry)
+	return fmt.Sprintf("go2xunit: %d regressed, %d fixed since %s", regressed, fixed,
+		prev.Time.In(lib.Options.Location).Format(time.RFC3339)), nil
+}
+
+// lockHistory creates the lock file of history file name (name.lock), waiting
+// up to historyLockTimeout for another run to remove it. The returned
+// function removes the lock file. A lock file left by a run that crashed has
+// to be removed by hand.
+func lockHistory(name string) (func(), error) {
+	lockName := name + ".lock"
+	deadline := time.Now().Add(historyLockTimeout)
+	for {
+		file, err := os.OpenFile(lockName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockName) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another run (remove %s if it's stale)", name, lockName)
+		}
+		time.Sleep(historyLockPoll)
+	}
+}
+
+// trimHistory rewrites history file name with its last limit entries,
+// corrupted lines are dropped. It must be called with the history lock held.
+func (app *App) trimHistory(name string, limit int) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	entries, _, err := lib.ReadHistory(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		data, err := entry.MarshalLine()
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return writeFileAtomic(name, buf.Bytes())
+}
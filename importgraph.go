@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// packageLister lists the packages of a module
+type packageLister interface {
+	List(root string) ([]lib.GoPackage, error)
+}
+
+// goList is a packageLister that runs "go list -json ./..." in the module
+// root directory
+type goList struct{}
+
+// List implements packageLister
+func (goList) List(root string) ([]lib.GoPackage, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", "list", "-json", "./...")
+	cmd.Dir = root
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list in %s: %s %s", root, err, strings.TrimSpace(stderr.String()))
+	}
+	return decodeGoList(bytes.NewReader(out))
+}
+
+// decodeGoList decodes "go list -json" output, a stream of JSON objects
+func decodeGoList(rd io.Reader) ([]lib.GoPackage, error) {
+	var packages []lib.GoPackage
+	dec := json.NewDecoder(rd)
+	for {
+		var pkg lib.GoPackage
+		err := dec.Decode(&pkg)
+		if err == io.EOF {
+			return packages, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad go list output: %s", err)
+		}
+		packages = append(packages, pkg)
+	}
+}
+
+// writeImportGraph writes the import graph of the -module-root packages,
+// annotated with the status of suites, to the -import-graph file
+func (app *App) writeImportGraph(args *cmdArgs, suites lib.Suites) error {
+	packages, err := app.lister.List(args.moduleRoot)
+	if err != nil {
+		return err
+	}
+
+	// Packages are matched by import path, without -suite-name-prefix
+	var unprefixed lib.Suites
+	for _, suite := range suites {
+		clone := *suite
+		clone.Name = strings.TrimPrefix(suite.Name, args.suitePrefix)
+		unprefixed = append(unprefixed, &clone)
+	}
+
+	var buf bytes.Buffer
+	if err := lib.WriteImportGraph(&buf, packages, unprefixed); err != nil {
+		return err
+	}
+	return writeFileAtomic(args.importGraph, buf.Bytes())
+}
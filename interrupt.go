@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// IncompleteProperty is the suite property set when the report was written
+// after an interrupt, before all input was read
+const IncompleteProperty = "incomplete"
+
+// interruptReader is an io.Reader that ends (with io.EOF) when interrupt is
+// closed, even if a Read of the underlying reader is blocked
+type interruptReader struct {
+	r           io.Reader // lib.NewContextReader of the underlying reader
+	interrupted bool
+}
+
+// interruptContext is a context that is canceled when interrupt is closed
+type interruptContext struct {
+	context.Context
+	interrupt <-chan struct{}
+}
+
+func (ctx interruptContext) Done() <-chan struct{} {
+	return ctx.interrupt
+}
+
+func (ctx interruptContext) Err() error {
+	select {
+	case <-ctx.interrupt:
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+// newInterruptReader returns r that ends when interrupt is closed
+func newInterruptReader(r io.Reader, interrupt <-chan struct{}) *interruptReader {
+	ctx := interruptContext{context.Background(), interrupt}
+	return &interruptReader{r: lib.NewContextReader(ctx, r)}
+}
+
+// Read implements io.Reader
+func (ir *interruptReader) Read(b []byte) (int, error) {
+	if ir.interrupted {
+		return 0, io.EOF
+	}
+	n, err := ir.r.Read(b)
+	if err == context.Canceled {
+		ir.interrupted = true
+		return n, io.EOF
+	}
+	return n, err
+}
+
+// Interrupted returns true if reading was interrupted
+func (ir *interruptReader) Interrupted() bool {
+	return ir.interrupted
+}
+
+// notifyInterrupt returns a channel that is closed on the first SIGINT or
+// SIGTERM, on the second one the program exits with exitInterrupted
+func notifyInterrupt() <-chan struct{} {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	interrupt := make(chan struct{})
+	go func() {
+		<-signals
+		close(interrupt)
+		<-signals
+		os.Exit(exitInterrupted)
+	}()
+	return interrupt
+}
@@ -0,0 +1,68 @@
+package lib_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// TestAPI uses the package the way external tools do
+func TestAPI(t *testing.T) {
+	file, err := os.Open("../_data/in/gotest-1.7.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	suites, err := lib.ParseGotest(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) == 0 {
+		t.Fatal("no suites")
+	}
+
+	var subtests int
+	for _, suite := range suites {
+		if suite.Name == "" {
+			t.Fatal("suite without a package name")
+		}
+		for _, test := range suite.Tests {
+			if test.Name == "" || test.Status == lib.UnknownStatus {
+				t.Fatalf("%s: bad test: %+v", suite.Name, test)
+			}
+			if test.Elapsed() < 0 {
+				t.Fatalf("%s: negative elapsed time", test.Name)
+			}
+			if strings.Contains(test.Name, "/") {
+				subtests++
+			}
+		}
+	}
+	if subtests == 0 {
+		t.Fatal("no subtests found")
+	}
+
+	var buf bytes.Buffer
+	if err := lib.WriteJUnit(&buf, suites, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := lib.ParseXUnit(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, stats := suites.Stats(), parsed.Stats()
+	for status, n := range expected {
+		if stats[status] != n {
+			t.Fatalf("%d %s tests after round trip, expected %d", stats[status], status, n)
+		}
+	}
+
+	if err := lib.WriteJUnit(&buf, nil, time.Now()); err == nil {
+		t.Fatal("no error writing no suites")
+	}
+}
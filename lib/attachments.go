@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxAttachmentName is the maximal length of attachment file names
+const maxAttachmentName = 200
+
+// AttachmentMarker returns the Jenkins JUnit Attachments plugin marker of the
+// file in path
+func AttachmentMarker(path string) string {
+	return fmt.Sprintf("[[ATTACHMENT|%s]]", path)
+}
+
+// WriteAttachments writes the output of failed (and errored) tests in suites,
+// or of all tests with output if all is set, to a file per test in dir
+// (created if needed) and adds the absolute file paths to the test
+// Attachments. Existing files referenced by "fuzz.input" properties of these
+// tests are attached as well.
+func WriteAttachments(suites Suites, dir string, all bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			failed := test.Status == Failed || test.Status == Errored
+			if !failed && !all {
+				continue
+			}
+
+			if test.Message != "" {
+				name := uniqueName(attachmentName(suite.Name+"."+test.Name), names, 0)
+				path := filepath.Join(dir, name+".txt")
+				if err := ioutil.WriteFile(path, []byte(test.Message+"\n"), 0644); err != nil {
+					return err
+				}
+				test.Attachments = append(test.Attachments, path)
+			}
+
+			for _, prop := range test.Properties {
+				if prop.Name != "fuzz.input" {
+					continue
+				}
+				path, err := filepath.Abs(prop.Value)
+				if err != nil {
+					continue
+				}
+				if _, err := os.Stat(path); err == nil {
+					test.Attachments = append(test.Attachments, path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// attachmentName returns name with characters other than letters, digits,
+// ".", "-" and "_" replaced with "_", truncated to maxAttachmentName. It's in
+// lower case so names are unique on case insensitive file systems as well.
+func attachmentName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, name)
+	return sanitizeName(name, maxAttachmentName)
+}
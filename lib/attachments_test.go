@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAttachments(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	input := filepath.Join(tmp, "crash")
+	if err := ioutil.WriteFile(input, []byte("go test fuzz v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	suites := Suites{{Name: "example.com/a", Tests: []*Test{
+		{Name: "TestOK", Status: Passed, Message: "fine"},
+		{Name: "TestTable/A", Status: Failed, Message: "a_test.go:1: boom"},
+		{Name: "TestTable/a", Status: Failed, Message: "a_test.go:2: boom"},
+		{Name: "FuzzX", Status: Failed, Message: "fuzz boom", Properties: []Property{
+			{"fuzz.input", input},
+			{"fuzz.input", filepath.Join(tmp, "missing")},
+		}},
+	}}}
+
+	dir := filepath.Join(tmp, "attachments", "new")
+	if err := WriteAttachments(suites, dir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := suites[0].Tests
+	if len(tests[0].Attachments) != 0 {
+		t.Fatalf("passed test attached: %v", tests[0].Attachments)
+	}
+	for _, test := range tests[1:] {
+		path := test.Attachments[0]
+		if !filepath.IsAbs(path) || filepath.Dir(path) != dir {
+			t.Fatalf("%s: bad attachment path %q", test.Name, path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(data)) != test.Message {
+			t.Fatalf("%s: attached %q", test.Name, data)
+		}
+	}
+	if tests[1].Attachments[0] == tests[2].Attachments[0] {
+		t.Fatalf("same attachment of TestTable/A and TestTable/a: %s", tests[1].Attachments[0])
+	}
+	if fuzz := tests[3]; len(fuzz.Attachments) != 2 || fuzz.Attachments[1] != input {
+		t.Fatalf("bad fuzz attachments: %v", fuzz.Attachments)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, suites, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests[1:] {
+		marker := AttachmentMarker(test.Attachments[0])
+		if !strings.Contains(buf.String(), marker) {
+			t.Fatalf("missing %s in:\n%s", marker, buf.String())
+		}
+	}
+
+	if err := WriteAttachments(suites, dir, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(tests[0].Attachments) != 1 {
+		t.Fatalf("passed test not attached with all")
+	}
+}
@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultBenchmarkThreshold is the default ratio of current to baseline ns/op
+// (or baseline to current) that WriteBenchmarkDiff reports
+const DefaultBenchmarkThreshold = 1.1
+
+// BenchmarkChange is the change of a benchmark compared to a baseline run
+type BenchmarkChange struct {
+	Package         string
+	Test            *Test
+	BaselineNsPerOp float64
+	CurrentNsPerOp  float64
+	Ratio           float64 // CurrentNsPerOp/BaselineNsPerOp
+	Improved        bool    // Faster than in baseline
+}
+
+// BenchmarkDiff returns the ns/op changes of benchmarks in current compared
+// to baseline, the biggest change (abs(Ratio - 1)) first. Benchmarks are
+// matched by suite and test name, benchmarks with no ns/op in either run are
+// ignored.
+func BenchmarkDiff(baseline, current Suites) []BenchmarkChange {
+	prev := testsByKey(baseline)
+	var changes []BenchmarkChange
+	for _, suite := range current {
+		for _, test := range suite.Tests {
+			old := prev[testKey{suite.Name, test.Name}]
+			if test.Benchmark == nil || old == nil || old.Benchmark == nil ||
+				test.Benchmark.NsPerOp <= 0 || old.Benchmark.NsPerOp <= 0 {
+				continue
+			}
+			ratio := test.Benchmark.NsPerOp / old.Benchmark.NsPerOp
+			changes = append(changes, BenchmarkChange{
+				Package:         suite.Name,
+				Test:            test,
+				BaselineNsPerOp: old.Benchmark.NsPerOp,
+				CurrentNsPerOp:  test.Benchmark.NsPerOp,
+				Ratio:           ratio,
+				Improved:        ratio < 1,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return math.Abs(changes[i].Ratio-1) > math.Abs(changes[j].Ratio-1)
+	})
+	return changes
+}
+
+// WriteBenchmarkDiff writes changes as a Markdown table, only changes that are
+// at least threshold times slower or faster
+func WriteBenchmarkDiff(w io.Writer, changes []BenchmarkChange, threshold float64) error {
+	var buf strings.Builder
+	buf.WriteString("| Benchmark | Baseline ns/op | Current ns/op | Change |\n")
+	buf.WriteString("|---|---:|---:|---:|\n")
+	for _, change := range changes {
+		if change.Ratio < threshold && change.Ratio > 1/threshold {
+			continue
+		}
+		name := change.Test.Name
+		if change.Package != "" {
+			name = change.Package + "/" + name
+		}
+		fmt.Fprintf(&buf, "| %s | %s | %s | %+.1f%% |\n", strings.Replace(name, "|", `\|`, -1),
+			formatNsPerOp(change.BaselineNsPerOp), formatNsPerOp(change.CurrentNsPerOp), (change.Ratio-1)*100)
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// formatNsPerOp formats ns/op like "go test -bench"
+func formatNsPerOp(ns float64) string {
+	if ns >= 100 {
+		return fmt.Sprintf("%.0f", ns)
+	}
+	return fmt.Sprintf("%.4g", ns)
+}
@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBenchmarkDiff(t *testing.T) {
+	bench := func(name string, ns float64) *Test {
+		return &Test{Name: name, Status: Passed, Benchmark: &BenchmarkResult{NsPerOp: ns}}
+	}
+	baseline := Suites{{Name: "pkg", Tests: []*Test{
+		bench("BenchmarkA", 100),
+		bench("BenchmarkB", 200),
+		bench("BenchmarkC", 50),
+		bench("BenchmarkD", 10),
+		{Name: "TestA", Status: Passed},
+	}}}
+	current := Suites{{Name: "pkg", Tests: []*Test{
+		bench("BenchmarkA", 105),
+		bench("BenchmarkB", 100),
+		bench("BenchmarkC", 75),
+		bench("BenchmarkNew", 10),
+		{Name: "TestA", Status: Passed},
+	}}}
+
+	changes := BenchmarkDiff(baseline, current)
+	expected := []struct {
+		name     string
+		ratio    float64
+		improved bool
+	}{{"BenchmarkB", 0.5, true}, {"BenchmarkC", 1.5, false}, {"BenchmarkA", 1.05, false}}
+	if len(changes) != len(expected) {
+		t.Fatalf("%d changes, expected %d: %+v", len(changes), len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.Test.Name != expected[i].name || math.Abs(change.Ratio-expected[i].ratio) > 1e-9 ||
+			change.Improved != expected[i].improved {
+			t.Fatalf("change %d: %s %f %v, expected %+v", i, change.Test.Name, change.Ratio, change.Improved, expected[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBenchmarkDiff(&buf, changes, DefaultBenchmarkThreshold); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[2] != "| pkg/BenchmarkB | 200 | 100 | -50.0% |" || lines[3] != "| pkg/BenchmarkC | 50 | 75 | +50.0% |" {
+		t.Fatalf("bad table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCobertura(t *testing.T) {
+	filename := "../_data/in/gotest-cover-multi.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCobertura(&buf, suites, time.Unix(1600000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<!DOCTYPE coverage") || !strings.HasSuffix(buf.String(), "</coverage>\n") {
+		t.Fatalf("bad report:\n%s", buf.String())
+	}
+
+	var report struct {
+		XMLName   xml.Name
+		LineRate  string `xml:"line-rate,attr"`
+		Timestamp int64  `xml:"timestamp,attr"`
+		Packages  []struct {
+			Name     string `xml:"name,attr"`
+			LineRate string `xml:"line-rate,attr"`
+			Classes  []struct {
+				LineRate string `xml:"line-rate,attr"`
+			} `xml:"classes>class"`
+		} `xml:"packages>package"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("bad XML: %s\n%s", err, buf.String())
+	}
+	if report.XMLName.Local != "coverage" || report.Timestamp != 1600000000000 {
+		t.Fatalf("bad root: %+v", report)
+	}
+
+	expected := map[string]string{
+		"example.com/mmath": "0.7860",
+		"example.com/parse": "0.5000",
+	}
+	if len(report.Packages) != len(expected) {
+		t.Fatalf("%d packages, expected %d\n%s", len(report.Packages), len(expected), buf.String())
+	}
+	for _, pkg := range report.Packages {
+		if pkg.LineRate != expected[pkg.Name] || len(pkg.Classes) != 1 || pkg.Classes[0].LineRate != pkg.LineRate {
+			t.Fatalf("%s: bad line-rate %q, expected %q", pkg.Name, pkg.LineRate, expected[pkg.Name])
+		}
+	}
+	// (79 + 50) / 200 lines
+	if report.LineRate != "0.6450" {
+		t.Fatalf("bad line-rate: %q", report.LineRate)
+	}
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CollapsedSubtestsProperty is the test property with the number of
+// subtests folded into a top level test by CollapseSubtests
+const CollapsedSubtestsProperty = "subtests.collapsed"
+
+// statusSeverity orders statuses for CollapseSubtests, a higher severity
+// wins. Skipped is below Passed so a test is skipped only if all of it is.
+var statusSeverity = map[Status]int{
+	Skipped:       0,
+	Passed:        1,
+	UnknownStatus: 2,
+	Failed:        3,
+	Errored:       4,
+}
+
+// CollapseSubtests folds the subtests of every top level test in s into the
+// test, which becomes a leaf. The test status is the worst of the test and
+// its subtests, its time is its own and its output is followed by the output
+// of failed (or errored) subtests, each after a line with the subtest name.
+// The number of folded subtests is kept in a CollapsedSubtestsProperty
+// property. Subtests without a top level test (e.g. filtered out) are kept.
+func (s Suites) CollapseSubtests() {
+	for _, suite := range s {
+		parents := make(map[string]*Test)
+		counts := make(map[*Test]int)
+		hasSubtests := make(map[string]bool)
+		for _, test := range suite.Tests {
+			prefixes := namePrefixes(test.Name)
+			for _, prefix := range prefixes[:len(prefixes)-1] {
+				hasSubtests[prefix] = true
+			}
+		}
+
+		var tests []*Test
+		for _, test := range suite.Tests {
+			i := strings.Index(test.Name, "/")
+			if i < 0 {
+				parents[test.Name] = test
+				tests = append(tests, test)
+				continue
+			}
+			parent, ok := parents[test.Name[:i]]
+			if !ok {
+				tests = append(tests, test)
+				continue
+			}
+			collapseInto(parent, test, hasSubtests[test.Name])
+			counts[parent]++
+		}
+
+		for _, test := range tests {
+			n, ok := counts[test]
+			if !ok {
+				continue
+			}
+			test.isParentTest = false
+			test.Properties = append(test.Properties, Property{CollapsedSubtestsProperty, strconv.Itoa(n)})
+		}
+		suite.Tests = tests
+	}
+}
+
+// collapseInto folds subtest into its top level test parent
+func collapseInto(parent, subtest *Test, hasSubtests bool) {
+	if statusSeverity[subtest.Status] > statusSeverity[parent.Status] {
+		parent.Status = subtest.Status
+	}
+	parent.Incomplete = parent.Incomplete || subtest.Incomplete
+	parent.OutputTruncated = parent.OutputTruncated || subtest.OutputTruncated
+	if subtest.Status != Failed && subtest.Status != Errored {
+		return
+	}
+	if hasSubtests && strings.TrimSpace(subtest.Message) == "" {
+		// Failed only since its subtests failed
+		return
+	}
+
+	if parent.FailureMessage == "" {
+		parent.FailureMessage = subtest.FailureMessage
+	}
+	if parent.Message != "" && !strings.HasSuffix(parent.Message, "\n") {
+		parent.Message += "\n"
+	}
+	parent.Message += "--- " + subtest.Name + "\n" + subtest.Message
+}
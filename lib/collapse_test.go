@@ -0,0 +1,47 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestCollapseSubtests(t *testing.T) {
+	filename := "../_data/in/gotest-subtests-mixed.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Suites(suites).CollapseSubtests()
+
+	tests := suites[0].Tests
+	if len(tests) != 3 {
+		t.Fatalf("%d tests, expected 3", len(tests))
+	}
+	table, plain, passing := tests[0], tests[1], tests[2]
+
+	if table.Name != "TestTable" || table.Status != Failed || table.Time != "0.03" {
+		t.Fatalf("bad collapsed test: %+v", table)
+	}
+	message := "--- TestTable/small/one\n    mixed_test.go:21: got 2, want 1\n" +
+		"--- TestTable/large/big\n    mixed_test.go:21: got 7, want 1000"
+	if table.Message != message {
+		t.Fatalf("message %q, expected %q", table.Message, message)
+	}
+	if table.FailureMessage != "got 2, want 1" {
+		t.Fatalf("failure message %q", table.FailureMessage)
+	}
+	if !hasProperty(table.Properties, CollapsedSubtestsProperty) || table.Properties[0].Value != "6" {
+		t.Fatalf("bad properties: %v", table.Properties)
+	}
+
+	if plain.Status != Passed || len(plain.Properties) != 0 {
+		t.Fatalf("bad test without subtests: %+v", plain)
+	}
+	if passing.Status != Passed || passing.Message != "" || passing.isParentTest {
+		t.Fatalf("bad passing test: %+v", passing)
+	}
+
+	stats := Suites(suites).Stats()
+	if stats["fail"] != 1 || stats["pass"] != 2 || stats["skip"] != 0 {
+		t.Fatalf("bad stats: %v", stats)
+	}
+}
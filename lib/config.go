@@ -0,0 +1,281 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigFile is the configuration file name go2xunit looks for in the
+// current directory
+const ConfigFile = "go2xunit.toml"
+
+// Config is go2xunit configuration, it has a field per command line flag and
+// the TOML key of a field is the flag name (without the leading -). For
+// example
+//
+//	fail = true
+//	slow = 10
+//	suite-name-prefix = "ci/"
+//
+// is the same as "go2xunit -fail -slow=10 -suite-name-prefix=ci/". Flag
+// aliases (e.g. -top for -slow) have no field.
+type Config struct {
+	// Input file or HTTP(S) URL (default to stdin)
+	Input string `toml:"input"`
+	// Output file (default to stdout)
+	Output string `toml:"output"`
+	// Gzip compress the output (default if -output ends with .gz)
+	OutputGZ bool `toml:"output-gz"`
+	// Gzip compression level (1 fastest to 9 best)
+	GzipLevel int `toml:"gzip-level"`
+	// Write report files to this directory (with -split)
+	OutputDir string `toml:"output-dir"`
+	// With package, write a report file per package to -output-dir
+	Split string `toml:"split"`
+	// Also write passed.xml, failed.xml and skipped.xml reports to this directory
+	SplitByStatus string `toml:"split-by-status"`
+	// Write only the first N tests (and their parent tests) to the report, 0 for all
+	MaxTests int `toml:"max-tests"`
+	// Stop reading input after more than N failed tests (0 stops on the first failure), -1 for no limit
+	MaxFailures int `toml:"max-failures"`
+	// Stop reading input after the first failed test (same as -max-failures=0)
+	FailFast bool `toml:"fail-fast"`
+	// Split the report to numbered files (e.g. report-001.xml) of at most N tests
+	MaxCasesPerFile int `toml:"max-cases-per-file"`
+	// Split the report to numbered files (e.g. report-001.xml) of about N bytes
+	MaxBytesPerFile int `toml:"max-bytes-per-file"`
+	// Fail (non zero exit) if any test failed
+	Fail bool `toml:"fail"`
+	// Exit with 0 on failed tests, even with -fail-under
+	NoExitCode bool `toml:"no-exit-code"`
+	// With new-failures, fail (non zero exit) only if tests failing now passed in -baseline
+	FailOn string `toml:"fail-on"`
+	// Fail (non zero exit) if pass rate is below N percent
+	FailUnder int `toml:"fail-under"`
+	// Report only tests matching regexp (e.g. TestA/sub)
+	Include string `toml:"include"`
+	// Don't report tests matching regexp
+	Exclude string `toml:"exclude"`
+	// Report only packages matching regexp
+	IncludePkg string `toml:"include-pkg"`
+	// Don't report packages matching regexp
+	ExcludePkg string `toml:"exclude-pkg"`
+	// Report skipped tests as failed
+	FailOnSkip bool `toml:"fail-on-skip"`
+	// Report skipped tests as disabled
+	SkipAsDisabled bool `toml:"skip-as-disabled"`
+	// File with tests (package/TestName globs, one per line) exempt from -fail-on-skip and -skip-as-disabled
+	SkipAllow string `toml:"skip-allow"`
+	// Convert -input again whenever it changes
+	Watch bool `toml:"watch"`
+	// "Name: value" HTTP header of an -input URL (can be repeated)
+	Headers []string `toml:"header"`
+	// Timeout of reading an -input URL
+	FetchTimeout time.Duration `toml:"fetch-timeout"`
+	// Serve the report over HTTP on this address (e.g. :8080) instead of writing it
+	Serve string `toml:"serve"`
+	// Report parsing progress to stderr if it's a terminal ("force" to always report)
+	Progress string `toml:"progress"`
+	// Check that the input is a well formed "go test -json" stream and print diagnostics instead of a report
+	Validate bool `toml:"validate"`
+	// Xml compatible with Atlassian's Bamboo
+	Bamboo bool `toml:"bamboo"`
+	// Xml compatible with xunit.net
+	XUnitNet bool `toml:"xunitnet"`
+	// Parse gocheck output
+	Gocheck bool `toml:"gocheck"`
+	// Parse go test -json (or go tool test2json) output
+	JSON bool `toml:"json"`
+	// Package name of output without a package summary line (e.g. of a test binary)
+	PackageName string `toml:"package-name"`
+	// Mark test as errored if it exposes a data race
+	FailOnRace bool `toml:"fail-on-race"`
+	// Report packages without test files as empty suites
+	ShowEmptyPackages bool `toml:"show-empty-packages"`
+	// Truncate output of each test to about N bytes (0 for no limit)
+	MaxOutputBytes int `toml:"max-output-bytes"`
+	// Keep test output for fail, fail+skip or all tests
+	OutputFor string `toml:"output-for"`
+	// Ignore go tool output (e.g. "go: downloading ...") in input, and non JSON lines with -json
+	IgnoreBuildOutput bool `toml:"ignore-build-output"`
+	// Maximal input line length in bytes
+	ScannerBuffer int `toml:"scanner-buffer"`
+	// Print the "go test -shuffle" seed of each package to stderr
+	PrintShuffleSeed bool `toml:"print-shuffle-seed"`
+	// Add total time of tests by status as suite properties
+	StatusTimes bool `toml:"status-times"`
+	// Report every top level test as one test case, with the worst status of its subtests
+	CollapseSubtests bool `toml:"collapse-subtests"`
+	// Put test output in CDATA sections (XML escape it if false)
+	CDATA bool `toml:"cdata"`
+	// Indentation of XML output, "" writes it without re-indenting
+	Indent string `toml:"indent"`
+	// XML output without indentation
+	Compact bool `toml:"compact"`
+	// Format of times in XML output: seconds, ms, human or iso8601 (default seconds)
+	DurationFormat string `toml:"duration-format"`
+	// Digits after the decimal point of times in seconds
+	TimePrecision int `toml:"time-precision"`
+	// <testsuites> time: sum of package times or wall clock time (only with -json)
+	TotalTime string `toml:"total-time"`
+	// Merge results into the existing -output report (e.g. of a rerun of failed tests)
+	Append bool `toml:"append"`
+	// Don't sanitize test and package names in XML output
+	RawNames bool `toml:"raw-names"`
+	// Truncate longer test and package names in XML output, 0 for no limit
+	MaxNameLength int `toml:"max-name-length"`
+	// Write output of failed tests to files in this directory and attach them (Jenkins JUnit Attachments)
+	AttachmentsDir string `toml:"attachments-dir"`
+	// With -attachments-dir, attach output of tests of all statuses
+	AttachmentsAll bool `toml:"attachments-all"`
+	// Add go2xunit and Go versions and generation time as <testsuites> properties
+	VersionInfo bool `toml:"version-info"`
+	// Default namespace (absolute URI) of the XML output
+	XMLNS string `toml:"xmlns"`
+	// Exit with 3 if a test took longer (e.g. 30s)
+	MaxTestTime time.Duration `toml:"max-test-time"`
+	// Exit with 3 if a package took longer (e.g. 5m)
+	MaxSuiteTime time.Duration `toml:"max-suite-time"`
+	// Prefix to include before all suite names
+	SuiteNamePrefix string `toml:"suite-name-prefix"`
+	// Convert package and test start times parsed from the input to UTC
+	NormalizeTimestamps bool `toml:"normalize-timestamps"`
+	// Report timestamps in this time zone: Local, UTC or Area/City (default UTC)
+	Timezone string `toml:"timezone"`
+	// Output format (xunit, diff, coveralls, cobertura, lcov, csv, yaml, slack, pretty or bench-diff)
+	Format string `toml:"format"`
+	// Don't write the header row of -format csv output
+	CSVNoHeader bool `toml:"csv-no-header"`
+	// Color -format pretty output: auto (if stdout is a terminal and NO_COLOR isn't set), always or never
+	Color string `toml:"color"`
+	// Write -format pretty output as tests finish instead of after parsing
+	Stream bool `toml:"stream"`
+	// Coveralls repo_token of -format coveralls output
+	CoverallsToken string `toml:"coveralls-token"`
+	// Slack incoming webhook URL to post -format slack output to
+	SlackWebhook string `toml:"slack-webhook"`
+	// URL to upload the XML output to with HTTP PUT
+	UploadURL string `toml:"upload-url"`
+	// Bearer token of -upload-url
+	UploadToken string `toml:"upload-token"`
+	// URL to post a JSON summary of the run to after the report is written
+	Webhook string `toml:"webhook"`
+	// Timeout of posting to -webhook (including retries)
+	WebhookTimeout time.Duration `toml:"webhook-timeout"`
+	// Exit with an error if posting to -webhook fails (default is a warning)
+	WebhookRequired bool `toml:"webhook-required"`
+	// Text/template file of the -webhook payload, executed with the run summary
+	WebhookTemplate string `toml:"webhook-template"`
+	// NAME=VALUE property of every package in the report and label of -metrics-file series (can be repeated)
+	Props []string `toml:"prop"`
+	// Write Prometheus metrics of the tests to this file (node_exporter textfile format)
+	MetricsFile string `toml:"metrics-file"`
+	// Add a -metrics-file series of the time of every test
+	MetricsPerTest bool `toml:"metrics-per-test"`
+	// Write the package import graph, colored by test status, to this Graphviz DOT file
+	ImportGraph string `toml:"import-graph"`
+	// Module root directory of -import-graph
+	ModuleRoot string `toml:"module-root"`
+	// Append a record of the run to this JSON lines file and compare with the previous one
+	History string `toml:"history"`
+	// Keep only the last N records of -history (0 keeps all)
+	HistoryLimit int `toml:"history-limit"`
+	// JSON file of package path to tag, packages are grouped by tag in <testsuites> elements
+	TagMap string `toml:"tag-map"`
+	// Output (or XML report) of a previous run to compare with
+	Baseline string `toml:"baseline"`
+	// Report tests slower than this many times their -baseline time
+	RegressionThreshold float64 `toml:"regression-threshold"`
+	// Report benchmarks of -format bench-diff that are this many times slower or faster than in -baseline
+	BenchThreshold float64 `toml:"bench-threshold"`
+	// Print per package summary to stderr
+	Summary bool `toml:"summary"`
+	// Don't print the summary line to stderr after conversion
+	Quiet bool `toml:"quiet"`
+	// Failed tests listed after the summary line, -1 for all
+	SummaryFailures int `toml:"summary-failures"`
+	// Print the number of tests by run time to stderr
+	Histogram bool `toml:"histogram"`
+	// Print N slowest tests to stderr
+	Slow int `toml:"slow"`
+	// Report top level tests (with their subtests) in -slow/-top
+	TopRollup bool `toml:"top-rollup"`
+	// Format of -slow report (table or json)
+	SlowFormat string `toml:"slow-format"`
+	// Report tests that ran more than once: all, last, worst or flaky
+	RerunPolicy string `toml:"rerun-policy"`
+	// Report tests that still ran more than once (see -rerun-policy) as suffix (name#N) or aggregate
+	Repeated string `toml:"repeated"`
+	// Comma separated outputs of previous runs, report flaky tests to stderr
+	Flaky CommaList `toml:"flaky"`
+
+	// keys are the keys read by ReadConfig
+	keys map[string]bool
+}
+
+// CommaList is a list option that is a comma separated flag value (e.g.
+// -flaky=run1.out,run2.out)
+type CommaList []string
+
+// ConfigFlag is a command line flag of a Config
+type ConfigFlag struct {
+	Name  string
+	Value string
+}
+
+// ReadConfig reads TOML configuration from r, unknown keys are an error
+func ReadConfig(r io.Reader) (*Config, error) {
+	config := &Config{keys: make(map[string]bool)}
+	md, err := toml.NewDecoder(r).Decode(config)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown option: %q", undecoded[0].String())
+	}
+	for _, key := range md.Keys() {
+		config.keys[key.String()] = true
+	}
+	return config, nil
+}
+
+// Flags returns the command line flags of config in field order: the options
+// read by ReadConfig, or the options with non zero values if config wasn't
+// read from a file. A list is a flag per item, except for CommaList.
+func (config *Config) Flags() []ConfigFlag {
+	var flags []ConfigFlag
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := field.Tag.Get("toml")
+		if name == "" {
+			continue
+		}
+		fv := value.Field(i)
+		if (config.keys != nil && !config.keys[name]) || (config.keys == nil && fv.IsZero()) {
+			continue
+		}
+
+		switch v := fv.Interface().(type) {
+		case CommaList:
+			flags = append(flags, ConfigFlag{name, strings.Join(v, ",")})
+		case []string:
+			for _, item := range v {
+				flags = append(flags, ConfigFlag{name, item})
+			}
+		case time.Duration:
+			flags = append(flags, ConfigFlag{name, v.String()})
+		case float64:
+			flags = append(flags, ConfigFlag{name, strconv.FormatFloat(v, 'g', -1, 64)})
+		default:
+			flags = append(flags, ConfigFlag{name, fmt.Sprint(v)})
+		}
+	}
+	return flags
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadConfig(t *testing.T) {
+	input := `# go2xunit configuration
+fail = false
+slow = 1_0  # slowest tests
+suite-name-prefix = "ci/\"x\" # not a comment"
+indent = '\t'
+flaky = ["a.out", 'b.out']
+prop = ["a=1", "b=2"]
+regression-threshold = 1.5
+max-test-time = "1m30s"
+`
+	config, err := ReadConfig(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Slow != 10 || config.SuiteNamePrefix != `ci/"x" # not a comment` || config.Indent != `\t` ||
+		config.MaxTestTime != 90*time.Second {
+		t.Fatalf("bad config: %+v", config)
+	}
+
+	expected := []ConfigFlag{
+		{"fail", "false"},
+		{"indent", `\t`},
+		{"max-test-time", "1m30s"},
+		{"suite-name-prefix", `ci/"x" # not a comment`},
+		{"prop", "a=1"},
+		{"prop", "b=2"},
+		{"regression-threshold", "1.5"},
+		{"slow", "10"},
+		{"flaky", "a.out,b.out"},
+	}
+	if flags := config.Flags(); !reflect.DeepEqual(flags, expected) {
+		t.Fatalf("flags %v, expected %v", flags, expected)
+	}
+
+	bad := []string{
+		"[table]\nfail = true\n",
+		"fail = yes\n",
+		"slow = \"10\"\n",
+		"no-such-option = 1\n",
+		"top = 1\n",
+		`prefix = "\x41"` + "\n",
+		"fail = true\nfail = false\n",
+	}
+	for _, input := range bad {
+		if _, err := ReadConfig(strings.NewReader(input)); err == nil {
+			t.Fatalf("no error on %q", input)
+		}
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	config := &Config{Format: "csv", Slow: 3, Headers: []string{"A: 1"}}
+	expected := []ConfigFlag{{"header", "A: 1"}, {"format", "csv"}, {"slow", "3"}}
+	if flags := config.Flags(); !reflect.DeepEqual(flags, expected) {
+		t.Fatalf("flags %v, expected %v", flags, expected)
+	}
+}
@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// ParseOptions limits the input parsed by ParseContext
+type ParseOptions struct {
+	// MaxLines is the maximal number of input lines, 0 means no limit
+	MaxLines int
+	// MaxBytes is the maximal input size, 0 means no limit
+	MaxBytes int64
+	// FailFast stops parsing at the first failed test, instead of after
+	// Options.MaxFailures failures
+	FailFast bool
+	// Progress, if set, counts the input and the parsed tests and packages
+	// (instead of Options.Progress)
+	Progress *Progress
+}
+
+// ParseContext parses rd with parse, it stops with ctx.Err() when ctx is done
+// and with an error when the input exceeds opts limits. Parsing stops early
+// (see ParseFastFail) with opts.FailFast, and opts.Progress is updated while
+// parsing. Settings other than opts are in Options.
+// A Read from rd that blocks is abandoned when ctx is done, it's left running
+// in the background until it returns.
+func ParseContext(ctx context.Context, parse ParseFunc, rd io.Reader, suitePrefix string, opts ParseOptions) (Suites, error) {
+	settings := defaultParseSettings()
+	if opts.FailFast {
+		settings.maxFailures = 0
+	}
+	rd = &contextReader{ctx: ctx, r: rd, opts: opts}
+	if opts.Progress != nil {
+		settings.progress = opts.Progress
+		rd = opts.Progress.Reader(rd)
+	}
+	suites, err := parseWith(parse, rd, suitePrefix, settings)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return suites, err
+}
+
+// NewContextReader returns a reader of r that stops with ctx.Err() when ctx is
+// done. A Read from r that blocks is abandoned when ctx is done, it's left
+// running in the background until it returns.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+// contextReader is a reader that stops when ctx is done or the input exceeds
+// opts limits
+type contextReader struct {
+	ctx    context.Context
+	r      io.Reader
+	opts   ParseOptions
+	nBytes int64
+	nLines int
+}
+
+// readResult is the result of a Read in the background
+type readResult struct {
+	data []byte
+	err  error
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var n int
+	var err error
+	if cr.ctx.Done() == nil {
+		// Can't be canceled
+		n, err = cr.r.Read(p)
+	} else {
+		ch := make(chan readResult, 1)
+		go func() {
+			buf := make([]byte, len(p))
+			n, err := cr.r.Read(buf)
+			ch <- readResult{buf[:n], err}
+		}()
+		select {
+		case <-cr.ctx.Done():
+			return 0, cr.ctx.Err()
+		case res := <-ch:
+			n, err = copy(p, res.data), res.err
+		}
+	}
+
+	cr.nBytes += int64(n)
+	cr.nLines += bytes.Count(p[:n], []byte{'\n'})
+	if cr.opts.MaxBytes > 0 && cr.nBytes > cr.opts.MaxBytes {
+		return n, fmt.Errorf("input larger than %d bytes", cr.opts.MaxBytes)
+	}
+	if cr.opts.MaxLines > 0 && cr.nLines > cr.opts.MaxLines {
+		return n, fmt.Errorf("input longer than %d lines", cr.opts.MaxLines)
+	}
+	return n, err
+}
+
+// ParseFastFail parses rd with parse like ParseContext, but stops at the first
+// failed test (like "go test -failfast"). The tests parsed up to there are
+// returned with a StoppedEarlyProperty, tests that were still running are
+// incomplete. Stopping early is not an error, the context is canceled to
+// abandon a blocked Read. Options.MaxFailures is not used (nor changed).
+func ParseFastFail(ctx context.Context, parse ParseFunc, rd io.Reader, suitePrefix string) (Suites, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return ParseContext(ctx, parse, rd, suitePrefix, ParseOptions{FailFast: true})
+}
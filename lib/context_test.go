@@ -0,0 +1,172 @@
+package lib
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseContextCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := ParseContext(ctx, ParseGotest, pr, "", ParseOptions{})
+		done <- err
+	}()
+
+	// Parser is now blocked reading the rest of the input
+	if _, err := io.WriteString(pw, "=== RUN   TestA\n--- PASS: TestA (0.00s)\n"); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("got %v, expected %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("parsing not canceled")
+	}
+
+	// The blocked read returns once the writer is closed
+	pw.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines, %d before parsing", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseContextLimits(t *testing.T) {
+	data, err := ioutil.ReadFile("../_data/in/gotest-pass.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := string(data)
+	nLines := strings.Count(input, "\n")
+
+	ctx := context.Background()
+	if _, err := ParseContext(ctx, ParseGotest, strings.NewReader(input), "", ParseOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	opts := ParseOptions{MaxLines: nLines, MaxBytes: int64(len(input))}
+	if _, err := ParseContext(ctx, ParseGotest, strings.NewReader(input), "", opts); err != nil {
+		t.Fatalf("error at limits: %s", err)
+	}
+
+	opts = ParseOptions{MaxLines: nLines - 1}
+	if _, err := ParseContext(ctx, ParseGotest, strings.NewReader(input), "", opts); err == nil {
+		t.Fatal("no error on too many lines")
+	}
+	opts = ParseOptions{MaxBytes: int64(len(input) - 1)}
+	if _, err := ParseContext(ctx, ParseGotest, strings.NewReader(input), "", opts); err == nil {
+		t.Fatal("no error on too many bytes")
+	}
+}
+
+func TestParseFastFail(t *testing.T) {
+	data, err := ioutil.ReadFile("../_data/in/test2json-shuffle.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Events up to the failure of TestSub/b, the rest of the stream is never
+	// written
+	var head []string
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		head = append(head, line)
+		if strings.Contains(line, `"Action":"fail","Package":"example.com/fz","Test":"TestSub/b"`) {
+			break
+		}
+	}
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go io.WriteString(pw, strings.Join(head, ""))
+
+	type result struct {
+		suites Suites
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		suites, err := ParseFastFail(context.Background(), ParseTest2JSON, pr, "")
+		done <- result{suites, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("parsing didn't stop at the failure")
+	}
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if !res.suites.StoppedEarly() {
+		t.Fatal("not stopped early")
+	}
+	expected := map[string]Status{
+		"TestSkip":  Skipped,
+		"TestSub":   Errored,
+		"TestSub/a": Passed,
+		"TestSub/b": Failed,
+	}
+	tests := res.suites[0].Tests
+	if len(tests) != len(expected) {
+		t.Fatalf("got %d tests instead of %d", len(tests), len(expected))
+	}
+	for _, test := range tests {
+		if test.Status != expected[test.Name] {
+			t.Fatalf("%s: status %v, expected %v", test.Name, test.Status, expected[test.Name])
+		}
+	}
+	if Options.MaxFailures != -1 {
+		t.Fatalf("Options.MaxFailures changed: %d", Options.MaxFailures)
+	}
+
+	// Parsing at the same time doesn't stop early
+	fastFail := make(chan error, 1)
+	go func() {
+		_, err := ParseFastFail(context.Background(), ParseTest2JSON, strings.NewReader(strings.Join(head, "")), "")
+		fastFail <- err
+	}()
+	suites, err := ParseAll(ParseTest2JSON, "", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-fastFail; err != nil {
+		t.Fatal(err)
+	}
+	if suites.StoppedEarly() {
+		t.Fatal("concurrent parsing stopped early")
+	}
+}
+
+func TestParseContextFailFastProgress(t *testing.T) {
+	data, err := ioutil.ReadFile("../_data/in/test2json-shuffle.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Progress reader wraps the input, fail fast is still set
+	progress := &Progress{}
+	opts := ParseOptions{FailFast: true, Progress: progress}
+	suites, err := ParseContext(context.Background(), ParseTest2JSON, strings.NewReader(string(data)), "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !suites.StoppedEarly() {
+		t.Fatal("not stopped early")
+	}
+	if progress.Stats().Bytes == 0 {
+		t.Fatal("input not counted")
+	}
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// CoverallsService is the service_name of Coveralls reports
+const CoverallsService = "go2xunit"
+
+// coverallsLines is the number of lines in a synthetic Coveralls source file,
+// each line is one percent of statement coverage
+const coverallsLines = 100
+
+// coverallsJob is the Coveralls API job payload
+type coverallsJob struct {
+	RepoToken   string                `json:"repo_token"`
+	ServiceName string                `json:"service_name"`
+	SourceFiles []coverallsSourceFile `json:"source_files"`
+}
+
+// coverallsSourceFile is a source file in a Coveralls job
+type coverallsSourceFile struct {
+	Name         string `json:"name"`
+	SourceDigest string `json:"source_digest"`
+	Coverage     []int  `json:"coverage"`
+}
+
+// WriteCoveralls writes the coverage of suites as a Coveralls API job payload.
+// "go test -cover" reports only statement coverage, so every package with
+// coverage is reported as a synthetic single file of 100 lines where the
+// covered lines are the coverage percent.
+func WriteCoveralls(w io.Writer, suites Suites, repoToken string) error {
+	job := coverallsJob{
+		RepoToken:   repoToken,
+		ServiceName: CoverallsService,
+		SourceFiles: []coverallsSourceFile{},
+	}
+	for _, suite := range suites {
+		if !suite.HasCoverage {
+			continue
+		}
+		job.SourceFiles = append(job.SourceFiles, coverallsFile(suite))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(job)
+}
+
+// coverallsFile returns the synthetic Coveralls source file of suite coverage
+func coverallsFile(suite *Suite) coverallsSourceFile {
+	covered := int(math.Round(suite.Coverage * coverallsLines))
+	coverage := make([]int, coverallsLines)
+	for i := 0; i < covered; i++ {
+		coverage[i] = 1
+	}
+
+	return coverallsSourceFile{
+		Name:         suite.Name,
+		SourceDigest: fmt.Sprintf("%x", md5.Sum([]byte(suite.Name))),
+		Coverage:     coverage,
+	}
+}
@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteCoveralls(t *testing.T) {
+	suites := Suites{
+		{Name: "pkg/a", Coverage: 0.755, HasCoverage: true},
+		{Name: "pkg/b"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCoveralls(&buf, suites, "s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+
+	var job struct {
+		RepoToken   *string `json:"repo_token"`
+		ServiceName string  `json:"service_name"`
+		SourceFiles []struct {
+			Name         string `json:"name"`
+			SourceDigest string `json:"source_digest"`
+			Coverage     []int  `json:"coverage"`
+		} `json:"source_files"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &job); err != nil {
+		t.Fatalf("bad JSON: %s\n%s", err, buf.String())
+	}
+	if job.RepoToken == nil || *job.RepoToken != "s3cr3t" {
+		t.Fatalf("bad repo_token:\n%s", buf.String())
+	}
+	if job.ServiceName != CoverallsService {
+		t.Fatalf("bad service_name: %q", job.ServiceName)
+	}
+	if len(job.SourceFiles) != 1 {
+		t.Fatalf("expected 1 source file, got %d", len(job.SourceFiles))
+	}
+
+	file := job.SourceFiles[0]
+	if file.Name != "pkg/a" || len(file.SourceDigest) != 32 {
+		t.Fatalf("bad source file: %+v", file)
+	}
+	if len(file.Coverage) != coverallsLines {
+		t.Fatalf("expected %d lines, got %d", coverallsLines, len(file.Coverage))
+	}
+	covered := 0
+	for _, hits := range file.Coverage {
+		covered += hits
+	}
+	if covered != 76 {
+		t.Fatalf("expected 76 covered lines, got %d", covered)
+	}
+}
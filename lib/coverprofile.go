@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// CoverBlock is a block of a coverage profile ("go test -coverprofile")
+type CoverBlock struct {
+	File               string // e.g. example.com/pkg/file.go
+	StartLine, EndLine int
+	StartCol, EndCol   int
+	NumStmt            int
+	Count              int // Number of times the block ran (0 or 1 in set mode)
+}
+
+// coverProfile is the coverage profile lines found in the input
+type coverProfile []CoverBlock
+
+// add adds line to p if it's a coverage profile line, it returns false if
+// it's not
+func (p *coverProfile) add(line string) bool {
+	line = strings.TrimRight(line, "\r")
+	if gtCoverModeRE.MatchString(line) {
+		return true
+	}
+	tokens := gtCoverBlockRE.FindStringSubmatch(line)
+	if tokens == nil {
+		return false
+	}
+	var nums [6]int
+	for i := range nums {
+		n, err := strconv.Atoi(tokens[i+2])
+		if err != nil {
+			return false
+		}
+		nums[i] = n
+	}
+	*p = append(*p, CoverBlock{
+		File:      tokens[1],
+		StartLine: nums[0],
+		StartCol:  nums[1],
+		EndLine:   nums[2],
+		EndCol:    nums[3],
+		NumStmt:   nums[4],
+		Count:     nums[5],
+	})
+	return true
+}
+
+// setCoverProfiles adds the blocks of p to the suites of their packages, which
+// are the directories of the block files
+func setCoverProfiles(suites []*Suite, p coverProfile, suitePrefix string) {
+	if len(p) == 0 {
+		return
+	}
+	byName := make(map[string]*Suite)
+	for _, suite := range suites {
+		byName[suite.Name] = suite
+	}
+	missing := make(map[string]bool)
+	for _, block := range p {
+		pkg := path.Dir(block.File)
+		suite, ok := byName[suitePrefix+pkg]
+		if !ok {
+			if !missing[pkg] && Options.Warnf != nil {
+				Options.Warnf("coverage profile of package %s without tests", pkg)
+			}
+			missing[pkg] = true
+			continue
+		}
+		suite.CoverProfile = append(suite.CoverProfile, block)
+	}
+}
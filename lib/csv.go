@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// csvHeader is the header row of WriteCSV
+var csvHeader = []string{"package", "name", "status", "time", "message"}
+
+// WriteCSV writes a CSV (RFC 4180) row of package, name, status, time in
+// seconds and output for every leaf test (not a parent of subtests)
+// in suites. If header is set, the first row is the column names.
+func WriteCSV(w io.Writer, suites Suites, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	err := suites.Walk(func(path []string, test *Test) error {
+		if test.isParentTest {
+			return nil
+		}
+		status, ok := statusNames[test.Status]
+		if !ok {
+			status = otherStatus
+		}
+		return cw.Write([]string{
+			path[0],
+			test.Name,
+			status,
+			FormatSeconds(test.Elapsed()),
+			test.Message,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
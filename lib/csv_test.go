@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	filename := "../_data/in/gotest-1.7.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, suites, true); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("bad CSV: %s", err)
+	}
+
+	leaves := Suites(suites).Leaves()
+	if len(records) != len(leaves)+1 {
+		t.Fatalf("got %d rows, expected %d", len(records), len(leaves)+1)
+	}
+	if fmt.Sprint(records[0]) != fmt.Sprint(csvHeader) {
+		t.Fatalf("bad header: %v", records[0])
+	}
+	for i, test := range leaves {
+		record := records[i+1]
+		expected := []string{
+			suites[0].Name,
+			test.Name,
+			statusNames[test.Status],
+			FormatSeconds(test.Elapsed()),
+			test.Message,
+		}
+		for j := range expected {
+			if record[j] != expected[j] {
+				t.Fatalf("%s: %s is %q, expected %q", test.Name, csvHeader[j], record[j], expected[j])
+			}
+		}
+	}
+
+	buf.Reset()
+	if err := WriteCSV(&buf, Suites{{Name: "pkg", Tests: []*Test{{Name: "TestA", Status: Failed, Message: "a, b\n\"c\""}}}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != "pkg,TestA,fail,0.000,\"a, b\n\"\"c\"\"\"\n" {
+		t.Fatalf("bad CSV: %q", out)
+	}
+}
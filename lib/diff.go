@@ -0,0 +1,239 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// TestDiff is the difference between two test runs
+type TestDiff struct {
+	NewFailures  []*Test // Failed (or errored) now but not in baseline
+	NewPasses    []*Test // Passed now but not in baseline
+	NewSkips     []*Test // Skipped now but not in baseline
+	Regressions  []*Test // More than threshold times slower than in baseline
+	Improvements []*Test // More than threshold times faster than in baseline
+}
+
+// isFailure returns true if status is a failing one
+func isFailure(status Status) bool {
+	return status == Failed || status == Errored
+}
+
+// testsByKey returns the tests of suites by suite and test name
+func testsByKey(suites Suites) map[testKey]*Test {
+	tests := make(map[testKey]*Test)
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			tests[testKey{suite.Name, test.Name}] = test
+		}
+	}
+	return tests
+}
+
+// elapsedRatio returns the ratio of the elapsed time of test to the elapsed
+// time of old, the test in the baseline run. It's false if the times can't
+// be compared: old is nil (not in baseline), has no time or either is
+// skipped.
+func elapsedRatio(old, test *Test) (float64, bool) {
+	if old == nil || old.Elapsed() <= 0 || test.Status == Skipped || old.Status == Skipped {
+		return 0, false
+	}
+	return float64(test.Elapsed()) / float64(old.Elapsed()), true
+}
+
+// Diff returns the difference between baseline and current runs, tests are
+// matched by suite and test name. Tests are regressions (or improvements) if
+// they are more than threshold times slower (or faster) than in baseline,
+// like in CompareRuns.
+func Diff(baseline, current Suites, threshold float64) *TestDiff {
+	prev := testsByKey(baseline)
+	diff := &TestDiff{}
+	for _, suite := range current {
+		for _, test := range suite.Tests {
+			old := prev[testKey{suite.Name, test.Name}]
+			switch {
+			case isFailure(test.Status):
+				if old == nil || !isFailure(old.Status) {
+					diff.NewFailures = append(diff.NewFailures, test)
+				}
+			case old == nil || old.Status != test.Status:
+				if test.Status == Passed {
+					diff.NewPasses = append(diff.NewPasses, test)
+				} else if test.Status == Skipped {
+					diff.NewSkips = append(diff.NewSkips, test)
+				}
+			}
+
+			ratio, ok := elapsedRatio(old, test)
+			switch {
+			case !ok:
+			case ratio > threshold:
+				diff.Regressions = append(diff.Regressions, test)
+			case ratio*threshold < 1:
+				diff.Improvements = append(diff.Improvements, test)
+			}
+		}
+	}
+
+	return diff
+}
+
+// WriteDiff writes diff as text, one line per changed test
+func WriteDiff(w io.Writer, diff *TestDiff) error {
+	sections := []struct {
+		title string
+		tests []*Test
+	}{
+		{"new failure", diff.NewFailures},
+		{"new pass", diff.NewPasses},
+		{"new skip", diff.NewSkips},
+		{"slower", diff.Regressions},
+		{"faster", diff.Improvements},
+	}
+
+	for _, section := range sections {
+		for _, test := range section.tests {
+			if _, err := fmt.Fprintf(w, "%s: %s (%s)\n", section.title, test.Name, test.Elapsed()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BaselineComparison is the failures of a run compared to a baseline run
+type BaselineComparison struct {
+	NewFailures  []*Test // Failing now, passed or missing in baseline
+	Fixed        []*Test // Passing now, failed in baseline
+	StillFailing []*Test // Failing now and in baseline
+
+	suites map[*Test]string // suite name of tests
+}
+
+// CompareBaseline compares failures in current with baseline, tests are
+// matched by suite and test name. Tests that are missing from current are
+// ignored.
+func CompareBaseline(baseline, current Suites) *BaselineComparison {
+	prev := testsByKey(baseline)
+	cmp := &BaselineComparison{suites: make(map[*Test]string)}
+	for _, suite := range current {
+		for _, test := range suite.Tests {
+			cmp.suites[test] = suite.Name
+			old := prev[testKey{suite.Name, test.Name}]
+			wasFailing := old != nil && isFailure(old.Status)
+			switch {
+			case isFailure(test.Status) && wasFailing:
+				cmp.StillFailing = append(cmp.StillFailing, test)
+			case isFailure(test.Status):
+				cmp.NewFailures = append(cmp.NewFailures, test)
+			case wasFailing && test.Status == Passed:
+				cmp.Fixed = append(cmp.Fixed, test)
+			}
+		}
+	}
+	return cmp
+}
+
+// AddProperties adds the number of new failures, fixed and still failing
+// tests as "baseline.new-failures", "baseline.fixed" and
+// "baseline.still-failing" properties of every suite in suites
+func (cmp *BaselineComparison) AddProperties(suites Suites) {
+	for _, suite := range suites {
+		counts := make(map[string]int)
+		for name, tests := range cmp.sections() {
+			for _, test := range tests {
+				if cmp.suites[test] == suite.Name {
+					counts[name]++
+				}
+			}
+		}
+		for _, name := range []string{"new-failures", "fixed", "still-failing"} {
+			suite.Properties = append(suite.Properties, Property{
+				Name:  "baseline." + name,
+				Value: fmt.Sprintf("%d", counts[name]),
+			})
+		}
+	}
+}
+
+// sections returns tests in cmp by property name
+func (cmp *BaselineComparison) sections() map[string][]*Test {
+	return map[string][]*Test{
+		"new-failures":  cmp.NewFailures,
+		"fixed":         cmp.Fixed,
+		"still-failing": cmp.StillFailing,
+	}
+}
+
+// WriteBaseline writes cmp as text: a line for every new failure and fixed
+// test and the number of still failing tests
+func WriteBaseline(w io.Writer, cmp *BaselineComparison) error {
+	for _, test := range cmp.NewFailures {
+		if _, err := fmt.Fprintf(w, "new failure: %s/%s\n", cmp.suites[test], test.Name); err != nil {
+			return err
+		}
+	}
+	for _, test := range cmp.Fixed {
+		if _, err := fmt.Fprintf(w, "fixed: %s/%s\n", cmp.suites[test], test.Name); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d new failures, %d fixed, %d still failing\n",
+		len(cmp.NewFailures), len(cmp.Fixed), len(cmp.StillFailing))
+	return err
+}
+
+// DefaultRegressionThreshold is the default ratio of current to baseline
+// elapsed time that counts as a performance regression
+const DefaultRegressionThreshold = 2.0
+
+// Regression is a test that got slower compared to a baseline run
+type Regression struct {
+	Package         string
+	Test            *Test
+	BaselineElapsed time.Duration
+	CurrentElapsed  time.Duration
+	Ratio           float64 // CurrentElapsed/BaselineElapsed
+}
+
+// CompareRuns returns the tests in current that their elapsed time is more
+// than threshold times their elapsed time in baseline, slowest first. Tests
+// are matched by suite and test name, skipped tests and tests without
+// baseline time are ignored.
+func CompareRuns(baseline, current Suites, threshold float64) []Regression {
+	prev := testsByKey(baseline)
+	var regressions []Regression
+	for _, suite := range current {
+		for _, test := range suite.Tests {
+			old := prev[testKey{suite.Name, test.Name}]
+			if ratio, ok := elapsedRatio(old, test); ok && ratio > threshold {
+				regressions = append(regressions, Regression{
+					Package:         suite.Name,
+					Test:            test,
+					BaselineElapsed: old.Elapsed(),
+					CurrentElapsed:  test.Elapsed(),
+					Ratio:           ratio,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(regressions, func(i, j int) bool {
+		return regressions[i].Ratio > regressions[j].Ratio
+	})
+	return regressions
+}
+
+// WriteRegressions writes regressions as text, one line per test
+func WriteRegressions(w io.Writer, regressions []Regression) error {
+	for _, r := range regressions {
+		_, err := fmt.Fprintf(w, "regression: %s/%s %s -> %s (%.1fx)\n",
+			r.Package, r.Test.Name, r.BaselineElapsed, r.CurrentElapsed, r.Ratio)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func diffRun(status Status, time string) Suites {
+	return Suites{
+		{Name: "pkg", Tests: []*Test{
+			{Name: "TestStable", Status: Passed, Time: "1.0"},
+			{Name: "TestChanged", Status: status, Time: time},
+		}},
+	}
+}
+
+func TestDiffRuns(t *testing.T) {
+	diff := Diff(diffRun(Passed, "1.0"), diffRun(Failed, "1.0"), DefaultRegressionThreshold)
+	if len(diff.NewFailures) != 1 || diff.NewFailures[0].Name != "TestChanged" {
+		t.Fatalf("bad new failures: %v", diff.NewFailures)
+	}
+	if len(diff.NewPasses) != 0 || len(diff.Regressions) != 0 {
+		t.Fatalf("unexpected changes: %+v", diff)
+	}
+
+	diff = Diff(diffRun(Failed, "1.0"), diffRun(Passed, "2.0"), 1.5)
+	if len(diff.NewPasses) != 1 || len(diff.Regressions) != 1 {
+		t.Fatalf("bad diff: %+v", diff)
+	}
+	// Regressions are the same as CompareRuns ones
+	if diff := Diff(diffRun(Failed, "1.0"), diffRun(Passed, "2.0"), DefaultRegressionThreshold); len(diff.Regressions) != 0 {
+		t.Fatalf("regression below the threshold: %+v", diff)
+	}
+	if diff := Diff(diffRun(Passed, "3.0"), diffRun(Passed, "1.0"), DefaultRegressionThreshold); len(diff.Improvements) != 1 {
+		t.Fatalf("no improvement: %+v", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, diff); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != "new pass: TestChanged (2s)" {
+		t.Fatalf("bad diff output:\n%s", buf.String())
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	baseline := Suites{
+		{Name: "pkg", Tests: []*Test{
+			{Name: "TestStillFailing", Status: Failed},
+			{Name: "TestFixed", Status: Failed},
+			{Name: "TestBroken", Status: Passed},
+			{Name: "TestRemoved", Status: Failed},
+		}},
+	}
+	current := Suites{
+		{Name: "pkg", Tests: []*Test{
+			{Name: "TestStillFailing", Status: Errored},
+			{Name: "TestFixed", Status: Passed},
+			{Name: "TestBroken", Status: Failed},
+			{Name: "TestAdded", Status: Failed},
+			{Name: "TestAddedOK", Status: Passed},
+		}},
+		{Name: "other", Tests: []*Test{{Name: "TestFixed", Status: Passed}}},
+	}
+
+	cmp := CompareBaseline(baseline, current)
+	names := func(tests []*Test) string {
+		var names []string
+		for _, test := range tests {
+			names = append(names, test.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	if s := names(cmp.NewFailures); s != "TestBroken,TestAdded" {
+		t.Fatalf("bad new failures: %s", s)
+	}
+	if s := names(cmp.Fixed); s != "TestFixed" {
+		t.Fatalf("bad fixed: %s", s)
+	}
+	if s := names(cmp.StillFailing); s != "TestStillFailing" {
+		t.Fatalf("bad still failing: %s", s)
+	}
+
+	cmp.AddProperties(current)
+	props := current[0].Properties
+	if len(props) != 3 || props[0] != (Property{"baseline.new-failures", "2"}) {
+		t.Fatalf("bad properties: %v", props)
+	}
+	if props := current[1].Properties; props[1] != (Property{"baseline.fixed", "0"}) {
+		t.Fatalf("bad properties: %v", props)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBaseline(&buf, cmp); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "new failure: pkg/TestBroken" || lines[3] != "2 new failures, 1 fixed, 1 still failing" {
+		t.Fatalf("bad output:\n%s", buf.String())
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	baseline := Suites{
+		{Name: "pkg", Tests: []*Test{
+			{Name: "TestStable", Status: Passed, Time: "1.0"},
+			{Name: "TestSlower", Status: Passed, Time: "1.0"},
+			{Name: "TestDoubled", Status: Passed, Time: "1.0"},
+		}},
+	}
+	current := Suites{
+		{Name: "pkg", Tests: []*Test{
+			{Name: "TestStable", Status: Passed, Time: "1.1"},
+			{Name: "TestSlower", Status: Passed, Time: "1.5"},
+			{Name: "TestDoubled", Status: Passed, Time: "2.5"},
+			{Name: "TestNew", Status: Passed, Time: "9.0"},
+		}},
+	}
+
+	regressions := CompareRuns(baseline, current, DefaultRegressionThreshold)
+	if len(regressions) != 1 {
+		t.Fatalf("expected 1 regression, got %+v", regressions)
+	}
+	r := regressions[0]
+	if r.Test.Name != "TestDoubled" || r.Package != "pkg" {
+		t.Fatalf("bad regression: %+v", r)
+	}
+	if r.BaselineElapsed != time.Second || r.CurrentElapsed != 2500*time.Millisecond || r.Ratio != 2.5 {
+		t.Fatalf("bad regression times: %+v", r)
+	}
+
+	if n := len(CompareRuns(baseline, current, 1.2)); n != 2 {
+		t.Fatalf("expected 2 regressions with threshold 1.2, got %d", n)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRegressions(&buf, regressions); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != "regression: pkg/TestDoubled 1s -> 2.5s (2.5x)\n" {
+		t.Fatalf("bad regressions output: %q", out)
+	}
+}
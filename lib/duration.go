@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimePrecision is the default number of digits after the decimal
+	// point of times in seconds
+	DefaultTimePrecision = 3
+	// MaxTimePrecision is the maximal Options.TimePrecision (nanoseconds)
+	MaxTimePrecision = 9
+	// MaxDuration is the longest valid test or package time, longer (or
+	// negative) times are reported as 0
+	MaxDuration = 7 * 24 * time.Hour
+)
+
+// DurationFormats are the formats known to FormatDuration
+var DurationFormats = map[string]bool{
+	"seconds": true,
+	"ms":      true,
+	"human":   true,
+	"iso8601": true,
+}
+
+// FormatDuration returns d in format: "seconds" (1.234), "ms" (1234), "human"
+// (1.234s) or "iso8601" (PT1.234S). Unknown formats are treated as
+// "seconds".
+func FormatDuration(d time.Duration, format string) string {
+	d = clampDuration(d)
+	switch format {
+	case "ms":
+		return strconv.FormatInt(d.Milliseconds(), 10)
+	case "human":
+		return d.String()
+	case "iso8601":
+		return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+	default:
+		return FormatSeconds(d)
+	}
+}
+
+// FormatSeconds returns d in seconds with Options.TimePrecision digits after
+// the decimal point (e.g. "0.042"), rounded half away from zero and never in
+// exponent notation. Negative durations and durations longer than
+// MaxDuration are formatted as 0.
+func FormatSeconds(d time.Duration) string {
+	precision := Options.TimePrecision
+	if precision < 0 || precision > MaxTimePrecision {
+		precision = DefaultTimePrecision
+	}
+	unit := time.Duration(math.Pow10(MaxTimePrecision - precision))
+	d = clampDuration(d).Round(unit)
+
+	seconds := strconv.FormatInt(int64(d/time.Second), 10)
+	if precision == 0 {
+		return seconds
+	}
+	frac := strconv.FormatInt(int64(d%time.Second/unit), 10)
+	return seconds + "." + strings.Repeat("0", precision-len(frac)) + frac
+}
+
+// durationOf returns the duration of seconds, values that don't fit in a
+// time.Duration (or NaN) are returned as -1
+func durationOf(seconds float64) time.Duration {
+	ns := seconds * float64(time.Second)
+	if math.IsNaN(ns) || ns >= math.MaxInt64 || ns <= math.MinInt64 {
+		return -1
+	}
+	return time.Duration(ns)
+}
+
+// clampDuration returns d, or 0 with a warning if d is negative or longer
+// than MaxDuration
+func clampDuration(d time.Duration) time.Duration {
+	if d >= 0 && d <= MaxDuration {
+		return d
+	}
+	if Options.Warnf != nil {
+		Options.Warnf("invalid duration %s, reporting 0", d)
+	}
+	return 0
+}
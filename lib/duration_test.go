@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	d := 1234 * time.Millisecond
+	cases := map[string]string{
+		"seconds": "1.234",
+		"ms":      "1234",
+		"human":   "1.234s",
+		"iso8601": "PT1.234S",
+	}
+	for format, expected := range cases {
+		if out := FormatDuration(d, format); out != expected {
+			t.Errorf("%s: got %q, expected %q", format, out, expected)
+		}
+	}
+}
+
+func TestFormatSeconds(t *testing.T) {
+	defer func(precision int) { Options.TimePrecision = precision }(Options.TimePrecision)
+	defer func(warnf func(string, ...interface{})) { Options.Warnf = warnf }(Options.Warnf)
+
+	var warnings []string
+	Options.Warnf = func(format string, args ...interface{}) {
+		warnings = append(warnings, format)
+	}
+
+	cases := []struct {
+		d         time.Duration
+		precision int
+		expected  string
+	}{
+		{0, 3, "0.000"},
+		{1, 3, "0.000"},
+		{1, 9, "0.000000001"},
+		{42 * time.Microsecond, 3, "0.000"},
+		{42 * time.Microsecond, 6, "0.000042"},
+		{500 * time.Microsecond, 3, "0.001"},
+		{42 * time.Millisecond, 3, "0.042"},
+		{42 * time.Millisecond, 1, "0.0"},
+		{1234 * time.Millisecond, 0, "1"},
+		{59*time.Second + 999500*time.Microsecond, 3, "60.000"},
+		{59*time.Second + 999499*time.Microsecond, 3, "59.999"},
+		{3*time.Hour + 25*time.Minute + 45*time.Second + 678*time.Millisecond, 3, "12345.678"},
+		{MaxDuration, 3, "604800.000"},
+		{-time.Millisecond, 3, "0.000"},
+		{MaxDuration + 1, 3, "0.000"},
+		{durationOf(1e300), 3, "0.000"},
+		{42 * time.Millisecond, 42, "0.042"}, // bad precision
+	}
+	for _, tc := range cases {
+		Options.TimePrecision = tc.precision
+		out := FormatSeconds(tc.d)
+		if out != tc.expected {
+			t.Errorf("%d (precision %d): got %q, expected %q", tc.d, tc.precision, out, tc.expected)
+		}
+		if strings.ContainsAny(out, "eE") {
+			t.Errorf("%d: exponent notation %q", tc.d, out)
+		}
+	}
+	if len(warnings) != 3 {
+		t.Fatalf("got %d warnings, expected 3", len(warnings))
+	}
+}
@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"regexp"
+)
+
+// TestFilter selects tests by name, nil regular expressions match everything
+// (include) or nothing (exclude)
+type TestFilter struct {
+	Include    *regexp.Regexp // Full test name (e.g. TestA/sub)
+	Exclude    *regexp.Regexp
+	IncludePkg *regexp.Regexp // Suite (package) name
+	ExcludePkg *regexp.Regexp
+}
+
+// IsEmpty returns true if the filter keeps all tests
+func (f *TestFilter) IsEmpty() bool {
+	return f.Include == nil && f.Exclude == nil && f.IncludePkg == nil && f.ExcludePkg == nil
+}
+
+// Apply returns suites with only the tests selected by the filter, and the
+// number of tests removed. Subtests of excluded tests are removed and parent
+// tests of selected subtests are kept. Suites left without tests are
+// removed.
+func (f *TestFilter) Apply(suites Suites) (Suites, int) {
+	var out Suites
+	removed := 0
+	for _, suite := range suites {
+		if !f.selectPkg(suite.Name) {
+			removed += len(suite.Tests)
+			continue
+		}
+		tests := f.filterTests(suite.Tests)
+		removed += len(suite.Tests) - len(tests)
+		if len(tests) == 0 && len(suite.Tests) > 0 {
+			continue
+		}
+		suite.Tests = tests
+		out = append(out, suite)
+	}
+	return out, removed
+}
+
+// selectPkg returns true if the package called name is selected
+func (f *TestFilter) selectPkg(name string) bool {
+	if f.IncludePkg != nil && !f.IncludePkg.MatchString(name) {
+		return false
+	}
+	return f.ExcludePkg == nil || !f.ExcludePkg.MatchString(name)
+}
+
+// selectTest returns true if the test called name, or one of its parents,
+// is included and none of them is excluded
+func (f *TestFilter) selectTest(name string) bool {
+	included := f.Include == nil
+	for _, prefix := range namePrefixes(name) {
+		if f.Exclude != nil && f.Exclude.MatchString(prefix) {
+			return false
+		}
+		if f.Include != nil && f.Include.MatchString(prefix) {
+			included = true
+		}
+	}
+	return included
+}
+
+// filterTests returns the selected tests, and parents of selected subtests.
+// Parents that failed only since a removed subtest failed get the status of
+// their remaining subtests.
+func (f *TestFilter) filterTests(tests []*Test) []*Test {
+	selected := make(map[string]bool)
+	for _, test := range tests {
+		if !f.selectTest(test.Name) {
+			continue
+		}
+		for _, prefix := range namePrefixes(test.Name) {
+			selected[prefix] = true
+		}
+	}
+
+	var out []*Test
+	// Parents of removed tests that failed
+	failedParents := make(map[string]bool)
+	for _, test := range tests {
+		switch {
+		case selected[test.Name]:
+			out = append(out, test)
+		case test.Status == Failed || test.Status == Errored:
+			for _, prefix := range namePrefixes(test.Name) {
+				failedParents[prefix] = true
+			}
+		}
+	}
+	updateParents(out, failedParents)
+	return out
+}
+
+// updateParents sets the status of failed tests in failedParents to the worst
+// status of their subtests in tests (passed if none failed)
+func updateParents(tests []*Test, failedParents map[string]bool) {
+	if len(failedParents) == 0 {
+		return
+	}
+
+	hasSubtests := make(map[string]bool)
+	for _, test := range tests {
+		prefixes := namePrefixes(test.Name)
+		for _, prefix := range prefixes[:len(prefixes)-1] {
+			hasSubtests[prefix] = true
+		}
+	}
+	// Worst status of leaf subtests by parent name
+	worst := make(map[string]Status)
+	for _, test := range tests {
+		if hasSubtests[test.Name] {
+			continue
+		}
+		prefixes := namePrefixes(test.Name)
+		for _, prefix := range prefixes[:len(prefixes)-1] {
+			if status, ok := worst[prefix]; !ok || statusSeverity[test.Status] > statusSeverity[status] {
+				worst[prefix] = test.Status
+			}
+		}
+	}
+
+	for _, test := range tests {
+		if !failedParents[test.Name] || (test.Status != Failed && test.Status != Errored) {
+			continue
+		}
+		status, ok := worst[test.Name]
+		if !ok || statusSeverity[status] < statusSeverity[Passed] {
+			status = Passed
+		}
+		test.Status = status
+		if status == Passed {
+			test.FailureMessage = ""
+		}
+	}
+}
+
+// namePrefixes returns the names of the test called name and its parents
+// (e.g. "A", "A/b", "A/b/c" for "A/b/c")
+func namePrefixes(name string) []string {
+	var prefixes []string
+	for i, c := range name {
+		if c == '/' {
+			prefixes = append(prefixes, name[:i])
+		}
+	}
+	return append(prefixes, name)
+}
@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"regexp"
+	"testing"
+)
+
+func filterSuites() Suites {
+	return Suites{
+		{Name: "example.com/a", Tests: []*Test{
+			{Name: "TestUnit", Status: Passed},
+			{Name: "TestIntegration", Status: Failed, isParentTest: true},
+			{Name: "TestIntegration/db", Status: Failed},
+			{Name: "TestIntegration/http", Status: Passed},
+		}},
+		{Name: "example.com/b", Tests: []*Test{
+			{Name: "TestIntegrationOnly", Status: Passed},
+		}},
+	}
+}
+
+func testNames(suites Suites) []string {
+	var names []string
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			names = append(names, suite.Name+"/"+test.Name)
+		}
+	}
+	return names
+}
+
+func TestFilterApply(t *testing.T) {
+	cases := []struct {
+		filter   TestFilter
+		names    []string
+		nRemoved int
+	}{
+		{
+			TestFilter{Exclude: regexp.MustCompile("^TestIntegration")},
+			[]string{"example.com/a/TestUnit"},
+			4,
+		},
+		{
+			TestFilter{Exclude: regexp.MustCompile("^TestIntegration/db$")},
+			[]string{
+				"example.com/a/TestUnit",
+				"example.com/a/TestIntegration",
+				"example.com/a/TestIntegration/http",
+				"example.com/b/TestIntegrationOnly",
+			},
+			1,
+		},
+		{
+			TestFilter{Include: regexp.MustCompile("/http$")},
+			[]string{"example.com/a/TestIntegration", "example.com/a/TestIntegration/http"},
+			3,
+		},
+		{
+			TestFilter{Include: regexp.MustCompile("^TestIntegration$")},
+			[]string{
+				"example.com/a/TestIntegration",
+				"example.com/a/TestIntegration/db",
+				"example.com/a/TestIntegration/http",
+			},
+			2,
+		},
+		{
+			TestFilter{ExcludePkg: regexp.MustCompile("/a$")},
+			[]string{"example.com/b/TestIntegrationOnly"},
+			4,
+		},
+	}
+
+	for _, tc := range cases {
+		suites, removed := tc.filter.Apply(filterSuites())
+		names := testNames(suites)
+		if removed != tc.nRemoved {
+			t.Errorf("%+v: removed %d, expected %d", tc.filter, removed, tc.nRemoved)
+		}
+		if len(names) != len(tc.names) {
+			t.Errorf("%+v: got %q, expected %q", tc.filter, names, tc.names)
+			continue
+		}
+		for i, name := range names {
+			if name != tc.names[i] {
+				t.Errorf("%+v: got %q, expected %q", tc.filter, names, tc.names)
+				break
+			}
+		}
+	}
+}
+
+func TestFilterParentStatus(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/a", Tests: []*Test{
+			{Name: "TestI", Status: Failed, FailureMessage: "db failed", isParentTest: true},
+			{Name: "TestI/db", Status: Failed},
+			{Name: "TestI/http", Status: Passed},
+			{Name: "TestJ", Status: Failed, isParentTest: true},
+			{Name: "TestJ/a", Status: Failed, isParentTest: true},
+			{Name: "TestJ/a/x", Status: Failed},
+			{Name: "TestJ/a/y", Status: Failed},
+			{Name: "TestJ/b", Status: Failed},
+		}},
+	}
+	filter := TestFilter{Exclude: regexp.MustCompile("^(TestI/db|TestJ/a/x|TestJ/b)$")}
+	suites, _ = filter.Apply(suites)
+
+	expected := map[string]Status{
+		"TestI":      Passed,
+		"TestI/http": Passed,
+		"TestJ":      Failed,
+		"TestJ/a":    Failed,
+		"TestJ/a/y":  Failed,
+	}
+	for _, test := range suites[0].Tests {
+		if test.Status != expected[test.Name] {
+			t.Errorf("%s: status %v, expected %v", test.Name, test.Status, expected[test.Name])
+		}
+	}
+	if len(suites[0].Tests) != len(expected) {
+		t.Fatalf("got %d tests, expected %d", len(suites[0].Tests), len(expected))
+	}
+	if msg := suites[0].Tests[0].FailureMessage; msg != "" {
+		t.Fatalf("passed parent with failure message %q", msg)
+	}
+	if !suites.HasFailures() {
+		t.Fatal("no failures")
+	}
+
+	suites, _ = filter.Apply(Suites{{Name: "example.com/b", Tests: []*Test{
+		{Name: "TestI", Status: Failed, isParentTest: true},
+		{Name: "TestI/db", Status: Failed},
+	}}})
+	if suites.HasFailures() {
+		t.Fatal("failures after the failed subtest was filtered out")
+	}
+}
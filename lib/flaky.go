@@ -0,0 +1,220 @@
+package lib
+
+import (
+	"fmt"
+	"time"
+)
+
+// testKey identifies a test across runs
+type testKey struct {
+	suite, test string
+}
+
+// DetectFlaky returns tests whose status differ between runs. For every
+// flaky test the returned value is the test from the most recent run it
+// appears in, with Flaky set.
+func DetectFlaky(runs []Suites) []*Test {
+	statuses := make(map[testKey]Status)
+	latest := make(map[testKey]*Test)
+	flaky := make(map[testKey]bool)
+	var keys []testKey
+
+	for _, run := range runs {
+		for _, suite := range run {
+			for _, test := range suite.Tests {
+				key := testKey{suite.Name, test.Name}
+				status, ok := statuses[key]
+				switch {
+				case !ok:
+					statuses[key] = test.Status
+					keys = append(keys, key)
+				case status != test.Status:
+					flaky[key] = true
+				}
+				latest[key] = test
+			}
+		}
+	}
+
+	var tests []*Test
+	for _, key := range keys {
+		if flaky[key] {
+			test := latest[key]
+			test.Flaky = true
+			tests = append(tests, test)
+		}
+	}
+	return tests
+}
+
+// Policies for tests that ran more than once in the same input (e.g. with
+// "go test -count=2")
+const (
+	RerunAll   = "all"   // Report every run
+	RerunLast  = "last"  // Report the last run
+	RerunWorst = "worst" // Report a failed run if there is one
+	RerunFlaky = "flaky" // Report failed and passed tests as passed and flaky
+)
+
+// ApplyRerunPolicy replaces multiple runs of the same test in a suite with a
+// single test according to policy (one of the Rerun* constants). With
+// RerunFlaky, tests that both failed and passed are reported as passed with
+// Flaky set and the output of the failed run.
+func ApplyRerunPolicy(suites Suites, policy string) error {
+	switch policy {
+	case RerunAll:
+		return nil
+	case RerunLast, RerunWorst, RerunFlaky:
+	default:
+		return fmt.Errorf("unknown rerun policy - %q", policy)
+	}
+
+	for _, suite := range suites {
+		runs := make(map[string][]*Test)
+		var names []string
+		for _, test := range suite.Tests {
+			if _, ok := runs[test.Name]; !ok {
+				names = append(names, test.Name)
+			}
+			runs[test.Name] = append(runs[test.Name], test)
+		}
+
+		tests := make([]*Test, 0, len(names))
+		for _, name := range names {
+			tests = append(tests, pickRun(runs[name], policy))
+		}
+		suite.Tests = tests
+	}
+	return nil
+}
+
+// pickRun returns the test to report from runs according to policy
+func pickRun(runs []*Test, policy string) *Test {
+	last := runs[len(runs)-1]
+	if len(runs) == 1 || policy == RerunLast {
+		return last
+	}
+
+	var failed, passed *Test
+	for _, test := range runs {
+		switch {
+		case isFailure(test.Status):
+			if failed == nil {
+				failed = test
+			}
+		case test.Status == Passed:
+			passed = test
+		}
+	}
+
+	switch {
+	case failed == nil:
+		return last
+	case policy == RerunWorst || passed == nil:
+		return failed
+	}
+
+	passed.Flaky = true
+	passed.Message = failed.Message
+	return passed
+}
+
+// How to report tests that still have more than one run after
+// ApplyRerunPolicy
+const (
+	RepeatSuffix    = "suffix"    // Report every run as "name#1", "name#2" ...
+	RepeatAggregate = "aggregate" // Report a single test with time statistics
+)
+
+// setRunCounts sets RunCount of tests to the number of runs of tests with the
+// same name in their suite
+func setRunCounts(suites Suites) {
+	for _, suite := range suites {
+		counts := make(map[string]int)
+		for _, test := range suite.Tests {
+			counts[test.Name]++
+		}
+		for _, test := range suite.Tests {
+			test.RunCount = counts[test.Name]
+		}
+	}
+}
+
+// DisambiguateRuns handles tests that ran more than once in the same suite
+// according to mode (one of the Repeat* constants). With RepeatAggregate,
+// the test is reported once with the worst status (Flaky is set if the runs
+// statuses differ), the mean time and "time.min", "time.max" and "time.mean"
+// properties.
+func DisambiguateRuns(suites Suites, mode string) error {
+	if mode != RepeatSuffix && mode != RepeatAggregate {
+		return fmt.Errorf("unknown repeat mode - %q", mode)
+	}
+
+	for _, suite := range suites {
+		runs := make(map[string][]*Test)
+		var names []string
+		for _, test := range suite.Tests {
+			if _, ok := runs[test.Name]; !ok {
+				names = append(names, test.Name)
+			}
+			runs[test.Name] = append(runs[test.Name], test)
+		}
+		if len(names) == len(suite.Tests) {
+			continue
+		}
+
+		if mode == RepeatSuffix {
+			seen := make(map[string]int)
+			for _, test := range suite.Tests {
+				if len(runs[test.Name]) > 1 {
+					seen[test.Name]++
+					test.Name = fmt.Sprintf("%s#%d", test.Name, seen[test.Name])
+				}
+			}
+			continue
+		}
+
+		tests := make([]*Test, 0, len(names))
+		for _, name := range names {
+			tests = append(tests, aggregateRuns(runs[name]))
+		}
+		suite.Tests = tests
+	}
+	return nil
+}
+
+// aggregateRuns returns a single test summarizing runs
+func aggregateRuns(runs []*Test) *Test {
+	if len(runs) == 1 {
+		return runs[0]
+	}
+
+	test := pickRun(runs, RerunWorst)
+	var min, max, total time.Duration
+	for i, run := range runs {
+		elapsed := run.Elapsed()
+		if i == 0 || elapsed < min {
+			min = elapsed
+		}
+		if elapsed > max {
+			max = elapsed
+		}
+		total += elapsed
+	}
+	mean := total / time.Duration(len(runs))
+
+	test.Time = FormatSeconds(mean)
+	test.RunCount = len(runs)
+	for _, run := range runs {
+		if run.Status != test.Status {
+			test.Flaky = true
+		}
+	}
+	test.Properties = append(test.Properties,
+		Property{"runs", fmt.Sprintf("%d", len(runs))},
+		Property{"time.min", FormatSeconds(min)},
+		Property{"time.max", FormatSeconds(max)},
+		Property{"time.mean", FormatSeconds(mean)},
+	)
+	return test
+}
@@ -0,0 +1,167 @@
+package lib
+
+import "testing"
+
+func flakyRun(status Status) Suites {
+	return Suites{
+		{Name: "pkg", Tests: []*Test{
+			{Name: "TestStable", Status: Passed},
+			{Name: "TestFlaky", Status: status},
+		}},
+	}
+}
+
+func TestDetectFlaky(t *testing.T) {
+	runs := []Suites{flakyRun(Passed), flakyRun(Failed), flakyRun(Passed)}
+	flaky := DetectFlaky(runs)
+	if len(flaky) != 1 {
+		t.Fatalf("got %d flaky tests instead of 1", len(flaky))
+	}
+	test := flaky[0]
+	if test.Name != "TestFlaky" || !test.Flaky {
+		t.Fatalf("bad flaky test: %+v", test)
+	}
+	if test != runs[2][0].Tests[1] {
+		t.Fatalf("flaky test is not from the last run")
+	}
+	if runs[2][0].Tests[0].Flaky {
+		t.Fatalf("stable test marked as flaky")
+	}
+}
+
+func TestApplyRerunPolicy(t *testing.T) {
+	for _, name := range []string{"fail-pass", "pass-fail"} {
+		filename := "../_data/in/gotest-rerun-" + name + ".out"
+		for _, tc := range []struct {
+			policy string
+			status Status
+			flaky  bool
+		}{
+			{RerunWorst, Failed, false},
+			{RerunFlaky, Passed, true},
+		} {
+			suites, err := loadGotest(filename, t)
+			if err != nil {
+				t.Fatalf("error loading %s - %s", filename, err)
+			}
+			if n := len(suites[0].Tests); n != 4 {
+				t.Fatalf("%s: %d tests before policy, should be 4", name, n)
+			}
+			if err := ApplyRerunPolicy(suites, tc.policy); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := suites[0].Tests
+			if len(tests) != 2 || tests[0].Name != "TestStable" || tests[0].Flaky {
+				t.Fatalf("%s/%s: bad tests - %v", name, tc.policy, tests)
+			}
+			test := tests[1]
+			if test.Status != tc.status || test.Flaky != tc.flaky {
+				t.Fatalf("%s/%s: bad test - %+v", name, tc.policy, test)
+			}
+			if test.Message == "" {
+				t.Fatalf("%s/%s: output of failed run missing", name, tc.policy)
+			}
+		}
+	}
+
+	suites, err := loadGotest("../_data/in/gotest-rerun-pass-fail.out", t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyRerunPolicy(suites, RerunLast); err != nil {
+		t.Fatal(err)
+	}
+	if test := suites[0].Tests[1]; test.Status != Failed {
+		t.Fatalf("last run not reported - %+v", test)
+	}
+
+	if err := ApplyRerunPolicy(suites, "best"); err == nil {
+		t.Fatal("no error on unknown policy")
+	}
+}
+
+func TestDisambiguateRuns(t *testing.T) {
+	filename := "../_data/in/gotest-count.out"
+	load := func() Suites {
+		suites, err := loadGotest(filename, t)
+		if err != nil {
+			t.Fatalf("error loading %s - %s", filename, err)
+		}
+		return suites
+	}
+
+	suites := load()
+	if err := DisambiguateRuns(suites, RepeatSuffix); err != nil {
+		t.Fatal(err)
+	}
+	tests := suites[0].Tests
+	if len(tests) != 6 || tests[0].Name != "TestAdd#1" || tests[5].Name != "TestSub#3" {
+		t.Fatalf("bad suffixed tests: %v", tests)
+	}
+	if n := suites.Stats()["pass"]; n != 6 {
+		t.Fatalf("%d passed tests, should be 6", n)
+	}
+
+	suites = load()
+	if err := DisambiguateRuns(suites, RepeatAggregate); err != nil {
+		t.Fatal(err)
+	}
+	tests = suites[0].Tests
+	if len(tests) != 2 || tests[0].Name != "TestAdd" || tests[0].Time != "0.200" {
+		t.Fatalf("bad aggregated tests: %v", tests)
+	}
+	props := map[string]string{}
+	for _, prop := range tests[0].Properties {
+		props[prop.Name] = prop.Value
+	}
+	if props["runs"] != "3" || props["time.min"] != "0.100" || props["time.max"] != "0.300" {
+		t.Fatalf("bad properties: %v", props)
+	}
+	if n := suites.Stats()["pass"]; n != 2 {
+		t.Fatalf("%d passed tests, should be 2", n)
+	}
+
+	if err := DisambiguateRuns(suites, "nope"); err == nil {
+		t.Fatal("no error on unknown mode")
+	}
+}
+
+func TestRunCount(t *testing.T) {
+	filename := "../_data/in/gotest-count.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	for _, test := range suites[0].Tests {
+		if test.RunCount != 3 {
+			t.Fatalf("%s: RunCount is %d, should be 3", test.Name, test.RunCount)
+		}
+	}
+	if err := DisambiguateRuns(suites, RepeatAggregate); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range suites[0].Tests {
+		if test.RunCount != 3 || test.Status != Passed || test.Flaky {
+			t.Fatalf("bad aggregated test: %+v", test)
+		}
+	}
+
+	filename = "../_data/in/gotest-rerun-fail-pass.out"
+	suites, err = loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	if err := DisambiguateRuns(suites, RepeatAggregate); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range suites[0].Tests {
+		flaky := test.Name == "TestFlaky"
+		if test.RunCount != 2 || test.Flaky != flaky {
+			t.Fatalf("bad aggregated test: %+v", test)
+		}
+		if flaky && test.Status != Failed {
+			t.Fatalf("flaky test status is %v, should be failed", test.Status)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// histogramWidth is the width of the longest bar of WriteHistogram
+const histogramWidth = 40
+
+// DefaultDurationBuckets are the default upper bounds of DurationBuckets
+var DefaultDurationBuckets = []time.Duration{100 * time.Millisecond, time.Second, 10 * time.Second}
+
+// DurationBuckets returns the number of tests in suites by elapsed time range,
+// buckets are the range bounds. Keys are range labels: "<100ms" for tests
+// faster than the first bound, "100ms-1s" for tests between bounds (including
+// the lower one) and ">10s" for tests at least as slow as the last bound.
+// Parent tests of subtests and package level results are not counted.
+func DurationBuckets(suites Suites, buckets []time.Duration) map[string]int {
+	bounds, labels := bucketLabels(buckets)
+	counts := make(map[string]int, len(labels))
+	for _, label := range labels {
+		counts[label] = 0
+	}
+
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.isParentTest || test.isSynthetic {
+				continue
+			}
+			elapsed := test.Elapsed()
+			i := sort.Search(len(bounds), func(i int) bool { return elapsed < bounds[i] })
+			counts[labels[i]]++
+		}
+	}
+	return counts
+}
+
+// bucketLabels returns the sorted unique bounds of buckets and the labels of
+// the ranges between them, there's one more label than bounds
+func bucketLabels(buckets []time.Duration) ([]time.Duration, []string) {
+	var bounds []time.Duration
+	seen := make(map[time.Duration]bool)
+	for _, bound := range buckets {
+		if !seen[bound] {
+			seen[bound] = true
+			bounds = append(bounds, bound)
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	if len(bounds) == 0 {
+		return nil, []string{"all"}
+	}
+	labels := []string{"<" + bounds[0].String()}
+	for i := 1; i < len(bounds); i++ {
+		labels = append(labels, bounds[i-1].String()+"-"+bounds[i].String())
+	}
+	labels = append(labels, ">"+bounds[len(bounds)-1].String())
+	return bounds, labels
+}
+
+// WriteHistogram writes the DurationBuckets of suites to w as a table with a
+// bar for every range, fastest first
+func WriteHistogram(w io.Writer, suites Suites, buckets []time.Duration) error {
+	counts := DurationBuckets(suites, buckets)
+	_, labels := bucketLabels(buckets)
+	max := 0
+	for _, n := range counts {
+		if n > max {
+			max = n
+		}
+	}
+
+	labelWidth := len("TIME")
+	for _, label := range labels {
+		if len(label) > labelWidth {
+			labelWidth = len(label)
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "%-*s  %5s\n", labelWidth, "TIME", "TESTS"); err != nil {
+		return err
+	}
+	for _, label := range labels {
+		n := counts[label]
+		width := 0
+		if max > 0 {
+			width = n * histogramWidth / max
+		}
+		if n > 0 && width == 0 {
+			width = 1
+		}
+		line := fmt.Sprintf("%-*s  %5d  %s", labelWidth, label, n, strings.Repeat("#", width))
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationBuckets(t *testing.T) {
+	suites := Suites{
+		{Name: "a", Tests: []*Test{
+			{Name: "TestZero", Time: "0.000"},
+			{Name: "TestFast", Time: "0.099"},
+			{Name: "TestBound", Time: "0.100"},
+			{Name: "TestMedium", Time: "0.500"},
+			{Name: "TestParent", Time: "12.0", isParentTest: true},
+			{Name: "TestParent/sub", Time: "12.0"},
+		}},
+		{Name: "b", Tests: []*Test{
+			{Name: "TestSecond", Time: "1.000"},
+			{Name: "TestSlow", Time: "9.999"},
+			{Name: "TestTen", Time: "10.0"},
+		}},
+	}
+
+	buckets := []time.Duration{10 * time.Second, 100 * time.Millisecond, time.Second}
+	expected := map[string]int{
+		"<100ms":   2,
+		"100ms-1s": 2,
+		"1s-10s":   2,
+		">10s":     2,
+	}
+	if counts := DurationBuckets(suites, buckets); !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("buckets: %v, expected %v", counts, expected)
+	}
+
+	expected = map[string]int{"all": 8}
+	if counts := DurationBuckets(suites, nil); !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("no buckets: %v, expected %v", counts, expected)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHistogram(&buf, suites, DefaultDurationBuckets); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[1], "<100ms") || !strings.HasPrefix(lines[4], ">10s") {
+		t.Fatalf("bad histogram:\n%s", buf.String())
+	}
+	if !strings.HasSuffix(lines[1], strings.Repeat("#", histogramWidth)) {
+		t.Fatalf("bad bar: %q", lines[1])
+	}
+}
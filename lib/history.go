@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryEntry is the record of a run in a history file, a JSON line
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	// Counts are the number of tests by status (see Suites.Stats)
+	Counts map[string]int `json:"counts"`
+	// Elapsed is the total elapsed time of packages in seconds
+	Elapsed float64 `json:"elapsed"`
+	// Failed are the failed (or errored) tests as "package/Test"
+	Failed []string `json:"failed,omitempty"`
+}
+
+// NewHistoryEntry returns the history entry of a run of suites at runTime
+func NewHistoryEntry(suites Suites, runTime time.Time) HistoryEntry {
+	entry := HistoryEntry{Time: runTime, Counts: suites.Stats()}
+	var elapsed time.Duration
+	for _, suite := range suites {
+		elapsed += suite.Elapsed()
+		for _, test := range suite.Tests {
+			if !test.isParentTest && (test.Status == Failed || test.Status == Errored) {
+				entry.Failed = append(entry.Failed, suite.Name+"/"+test.Name)
+			}
+		}
+	}
+	entry.Elapsed = elapsed.Seconds()
+	sort.Strings(entry.Failed)
+	return entry
+}
+
+// MarshalLine returns entry as a JSON line (ending with a newline)
+func (entry HistoryEntry) MarshalLine() ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ReadHistory reads the JSON lines history entries of rd, the numbers of
+// lines that are not entries (e.g. corrupted by a crash) are returned in bad
+func ReadHistory(rd io.Reader) (entries []HistoryEntry, bad []int, err error) {
+	scanner := NewLineScanner(rd)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil || entry.Counts == nil {
+			bad = append(bad, line)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return entries, bad, nil
+}
+
+// CompareHistory returns the number of tests that failed in cur but not in
+// prev (regressed) and that failed in prev but not in cur (fixed)
+func CompareHistory(prev, cur HistoryEntry) (regressed, fixed int) {
+	prevFailed := make(map[string]bool, len(prev.Failed))
+	for _, name := range prev.Failed {
+		prevFailed[name] = true
+	}
+	curFailed := make(map[string]bool, len(cur.Failed))
+	for _, name := range cur.Failed {
+		curFailed[name] = true
+	}
+	for name := range curFailed {
+		if !prevFailed[name] {
+			regressed++
+		}
+	}
+	for name := range prevFailed {
+		if !curFailed[name] {
+			fixed++
+		}
+	}
+	return regressed, fixed
+}
@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/a", Time: "1.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+			{Name: "TestB", Status: Failed, isParentTest: true},
+			{Name: "TestB/sub", Status: Failed},
+		}},
+		{Name: "example.com/b", Time: "0.5", Tests: []*Test{
+			{Name: "TestC", Status: Errored},
+		}},
+	}
+	runTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry := NewHistoryEntry(suites, runTime)
+	if entry.Elapsed != 2 || entry.Counts["pass"] != 1 || entry.Counts["fail"] != 2 || entry.Counts["error"] != 1 {
+		t.Fatalf("bad entry: %+v", entry)
+	}
+	if strings.Join(entry.Failed, ",") != "example.com/a/TestB/sub,example.com/b/TestC" {
+		t.Fatalf("bad failed tests: %v", entry.Failed)
+	}
+
+	line, err := entry.MarshalLine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := string(line) + "{\"time\": \"2020-01\n\n" + `{"time":"2020-01-03T00:00:00Z","counts":{"pass":2},"failed":["example.com/b/TestC","example.com/b/TestD"]}` + "\n"
+	entries, bad, err := ReadHistory(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !entries[0].Time.Equal(runTime) || len(bad) != 1 || bad[0] != 2 {
+		t.Fatalf("bad history: %+v (bad lines %v)", entries, bad)
+	}
+
+	regressed, fixed := CompareHistory(entries[0], entries[1])
+	if regressed != 1 || fixed != 1 {
+		t.Fatalf("regressed %d, fixed %d, expected 1 and 1", regressed, fixed)
+	}
+}
@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// HTMLTemplate is the template of WriteHTML
+const HTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Test report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { padding: 2px 8px; text-align: left; }
+.pass { color: green; }
+.fail, .error { color: red; }
+.skip { color: orange; }
+pre { margin: 0; }
+.artifacts { font-size: smaller; }
+</style>
+</head>
+<body>
+<h1>{{.Total}} tests, {{.Stats.fail}} failed, {{.Stats.error}} errors, {{.Stats.skip}} skipped</h1>
+<p>{{.Time.Format "2006-01-02 15:04:05 MST"}}</p>
+{{range .Suites}}<h2>{{.Name}}</h2>
+<table>
+<tr><th>Status</th><th>Test</th><th>Time</th></tr>
+{{range .Tests}}<tr><td class="{{status .Status}}">{{status .Status}}</td><td>{{if .Message}}<details><summary>{{.Name}}</summary><pre>{{.Message}}</pre></details>{{else}}{{.Name}}{{end}}</td><td>{{.Elapsed}}</td></tr>
+{{if .Artifacts}}<tr><td></td><td colspan="2" class="artifacts">{{range .Artifacts}}<a href="{{.}}">{{.}}</a> {{end}}</td></tr>
+{{end}}{{end}}</table>
+{{end}}</body>
+</html>
+`
+
+// htmlReport is passed to HTMLTemplate
+type htmlReport struct {
+	Suites Suites
+	Stats  map[string]int
+	Total  int
+	Time   time.Time
+}
+
+var htmlTemplate = template.Must(template.New("html").Funcs(template.FuncMap{
+	"status": StatusName,
+}).Parse(HTMLTemplate))
+
+// WriteHTML writes suites to w as an HTML page: the totals, and a table per
+// package with the status and time of its tests. The output of tests is shown
+// when the test name is clicked, their artifacts are links under them.
+func WriteHTML(w io.Writer, suites Suites, testTime time.Time) error {
+	stats := suites.Stats()
+	return htmlTemplate.Execute(w, htmlReport{suites, stats, statsTotal(stats), testTime})
+}
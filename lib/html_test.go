@@ -0,0 +1,38 @@
+package lib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTML(t *testing.T) {
+	suites := Suites{
+		{Name: "pkg/a", Tests: []*Test{
+			{Name: "TestOK", Status: Passed},
+			{Name: "TestBad", Status: Failed, Message: "a_test.go:10: got <b>1</b>",
+				Artifacts: []string{"out/diff.png", "out/log file.txt"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, suites, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, text := range []string{
+		"<h1>2 tests, 1 failed, 0 errors, 0 skipped</h1>",
+		"<h2>pkg/a</h2>",
+		`<td class="pass">pass</td><td>TestOK</td>`,
+		"<summary>TestBad</summary><pre>a_test.go:10: got &lt;b&gt;1&lt;/b&gt;</pre>",
+		`<a href="out/diff.png">out/diff.png</a> <a href="out/log%20file.txt">out/log file.txt</a>`,
+	} {
+		if !strings.Contains(out, text) {
+			t.Fatalf("%q not in\n%s", text, out)
+		}
+	}
+	if strings.Count(out, `class="artifacts"`) != 1 {
+		t.Fatalf("artifacts of tests without any:\n%s", out)
+	}
+}
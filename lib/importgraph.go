@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GoPackage is a package of "go list -json" output
+type GoPackage struct {
+	ImportPath string
+	Imports    []string
+}
+
+// Import graph node colors by package status
+const (
+	graphPassColor     = "green"
+	graphFailColor     = "red"
+	graphUntestedColor = "grey"
+)
+
+// WriteImportGraph writes the import graph of packages as a Graphviz DOT
+// digraph: nodes are packages, colored by their status in suites (green if
+// all tests passed, red if a test failed and grey if not tested), and edges
+// are imports between packages. Imports of other packages (e.g. the standard
+// library) are omitted.
+func WriteImportGraph(w io.Writer, packages []GoPackage, suites Suites) error {
+	failed := make(map[string]bool)
+	tested := make(map[string]bool)
+	for _, suite := range suites {
+		if suite.Len() > 0 {
+			tested[suite.Name] = true
+		}
+		if suite.NumFailed()+suite.NumErrors() > 0 {
+			failed[suite.Name] = true
+		}
+	}
+	listed := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		listed[pkg.ImportPath] = true
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph imports {\n\tnode [shape=box, style=filled];\n")
+	for _, pkg := range packages {
+		color := graphUntestedColor
+		switch {
+		case failed[pkg.ImportPath]:
+			color = graphFailColor
+		case tested[pkg.ImportPath]:
+			color = graphPassColor
+		}
+		fmt.Fprintf(&buf, "\t%s [label=%s, fillcolor=%s];\n", dotQuote(pkg.ImportPath), dotQuote(pkg.ImportPath), color)
+	}
+	for _, pkg := range packages {
+		for _, imp := range pkg.Imports {
+			if listed[imp] {
+				fmt.Fprintf(&buf, "\t%s -> %s;\n", dotQuote(pkg.ImportPath), dotQuote(imp))
+			}
+		}
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// dotQuoter escapes DOT quoted strings
+var dotQuoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// dotQuote returns s as a DOT quoted string
+func dotQuote(s string) string {
+	return `"` + dotQuoter.Replace(s) + `"`
+}
@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteImportGraph(t *testing.T) {
+	packages := []GoPackage{
+		{ImportPath: "example.com/app", Imports: []string{"example.com/app/db", "example.com/app/web", "fmt"}},
+		{ImportPath: "example.com/app/db", Imports: []string{"database/sql"}},
+		{ImportPath: "example.com/app/web", Imports: []string{"example.com/app/db", "net/http"}},
+	}
+	suites := Suites{
+		{Name: "example.com/app/db", Tests: []*Test{{Name: "TestDB", Status: Passed}}},
+		{Name: "example.com/app/web", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+			{Name: "TestB", Status: Failed},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteImportGraph(&buf, packages, suites); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph imports {\n") || !strings.HasSuffix(out, "}\n") {
+		t.Fatalf("bad graph:\n%s", out)
+	}
+	for _, line := range []string{
+		`"example.com/app" [label="example.com/app", fillcolor=grey];`,
+		`"example.com/app/db" [label="example.com/app/db", fillcolor=green];`,
+		`"example.com/app/web" [label="example.com/app/web", fillcolor=red];`,
+		`"example.com/app" -> "example.com/app/db";`,
+		`"example.com/app/web" -> "example.com/app/db";`,
+	} {
+		if !strings.Contains(out, "\t"+line+"\n") {
+			t.Fatalf("%s not in:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "fmt") || strings.Contains(out, "net/http") {
+		t.Fatalf("standard library in graph:\n%s", out)
+	}
+}
@@ -0,0 +1,156 @@
+package lib
+
+// JUnit XML output with encoding/xml
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+type junitProperties struct {
+	Properties []xunitProperty `xml:"property"`
+}
+
+type junitResult struct {
+	Type    string `xml:"type,attr,omitempty"`
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	XMLName    xml.Name         `xml:"testcase"`
+	Classname  string           `xml:"classname,attr"`
+	Name       string           `xml:"name,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Skipped    *junitResult     `xml:"skipped,omitempty"`
+	Failure    *junitResult     `xml:"failure,omitempty"`
+	Error      *junitResult     `xml:"error,omitempty"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+}
+
+type junitSuite struct {
+	XMLName    xml.Name         `xml:"testsuite"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Disabled   int              `xml:"disabled,attr,omitempty"`
+	Time       string           `xml:"time,attr,omitempty"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	TestCases  []junitTestCase
+}
+
+type junitSuites struct {
+	XMLName  xml.Name `xml:"testsuites"`
+	Tests    int      `xml:"tests,attr"`
+	Errors   int      `xml:"errors,attr"`
+	Failures int      `xml:"failures,attr"`
+	Skipped  int      `xml:"skipped,attr"`
+	Time     string   `xml:"time,attr"`
+	Suites   []junitSuite
+}
+
+// newJUnitProperties returns properties element from props, nil if empty
+func newJUnitProperties(props []Property) *junitProperties {
+	if len(props) == 0 {
+		return nil
+	}
+	out := &junitProperties{}
+	for _, prop := range props {
+		out.Properties = append(out.Properties, xunitProperty(prop))
+	}
+	return out
+}
+
+// testProperties returns the properties reported for test
+func testProperties(test *Test) []Property {
+	var props []Property
+	if bench := test.Benchmark; bench != nil {
+		props = append(props,
+			Property{"ns/op", fmt.Sprint(bench.NsPerOp)},
+			Property{"B/op", fmt.Sprint(bench.BytesPerOp)},
+			Property{"allocs/op", fmt.Sprint(bench.AllocsPerOp)},
+		)
+		props = append(props, bench.ExtraMetrics()...)
+	}
+	if test.Flaky {
+		props = append(props, Property{"flaky", "true"})
+	}
+	for i, path := range test.Artifacts {
+		props = append(props, Property{fmt.Sprintf("artifact.%d", i), path})
+	}
+	return append(props, test.Properties...)
+}
+
+// newJUnitTestCase returns the testcase element of test in suite
+func newJUnitTestCase(suite *Suite, test *Test) junitTestCase {
+	tc := junitTestCase{
+		Classname:  suite.Name,
+		Name:       test.Name,
+		Time:       FormatSeconds(test.Elapsed()),
+		Properties: newJUnitProperties(testProperties(test)),
+	}
+	switch test.Status {
+	case Skipped:
+		tc.Skipped = &junitResult{Message: test.SkipReason, Body: test.Message}
+	case Failed:
+		tc.Failure = &junitResult{Type: "go.error", Message: test.FailureMessage, Body: test.Message}
+	case Errored:
+		tc.Error = &junitResult{Type: "go.error", Message: test.FailureMessage, Body: test.Message}
+	case Passed:
+		if test.Flaky {
+			tc.SystemOut = test.Message
+		}
+	}
+	for _, path := range test.Attachments {
+		tc.SystemOut += "\n" + AttachmentMarker(path)
+	}
+	return tc
+}
+
+// newJUnitSuite returns the testsuite element of suite
+func newJUnitSuite(suite *Suite) junitSuite {
+	js := junitSuite{
+		Name:       suite.Name,
+		Tests:      suite.Len(),
+		Errors:     suite.NumErrors(),
+		Failures:   suite.NumFailed(),
+		Skipped:    suite.NumSkipped(),
+		Disabled:   suite.NumDisabled(),
+		Timestamp:  FormatTimestamp(suite.Started),
+		Properties: newJUnitProperties(suite.Properties),
+	}
+	if elapsed := suite.Elapsed(); elapsed > 0 {
+		js.Time = FormatSeconds(elapsed)
+	}
+	for _, test := range suite.Tests {
+		js.TestCases = append(js.TestCases, newJUnitTestCase(suite, test))
+	}
+	return js
+}
+
+// MarshalXML implements xml.Marshaler, suite is encoded as a JUnit
+// <testsuite> element
+func (suite *Suite) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(newJUnitSuite(suite))
+}
+
+// MarshalXML implements xml.Marshaler, suites are encoded as a JUnit
+// <testsuites> element
+func (s Suites) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	js := junitSuites{}
+	var total time.Duration
+	for _, suite := range s {
+		js.Suites = append(js.Suites, newJUnitSuite(suite))
+		js.Tests += suite.Len()
+		js.Errors += suite.NumErrors()
+		js.Failures += suite.NumFailed()
+		js.Skipped += suite.NumSkipped()
+		total += suite.Elapsed()
+	}
+	js.Time = FormatSeconds(total)
+	return e.Encode(js)
+}
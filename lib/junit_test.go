@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestMarshalXML(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/a", Time: "1.5", Tests: []*Test{
+			{Name: "TestPass", Time: "0.1", Status: Passed},
+			{Name: "TestFail", Time: "0.2", Status: Failed, Message: "a < b & ]]>", FailureMessage: "a < b"},
+			{Name: "TestError", Time: "0", Status: Errored, Message: "panic"},
+		}},
+		{Name: "example.com/b", Time: "(cached)", Tests: []*Test{
+			{Name: "TestSkip", Time: "0", Status: Skipped, SkipReason: "short"},
+		}},
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		XMLName  xml.Name `xml:"testsuites"`
+		Tests    int      `xml:"tests,attr"`
+		Failures int      `xml:"failures,attr"`
+		Errors   int      `xml:"errors,attr"`
+		Skipped  int      `xml:"skipped,attr"`
+		Suites   []struct {
+			Name     string  `xml:"name,attr"`
+			Tests    int     `xml:"tests,attr"`
+			Time     *string `xml:"time,attr"`
+			Testcase []struct {
+				Name    string `xml:"name,attr"`
+				Time    string `xml:"time,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+					Body    string `xml:",chardata"`
+				} `xml:"failure"`
+				Error   *struct{} `xml:"error"`
+				Skipped *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"skipped"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("%s:\n%s", err, data)
+	}
+
+	if report.Tests != 4 || report.Failures != 1 || report.Errors != 1 || report.Skipped != 1 {
+		t.Fatalf("bad totals:\n%s", data)
+	}
+	if len(report.Suites) != 2 || report.Suites[0].Name != "example.com/a" || report.Suites[0].Tests != 3 {
+		t.Fatalf("bad suites:\n%s", data)
+	}
+	if tm := report.Suites[0].Time; tm == nil || *tm != "1.500" {
+		t.Fatalf("bad suite time:\n%s", data)
+	}
+	if report.Suites[1].Time != nil {
+		t.Fatalf("time of cached suite:\n%s", data)
+	}
+
+	cases := report.Suites[0].Testcase
+	if cases[0].Name != "TestPass" || cases[0].Time != "0.100" || cases[0].Failure != nil {
+		t.Fatalf("bad passed test:\n%s", data)
+	}
+	if f := cases[1].Failure; f == nil || f.Message != "a < b" || f.Body != "a < b & ]]>" {
+		t.Fatalf("bad failure:\n%s", data)
+	}
+	if cases[2].Error == nil {
+		t.Fatalf("bad error:\n%s", data)
+	}
+	if s := report.Suites[1].Testcase[0].Skipped; s == nil || s.Message != "short" {
+		t.Fatalf("bad skip:\n%s", data)
+	}
+
+	// Single suite, and reading back with ParseXUnit
+	data, err = xml.Marshal(suites[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseXUnit(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 1 || parsed[0].Len() != 3 || parsed[0].NumFailed() != 1 || parsed[0].NumErrors() != 1 {
+		t.Fatalf("bad round trip:\n%s", data)
+	}
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WriteLCOV writes the coverage of suites as an LCOV tracefile (.info). The
+// lines of packages with a coverage profile in the input ("go test
+// -coverprofile" output) are written by file. "go test -cover" reports only
+// the statement coverage of other packages, each is a synthetic record of one
+// line that is covered if any statement is.
+func WriteLCOV(w io.Writer, suites Suites) error {
+	bw := bufio.NewWriter(w)
+	for _, suite := range suites {
+		switch {
+		case len(suite.CoverProfile) > 0:
+			writeLCOVProfile(bw, suite)
+		case suite.HasCoverage:
+			hits := 0
+			if suite.Coverage > 0 {
+				hits = 1
+			}
+			fmt.Fprintf(bw, "TN:%s\nSF:%s\nDA:1,%d\nLH:%d\nLF:1\nend_of_record\n",
+				lcovName(suite.Name), suite.Name, hits, hits)
+		}
+	}
+	return bw.Flush()
+}
+
+// writeLCOVProfile writes a record per file of the suite coverage profile,
+// the hit count of a line is the highest count of the blocks it's in
+func writeLCOVProfile(w io.Writer, suite *Suite) {
+	var files []string
+	lines := make(map[string]map[int]int)
+	for _, block := range suite.CoverProfile {
+		hits, ok := lines[block.File]
+		if !ok {
+			hits = make(map[int]int)
+			lines[block.File] = hits
+			files = append(files, block.File)
+		}
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			if count, ok := hits[line]; !ok || block.Count > count {
+				hits[line] = block.Count
+			}
+		}
+	}
+
+	for _, file := range files {
+		hits := lines[file]
+		numbers := make([]int, 0, len(hits))
+		for line := range hits {
+			numbers = append(numbers, line)
+		}
+		sort.Ints(numbers)
+
+		fmt.Fprintf(w, "TN:%s\nSF:%s\n", lcovName(suite.Name), file)
+		covered := 0
+		for _, line := range numbers {
+			fmt.Fprintf(w, "DA:%d,%d\n", line, hits[line])
+			if hits[line] > 0 {
+				covered++
+			}
+		}
+		fmt.Fprintf(w, "LH:%d\nLF:%d\nend_of_record\n", covered, len(numbers))
+	}
+}
+
+// lcovName returns name as an LCOV test name, which has only letters, digits
+// and underscores
+func lcovName(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			runes[i] = '_'
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteLCOV(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/a", Coverage: 0.755, HasCoverage: true},
+		{Name: "example.com/b"},
+		{Name: "example.com/c", HasCoverage: true},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLCOV(&buf, suites); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, text := range []string{
+		"TN:example_com_a\nSF:example.com/a\nDA:1,1\nLH:1\nLF:1\nend_of_record\n",
+		"TN:example_com_c\nSF:example.com/c\nDA:1,0\nLH:0\nLF:1\nend_of_record\n",
+	} {
+		if !strings.Contains(out, text) {
+			t.Fatalf("%q not in:\n%s", text, out)
+		}
+	}
+	if strings.Count(out, "end_of_record") != 2 || strings.Contains(out, "example.com/b") {
+		t.Fatalf("bad records:\n%s", out)
+	}
+}
+
+func TestWriteLCOVProfile(t *testing.T) {
+	suites := Suites{
+		{
+			Name:        "example.com/a",
+			Coverage:    0.5,
+			HasCoverage: true,
+			CoverProfile: []CoverBlock{
+				{File: "example.com/a/a.go", StartLine: 3, EndLine: 5, NumStmt: 2, Count: 0},
+				{File: "example.com/a/b.go", StartLine: 1, EndLine: 1, NumStmt: 1, Count: 2},
+				{File: "example.com/a/a.go", StartLine: 5, EndLine: 6, NumStmt: 1, Count: 3},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLCOV(&buf, suites); err != nil {
+		t.Fatal(err)
+	}
+	exp := "TN:example_com_a\nSF:example.com/a/a.go\n" +
+		"DA:3,0\nDA:4,0\nDA:5,3\nDA:6,3\nLH:2\nLF:4\nend_of_record\n" +
+		"TN:example_com_a\nSF:example.com/a/b.go\n" +
+		"DA:1,2\nLH:1\nLF:1\nend_of_record\n"
+	if out := buf.String(); out != exp {
+		t.Fatalf("bad LCOV:\n%s\nshould be:\n%s", out, exp)
+	}
+}
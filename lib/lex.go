@@ -14,6 +14,9 @@ var (
 	gtStartRE = regexp.MustCompile(
 		"^=== RUN:?[[:space:]]+([a-zA-Z_][^[:space:]]*)")
 
+	// === NAME  TestAdd
+	gtNameRE = regexp.MustCompile("^=== NAME:?[[:space:]]*([^[:space:]]*)")
+
 	// --- PASS: TestSub (0.00 seconds)
 	// --- FAIL: TestSubFail (0.00 seconds)
 	// --- SKIP: TestSubSkip (0.00 seconds)
@@ -26,14 +29,87 @@ var (
 	gtSuiteRE = regexp.MustCompile(
 		"^(ok|FAIL)[ \t]+([^ \t]+)[ \t]+((-?\\d+.\\d+)|\\(cached\\))")
 
+	// coverage: 78.6% of statements
+	// ok  	example.com/mmath	0.003s	coverage: 78.6% of statements
+	// coverage: 50.0% of statements in ./...
+	// coverage: [no statements]
+	gtCoverageRE = regexp.MustCompile("coverage: (([0-9.]+)% of statements|\\[no statements\\])")
+
+	// Lines of a coverage profile ("go test -coverprofile") in the input
+	// mode: set
+	// example.com/pkg/file.go:10.2,12.16 2 1
+	gtCoverModeRE  = regexp.MustCompile(`^mode: (set|count|atomic)$`)
+	gtCoverBlockRE = regexp.MustCompile(`^(.+\.go):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+	// Package without tests with -cover (go 1.22+)
+	//	example.com/notests		coverage: 0.0% of statements
+	gtNoTestsCoverageRE = regexp.MustCompile(
+		"^[ \t]*([^ \t]+)[ \t]+coverage: ([0-9.]+)% of statements")
+
 	// ?       alipay  [no test files]
-	gtNoFilesRE = regexp.MustCompile("^\\?.*\\[no test files\\]$")
+	gtNoFilesRE = regexp.MustCompile("^\\?[ \t]+([^ \t]+)[ \t]+\\[no test files\\]$")
 	// FAIL    node/config [build failed]
-	gtBuildFailedRE = regexp.MustCompile(`^FAIL.*\[(build|setup) failed\]$`)
+	gtBuildFailedRE = regexp.MustCompile(
+		`^FAIL[ \t]+([^ \t]+)[ \t]+\[(build|setup) failed\]$`)
+	// # node/config [node/config.test]
+	gtBuildHeaderRE = regexp.MustCompile(`^# ([^ \t]+)`)
+	// go: downloading github.com/stretchr/testify v1.8.0
+	goToolRE = regexp.MustCompile(`^go: (downloading|extracting|finding|found|added|upgraded) `)
+
+	// Output mismatch of an example
+	//	got:
+	//	hello
+	//	want (unordered):
+	//	world
+	gtExampleDiffRE = regexp.MustCompile(`(?s)got:\n(.*?)\n?want( \(unordered\))?:\n(.*)`)
+
+	// fuzz: elapsed: 3s, execs: 1184 (395/sec), new interesting: 2 (total: 4)
+	// fuzz: minimizing 48-byte failing input file
+	gtFuzzProgressRE = regexp.MustCompile(`^fuzz: (elapsed|minimizing)`)
+	// Failing input written to testdata/fuzz/FuzzReverse/1de061fa29cfbb3d
+	gtFuzzInputRE = regexp.MustCompile(`Failing input written to ([^[:space:]]+)`)
+
+	// BenchmarkJoin-8   5000000   243.5 ns/op   56 B/op   2 allocs/op
+	// BenchmarkSizes/size=16-8   100   74.33 ns/op   215.26 MB/s   1.2e+08 items/op
+	gtBenchRE = regexp.MustCompile(
+		"^(Benchmark[^[:space:]]*?)(-\\d+)?[[:space:]]+(\\d+)[[:space:]]+" +
+			"([-+]?[0-9.]+([eE][-+]?[0-9]+)? [^[:space:]]+.*)$")
+	// --- FAIL: BenchmarkBroken
+	gtBenchEndRE = regexp.MustCompile("^--- (FAIL|SKIP): (Benchmark[^[:space:]]*)$")
+
+	// goos: linux
+	// BenchmarkJoin
+	gtBenchHeaderRE = regexp.MustCompile(
+		"^(goos|goarch|pkg|cpu): |^Benchmark[^[:space:]]*$")
+
+	// TestAdd (go test -list output)
+	gtListRE = regexp.MustCompile("^(Test|Benchmark|Example|Fuzz)[[:word:]]*$")
+
+	// -test.shuffle 1629838416298917000
+	gtShuffleRE = regexp.MustCompile(
+		"^(=== RUN[[:space:]]+)?-test\\.shuffle ([0-9]+)$")
 
 	// exit status - 0
 	gtExitRE = regexp.MustCompile("^exit status -?\\d+")
 
+	// panic: runtime error: index out of range [recovered]
+	// fatal error: all goroutines are asleep - deadlock!
+	gtPanicRE = regexp.MustCompile("(?m)^[[:space:]]*(panic|fatal error): |runtime error: ")
+
+	// panic: test timed out after 10m0s
+	gtTimeoutRE = regexp.MustCompile("(?m)^panic: test timed out after ")
+
+	//     docker_test.go:8: needs docker
+	// ./foo.go:12:2: undefined: Foo
+	gtLogLineRE = regexp.MustCompile("^[[:space:]]*[^[:space:]]+\\.go:[0-9]+(:[0-9]+)?: ?")
+
+	//     ui_test.go:12: ARTIFACT: name=screenshot path=/tmp/foo.png
+	gtArtifactRE = regexp.MustCompile(
+		"^[[:space:]]*([^[:space:]]+\\.go:[0-9]+(:[0-9]+)?: )?ARTIFACT: (.+)$")
+
+	//         	Error:      	Not equal:
+	testifyErrorRE = regexp.MustCompile("^[[:space:]]*Error:[[:space:]]+(.*)$")
+
 	// gocheck regular expressions
 
 	// START: mmath_test.go:16: MySuite.TestAdd
@@ -47,6 +123,9 @@ var (
 			"([A-Za-z_][[:word:]]*).([A-Za-z_][[:word:]]*)" +
 			"[[:space:]]?(-?[0-9]+.[0-9]+)?")
 
+	// SKIP: mmath_test.go:35: MySuite.TestMul (not implemented)
+	gcSkipReasonRE = regexp.MustCompile("\\(([^)]*)\\)[[:space:]]*$")
+
 	// FAIL	go2xunit/demo-gocheck	0.008s
 	// ok  	go2xunit/demo-gocheck	0.008s
 	// ok  	sisu.sh/go/code/catalog/transformer	(cached)
@@ -59,30 +138,60 @@ var (
 	gcTestErrorRE = regexp.MustCompile("(?s).*Error Trace:.*\\.go.*Error:.*Messages:.*")
 )
 
+// DefaultScannerBuffer is the default maximal line length of LineScanner
+const DefaultScannerBuffer = 1024 * 1024
+
 // LineScanner scans lines and keep track of line numbers
 type LineScanner struct {
 	*bufio.Reader
-	lnum int    // Current line number.
-	text []byte // Content of current line of text.
-	err  error  // Error from latest operation.
+	lnum   int    // Current line number.
+	text   []byte // Content of current line of text.
+	err    error  // Error from latest operation.
+	maxLen int    // Maximal line length.
 }
 
-// NewLineScanner creates a new line scanner from r
+// NewLineScanner creates a new line scanner from r, lines can be up to
+// Options.ScannerBuffer bytes long (DefaultScannerBuffer if not set)
 func NewLineScanner(r io.Reader) *LineScanner {
+	return NewLineScannerWithBuffer(r, Options.ScannerBuffer)
+}
+
+// NewLineScannerWithBuffer creates a new line scanner from r, lines can be up
+// to size bytes long (DefaultScannerBuffer if size <= 0)
+func NewLineScannerWithBuffer(r io.Reader, size int) *LineScanner {
+	if size <= 0 {
+		size = DefaultScannerBuffer
+	}
 	br := bufio.NewReader(r)
 	ls := &LineScanner{
 		Reader: br,
+		maxLen: size,
 	}
 	return ls
 }
 
 // Scan advances to next line.
 func (ls *LineScanner) Scan() bool {
-	if ls.text, _, ls.err = ls.Reader.ReadLine(); ls.err != nil {
-		if ls.err == io.EOF {
-			ls.err = nil
+	var chunk []byte
+	isPrefix := true
+	ls.text = nil
+	for isPrefix {
+		if chunk, isPrefix, ls.err = ls.Reader.ReadLine(); ls.err != nil {
+			if ls.err == io.EOF {
+				ls.err = nil
+			}
+			return false
+		}
+		if len(ls.text)+len(chunk) > ls.maxLen {
+			ls.err = fmt.Errorf("%d: %s (line longer than %d bytes)", ls.lnum+1, bufio.ErrTooLong, ls.maxLen)
+			return false
+		}
+		// ReadLine reuses its buffer, copy long lines
+		if isPrefix || ls.text != nil {
+			ls.text = append(ls.text, chunk...)
+		} else {
+			ls.text = chunk
 		}
-		return false
 	}
 	ls.lnum++
 	return true
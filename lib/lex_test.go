@@ -1,7 +1,9 @@
 package lib
 
 import (
+	"bufio"
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -24,3 +26,27 @@ func TestLineScannerLongLine(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestLineScannerWithBuffer(t *testing.T) {
+	line := `{"Output":"` + strings.Repeat("x", 100*1024) + `"}`
+	input := "first\n" + line + "\nlast\n"
+
+	ls := NewLineScannerWithBuffer(strings.NewReader(input), 64*1024)
+	for ls.Scan() {
+	}
+	if err := ls.Err(); err == nil || !strings.Contains(err.Error(), bufio.ErrTooLong.Error()) {
+		t.Fatalf("no ErrTooLong for long line (err=%v)", err)
+	}
+
+	var lines []string
+	ls = NewLineScannerWithBuffer(strings.NewReader(input), 200*1024)
+	for ls.Scan() {
+		lines = append(lines, ls.Text())
+	}
+	if err := ls.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 || lines[1] != line || ls.Line() != 3 {
+		t.Fatalf("bad lines (%d lines)", len(lines))
+	}
+}
@@ -1,8 +1,17 @@
 package lib
 
 import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func loadGotest(filename string, t *testing.T) ([]*Suite, error) {
@@ -24,6 +33,9 @@ func Test_parseBadOutput(t *testing.T) {
 */
 
 func Test_ignoreDatarace(t *testing.T) {
+	Options.FailOnRace = false
+	defer func() { Options.FailOnRace = true }()
+
 	filename := "../_data/in/gotest-datarace.out"
 	suites, err := loadGotest(filename, t)
 	if err != nil {
@@ -52,3 +64,864 @@ func Test_ignoreDatarace(t *testing.T) {
 		t.Fatalf("wrong number of failed %d, should be %d", suite.NumFailed(), numFailed)
 	}
 }
+
+func Test_errorsVsFailures(t *testing.T) {
+	cases := []struct {
+		filename string
+		test     string
+		status   Status
+	}{
+		{"../_data/in/gotest-fail.out", "TestSubFail", Failed},
+		{"../_data/in/gotest-panic-test.out", "TestIndex", Errored},
+		{"../_data/in/gotest-panic-abort.out", "TestWorker", Errored},
+	}
+
+	for _, tc := range cases {
+		suites, err := loadGotest(tc.filename, t)
+		if err != nil {
+			t.Fatalf("error loading %s - %s", tc.filename, err)
+		}
+
+		var found *Test
+		for _, test := range suites[0].Tests {
+			if test.Name == tc.test {
+				found = test
+			}
+		}
+		if found == nil {
+			t.Fatalf("%s: can't find %s", tc.filename, tc.test)
+		}
+		if found.Status != tc.status {
+			t.Errorf("%s: %s status is %v, should be %v", tc.filename, tc.test, found.Status, tc.status)
+		}
+		if tc.status == Errored && !strings.Contains(found.Message, "goroutine") {
+			t.Errorf("%s: stack trace missing from %q", tc.filename, found.Message)
+		}
+	}
+}
+
+func Test_benchmarks(t *testing.T) {
+	filename := "../_data/in/gotest-bench.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	tests := suites[0].Tests
+	if len(tests) != 3 {
+		t.Fatalf("got %d tests instead of 3", len(tests))
+	}
+	if tests[0].Message != "" {
+		t.Fatalf("benchmark header leaked into %s output: %q", tests[0].Name, tests[0].Message)
+	}
+
+	test := tests[2]
+	if test.Name != "BenchmarkJoin" {
+		t.Fatalf("bad benchmark name %q", test.Name)
+	}
+	bench := test.Benchmark
+	if bench == nil {
+		t.Fatalf("no benchmark result for %s", test.Name)
+	}
+	if bench.NsPerOp != 243.5 || bench.BytesPerOp != 56 || bench.AllocsPerOp != 2 {
+		t.Fatalf("bad benchmark metrics: %+v", bench)
+	}
+}
+
+func Test_failOnRace(t *testing.T) {
+	filename := "../_data/in/gotest-race-test.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	test := suites[0].Tests[0]
+	if test.Name != "TestCounter" || test.Status != Errored {
+		t.Fatalf("%s should be errored, got %v", test.Name, test.Status)
+	}
+	if !strings.Contains(test.Message, "Previous write at") {
+		t.Fatalf("race report missing from %q", test.Message)
+	}
+	if suites[0].NumPassed() != 1 {
+		t.Fatalf("TestOK should pass")
+	}
+
+	filename = "../_data/in/gotest-race-pkg.out"
+	suites, err = loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	tests := suites[0].Tests
+	if len(tests) != 2 {
+		t.Fatalf("got %d tests instead of 2", len(tests))
+	}
+	if tests[0].Status != Passed || tests[0].Message != "" {
+		t.Fatalf("package level race attached to %s", tests[0].Name)
+	}
+	if tests[1].Name != "DATA RACE" || tests[1].Status != Errored {
+		t.Fatalf("no synthetic race test, got %s (%v)", tests[1].Name, tests[1].Status)
+	}
+}
+
+func Test_coverProfile(t *testing.T) {
+	filename := "../_data/in/gotest-coverprofile.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	if len(suites) != 1 {
+		t.Fatalf("got %d suites instead of 1", len(suites))
+	}
+	suite := suites[0]
+	if len(suite.CoverProfile) != 4 {
+		t.Fatalf("got %d cover blocks instead of 4", len(suite.CoverProfile))
+	}
+	exp := CoverBlock{
+		File:      "example.com/mmath/mmath.go",
+		StartLine: 15,
+		StartCol:  24,
+		EndLine:   16,
+		EndCol:    12,
+		NumStmt:   1,
+		Count:     0,
+	}
+	if block := suite.CoverProfile[3]; block != exp {
+		t.Fatalf("bad cover block %+v, should be %+v", block, exp)
+	}
+	for _, test := range suite.Tests {
+		if test.Message != "" {
+			t.Fatalf("coverage profile leaked into %s output: %q", test.Name, test.Message)
+		}
+	}
+}
+
+func Test_coverage(t *testing.T) {
+	filename := "../_data/in/gotest-cover.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	suite := suites[0]
+	if !suite.HasCoverage {
+		t.Fatalf("no coverage found")
+	}
+	if math.Abs(suite.Coverage-0.786) > 1e-9 {
+		t.Fatalf("bad coverage %f, should be 0.786", suite.Coverage)
+	}
+	for _, test := range suite.Tests {
+		if test.Message != "" {
+			t.Fatalf("coverage leaked into %s output: %q", test.Name, test.Message)
+		}
+	}
+}
+
+func Test_coverageMulti(t *testing.T) {
+	filename := "../_data/in/gotest-cover-multi.out"
+	Options.ShowEmptyPackages = true
+	defer func() { Options.ShowEmptyPackages = false }()
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	expected := []struct {
+		name     string
+		coverage string // "" for no coverage
+	}{
+		{"example.com/mmath", "78.6"},
+		{"example.com/consts", ""},
+		{"example.com/notests", "0.0"},
+		{"example.com/parse", "50.0"},
+	}
+	if len(suites) != len(expected) {
+		t.Fatalf("got %d suites instead of %d", len(suites), len(expected))
+	}
+	for i, suite := range suites {
+		exp := expected[i]
+		if suite.Name != exp.name || suite.HasCoverage != (exp.coverage != "") {
+			t.Fatalf("bad suite %s (coverage: %v)", suite.Name, suite.HasCoverage)
+		}
+		coverage := ""
+		for _, prop := range suite.Properties {
+			if prop.Name == "coverage.statements" {
+				coverage = prop.Value
+			}
+		}
+		if coverage != exp.coverage {
+			t.Fatalf("%s: coverage.statements is %q, should be %q", suite.Name, coverage, exp.coverage)
+		}
+		for _, test := range suite.Tests {
+			if test.Message != "" {
+				t.Fatalf("coverage leaked into %s output: %q", test.Name, test.Message)
+			}
+		}
+	}
+}
+
+func Test_timeout(t *testing.T) {
+	filename := "../_data/in/gotest-timeout.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	tests := suites[0].Tests
+	if len(tests) != 3 {
+		t.Fatalf("got %d tests instead of 3", len(tests))
+	}
+	if tests[0].Status != Passed {
+		t.Fatalf("%s should pass", tests[0].Name)
+	}
+	for _, test := range tests[1:] {
+		if test.Status != Errored {
+			t.Fatalf("%s status is %v, should be errored", test.Name, test.Status)
+		}
+		if !strings.HasPrefix(test.Message, timeoutMessage) {
+			t.Fatalf("%s: bad message %q", test.Name, test.Message)
+		}
+		if !strings.Contains(test.Message, "running tests:") {
+			t.Fatalf("%s: running tests missing from %q", test.Name, test.Message)
+		}
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("read error")
+}
+
+func Test_parseAll(t *testing.T) {
+	var readers []io.Reader
+	for _, filename := range []string{"../_data/in/gotest-pass.out", "../_data/in/gotest-cover.out"} {
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatalf("can't open %s - %s", filename, err)
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+
+	suites, err := ParseAll(ParseGotest, "", readers...)
+	if err != nil {
+		t.Fatalf("can't parse - %s", err)
+	}
+	if len(suites) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(suites))
+	}
+
+	_, err = ParseAll(ParseGotest, "", errReader{}, strings.NewReader(""))
+	if _, ok := err.(MultiError); !ok {
+		t.Fatalf("expected MultiError, got %v", err)
+	}
+}
+
+func Test_buildFailed(t *testing.T) {
+	filename := "../_data/in/gotest-buildfailed.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	if len(suites) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(suites))
+	}
+	suite := suites[1]
+	if suite.Name != "node/config" || suite.NumErrors() != 1 {
+		t.Fatalf("bad build failed suite: %s (%d errors)", suite.Name, suite.NumErrors())
+	}
+
+	filename = "../_data/in/gotest-buildfailed-multi.out"
+	suites, err = loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	if len(suites) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(suites))
+	}
+	if test := suites[0].Tests[0]; test.Message != "" {
+		t.Fatalf("compiler output leaked into %s: %q", test.Name, test.Message)
+	}
+	test := suites[1].Tests[0]
+	if test.Name != "[build failed]" || test.Status != Errored {
+		t.Fatalf("bad build failed test: %s (%v)", test.Name, test.Status)
+	}
+	if !strings.Contains(test.Message, "undefined: Foo") {
+		t.Fatalf("compiler output missing from %q", test.Message)
+	}
+}
+
+func Test_noTestFiles(t *testing.T) {
+	filename := "../_data/in/gotest-nofiles.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	numSuites := len(suites)
+
+	Options.ShowEmptyPackages = true
+	defer func() { Options.ShowEmptyPackages = false }()
+	suites, err = loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	if len(suites) != numSuites+10 {
+		t.Fatalf("got %d suites instead of %d", len(suites), numSuites+10)
+	}
+	suite := suites[0]
+	if suite.Name != "alipay" || suite.Len() != 0 || len(suite.Properties) != 1 {
+		t.Fatalf("bad empty suite: %+v", suite)
+	}
+}
+
+func Test_truncated(t *testing.T) {
+	filename := "../_data/in/gotest-truncated.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	suite := suites[0]
+	if suite.Len() != 6 {
+		t.Fatalf("got %d tests instead of 6", suite.Len())
+	}
+	if suite.Status != "FAIL" {
+		t.Fatalf("truncated suite status is %q", suite.Status)
+	}
+	if n := Suites(suites).NumIncomplete(); n != 2 {
+		t.Fatalf("got %d incomplete tests instead of 2", n)
+	}
+	for _, test := range suite.Tests[4:] {
+		if test.Status != Errored || !strings.HasPrefix(test.Message, truncatedMessage) {
+			t.Fatalf("%s: bad status (%v) or message (%q)", test.Name, test.Status, test.Message)
+		}
+	}
+	if !strings.Contains(suite.Tests[3].Message, "2/3 != 0.666667") {
+		t.Fatalf("captured output lost: %q", suite.Tests[3].Message)
+	}
+}
+
+func Test_maxOutputBytes(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("=== RUN   TestLoud\n")
+	buf.WriteString("first line\n")
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&buf, "    loud_test.go:10: line %d\n", i)
+	}
+	buf.WriteString("last line\n")
+	buf.WriteString("--- FAIL: TestLoud (0.01s)\n")
+	buf.WriteString("FAIL\texample.com/loud\t0.012s\n")
+	size := buf.Len()
+
+	Options.MaxOutputBytes = 1000
+	defer func() { Options.MaxOutputBytes = 0 }()
+	suites, err := ParseGotest(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := suites[0].Tests[0]
+	if !test.OutputTruncated || len(test.Message) > 1100 {
+		t.Fatalf("output not truncated (%d bytes)", len(test.Message))
+	}
+	if !strings.HasPrefix(test.Message, "first line") || !strings.HasSuffix(test.Message, "last line") {
+		t.Fatalf("head or tail missing: %q", test.Message)
+	}
+
+	tokens := truncatedOutputRE.FindStringSubmatch(test.Message)
+	if tokens == nil {
+		t.Fatalf("no truncation marker in %q", test.Message)
+	}
+	dropped, _ := strconv.Atoi(tokens[1])
+	kept := len(test.Message) - len(tokens[0])
+	// size includes RUN/FAIL/summary lines which are not part of the output
+	if diff := size - (dropped + kept); diff < 0 || diff > 100 {
+		t.Fatalf("bad dropped count %d (kept %d of %d)", dropped, kept, size)
+	}
+}
+
+func Test_outputFor(t *testing.T) {
+	filename := "../_data/in/gotest-mixed-output.out"
+	outputSize := func(outputFor string) int {
+		Options.OutputFor = outputFor
+		defer func() { Options.OutputFor = "" }()
+
+		suites, err := loadGotest(filename, t)
+		if err != nil {
+			t.Fatalf("error loading %s - %s", filename, err)
+		}
+		size := 0
+		for _, test := range suites[0].Tests {
+			size += len(test.Message)
+			if test.Status == Failed && test.Message == "" {
+				t.Fatalf("%s: output of failed %s dropped", outputFor, test.Name)
+			}
+		}
+		return size
+	}
+
+	all, failSkip, fail := outputSize("all"), outputSize("fail+skip"), outputSize("fail")
+	if !(all > failSkip && failSkip > fail && fail > 0) {
+		t.Fatalf("bad output sizes: all=%d, fail+skip=%d, fail=%d", all, failSkip, fail)
+	}
+}
+
+func Test_skipReason(t *testing.T) {
+	filename := "../_data/in/gotest-skip.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	reasons := map[string]string{
+		"TestDocker":  "needs docker",
+		"TestSkipNow": "",
+		"TestHelper":  "short mode: skipping slow test",
+		"TestOK":      "",
+	}
+	for _, test := range suites[0].Tests {
+		if test.SkipReason != reasons[test.Name] {
+			t.Errorf("%s: bad skip reason - %q", test.Name, test.SkipReason)
+		}
+	}
+}
+
+func Test_gocheckSkipReason(t *testing.T) {
+	filename := "../_data/in/gocheck-panic.out"
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("can't open %s - %s", filename, err)
+	}
+	defer file.Close()
+
+	suites, err := ParseGocheck(file, "")
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	for _, test := range suites[0].Tests {
+		if test.Name == "TestMul" && test.SkipReason != "not implemented" {
+			t.Fatalf("bad skip reason - %q", test.SkipReason)
+		}
+	}
+}
+
+func Test_failureMessage(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	cases := []struct {
+		output   string
+		expected string
+	}{
+		{"starting\n    add_test.go:12: got 2, want 3\n    add_test.go:13: again", "got 2, want 3"},
+		{"    foo_test.go:12: \n        \tError Trace:\tfoo_test.go:12\n        \tError:      \tNot equal: \n        \t            \texpected: 1", "Not equal:"},
+		{"    foo_test.go:12: before\npanic: runtime error: index out of range [recovered]", "panic: runtime error: index out of range [recovered]"},
+		{"\n\nsomething went wrong\nmore", "something went wrong"},
+		{"", ""},
+		{"    foo_test.go:1: " + long, strings.Repeat("x", maxFailureMessage) + "..."},
+	}
+
+	for _, tc := range cases {
+		if message := failureMessage(tc.output); message != tc.expected {
+			t.Errorf("%q: got %q, expected %q", tc.output, message, tc.expected)
+		}
+	}
+
+	filename := "../_data/in/gotest-simple-and-suite-tests.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	for _, test := range suites[0].Tests {
+		if test.Status == Failed && !test.isParentTest && test.FailureMessage != "Should be true" {
+			t.Errorf("%s: bad failure message - %q", test.Name, test.FailureMessage)
+		}
+	}
+}
+
+func Test_ignoreBuildOutput(t *testing.T) {
+	filename := "../_data/in/gotest-go-tool.out"
+	goToolOutput := func() bool {
+		suites, err := loadGotest(filename, t)
+		if err != nil {
+			t.Fatalf("error loading %s - %s", filename, err)
+		}
+		if len(suites) != 1 || len(suites[0].Tests) != 2 {
+			t.Fatalf("bad suites: %v", suites)
+		}
+		for _, test := range suites[0].Tests {
+			if strings.Contains(test.Message, "go: ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !goToolOutput() {
+		t.Fatal("go tool output dropped without IgnoreBuildOutput")
+	}
+
+	Options.IgnoreBuildOutput = true
+	defer func() { Options.IgnoreBuildOutput = false }()
+	if goToolOutput() {
+		t.Fatal("go tool output not dropped")
+	}
+
+	// Non JSON lines in "go test -json" output
+	input := "go: downloading example.com/dep v1.0.0\n" +
+		`{"Time":"2020-05-01T12:00:00Z","Action":"run","Package":"example.com/a","Test":"TestA"}` + "\n" +
+		`{"Time":"2020-05-01T12:00:00Z","Action":"output","Package":"example.com/a","Test":"TestA","Output":"=== RUN   TestA\n"}` + "\n" +
+		"# example.com/a\n" +
+		`{"Time":"2020-05-01T12:00:00Z","Action":"output","Package":"example.com/a","Test":"TestA","Output":"--- PASS: TestA (0.00s)\n"}` + "\n" +
+		`{"Time":"2020-05-01T12:00:00Z","Action":"pass","Package":"example.com/a","Test":"TestA","Elapsed":0}` + "\n" +
+		"  warning: preamble\n" +
+		`{"Time":"2020-05-01T12:00:00Z","Action":"output","Package":"example.com/a","Output":"ok  \texample.com/a\t0.010s\n"}` + "\n" +
+		`{"Time":"2020-05-01T12:00:00Z","Action":"pass","Package":"example.com/a","Elapsed":0.01}` + "\n"
+	suites, err := ParseTest2JSON(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 1 || suites[0].Name != "example.com/a" || len(suites[0].Tests) != 1 || suites[0].Tests[0].Status != Passed {
+		t.Fatalf("bad suites: %v", suites)
+	}
+
+	Options.IgnoreBuildOutput = false
+	if _, err := ParseTest2JSON(strings.NewReader(input), ""); err == nil {
+		t.Fatal("no error on non JSON line")
+	}
+}
+
+func Test_shuffleSeed(t *testing.T) {
+	filename := "../_data/in/gotest-shuffle.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	if len(suites) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(suites))
+	}
+
+	seeds := []string{"1629838416298917000", "42"}
+	for i, suite := range suites {
+		if suite.ShuffleSeed != seeds[i] {
+			t.Fatalf("%s: shuffle seed %q, expected %q", suite.Name, suite.ShuffleSeed, seeds[i])
+		}
+		if len(suite.Properties) != 1 || suite.Properties[0] != (Property{ShuffleSeedProperty, seeds[i]}) {
+			t.Fatalf("%s: bad properties: %v", suite.Name, suite.Properties)
+		}
+	}
+	if n := len(suites[0].Tests); n != 2 {
+		t.Fatalf("got %d tests instead of 2", n)
+	}
+}
+
+func Test_maxFailures(t *testing.T) {
+	defer func() { Options.MaxFailures = -1 }()
+
+	input := `=== RUN   TestA
+--- FAIL: TestA (0.00s)
+=== RUN   TestB
+--- FAIL: TestB (0.00s)
+=== RUN   TestC
+--- PASS: TestC (0.00s)
+FAIL
+FAIL	example.com/pkg	0.010s
+`
+	cases := []struct {
+		max     int
+		tests   []string
+		stopped bool
+	}{
+		{0, []string{"TestA"}, true},
+		{1, []string{"TestA", "TestB"}, true},
+		{2, []string{"TestA", "TestB", "TestC"}, false},
+		{-1, []string{"TestA", "TestB", "TestC"}, false},
+	}
+	for _, tc := range cases {
+		Options.MaxFailures = tc.max
+		suites, err := ParseGotest(strings.NewReader(input), "")
+		if err != nil {
+			t.Fatalf("%d: %s", tc.max, err)
+		}
+		var names []string
+		for _, test := range suites[0].Tests {
+			names = append(names, test.Name)
+		}
+		if !reflect.DeepEqual(names, tc.tests) {
+			t.Fatalf("%d: tests %v, expected %v", tc.max, names, tc.tests)
+		}
+		if suites.StoppedEarly() != tc.stopped {
+			t.Fatalf("%d: stopped early is %v", tc.max, !tc.stopped)
+		}
+	}
+}
+
+func Test_maxFailuresSubtests(t *testing.T) {
+	defer func() { Options.MaxFailures = -1 }()
+
+	// Parents aren't counted, subtests of the last test are read
+	cases := []struct {
+		max      int
+		statuses map[string]Status
+		stopped  bool
+	}{
+		{0, map[string]Status{"TestSkip": Skipped, "TestSub": Failed, "TestSub/a": Passed, "TestSub/b": Failed}, true},
+		{1, map[string]Status{"TestSkip": Skipped, "TestSub": Failed, "TestSub/a": Passed, "TestSub/b": Failed, "TestLast": Failed}, true},
+		{2, map[string]Status{"TestSkip": Skipped, "TestSub": Failed, "TestSub/a": Passed, "TestSub/b": Failed, "TestLast": Failed}, false},
+	}
+	filename := "../_data/in/gotest-failfast-subtests.out"
+	for _, tc := range cases {
+		Options.MaxFailures = tc.max
+		parsed, err := loadGotest(filename, t)
+		if err != nil {
+			t.Fatalf("%d: %s", tc.max, err)
+		}
+		suites := Suites(parsed)
+		statuses := map[string]Status{}
+		for _, test := range suites[0].Tests {
+			statuses[test.Name] = test.Status
+		}
+		if !reflect.DeepEqual(statuses, tc.statuses) {
+			t.Fatalf("%d: statuses %v, expected %v", tc.max, statuses, tc.statuses)
+		}
+		if suites.StoppedEarly() != tc.stopped {
+			t.Fatalf("%d: stopped early is %v", tc.max, !tc.stopped)
+		}
+	}
+}
+
+func Test_maxFailuresTest2JSON(t *testing.T) {
+	Options.MaxFailures = 0
+	defer func() { Options.MaxFailures = -1 }()
+
+	file, err := os.Open("../_data/in/test2json-shuffle.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	suites, err := ParseTest2JSON(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !suites.StoppedEarly() {
+		t.Fatal("not stopped early")
+	}
+	// TestSub was running when TestSub/b failed
+	expected := map[string]Status{
+		"TestSkip":  Skipped,
+		"TestSub":   Errored,
+		"TestSub/a": Passed,
+		"TestSub/b": Failed,
+	}
+	for _, test := range suites[0].Tests {
+		if test.Status != expected[test.Name] {
+			t.Fatalf("%s: status %v, expected %v", test.Name, test.Status, expected[test.Name])
+		}
+		delete(expected, test.Name)
+	}
+	if len(expected) > 0 {
+		t.Fatalf("missing tests: %v", expected)
+	}
+	if !suites[0].Tests[1].Incomplete {
+		t.Fatalf("%s is not incomplete", suites[0].Tests[1].Name)
+	}
+	// The failed parent isn't counted
+	Options.MaxFailures = 1
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if suites, err = ParseTest2JSON(file, ""); err != nil {
+		t.Fatal(err)
+	}
+	if suites.StoppedEarly() {
+		t.Fatal("stopped early on a failed parent")
+	}
+}
+
+func Test_artifacts(t *testing.T) {
+	filename := "../_data/in/gotest-artifacts.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	expected := map[string][]string{
+		"TestScreenshot": {"/tmp/ui/screenshot.png", "/tmp/ui/trace.out"},
+		"TestPlain":      {"/tmp/ui/plain & simple.log"},
+	}
+	for _, test := range suites[0].Tests {
+		if !reflect.DeepEqual(test.Artifacts, expected[test.Name]) {
+			t.Fatalf("%s: artifacts %v, expected %v", test.Name, test.Artifacts, expected[test.Name])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, suites, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, prop := range []string{
+		`<property name="artifact.0" value="/tmp/ui/screenshot.png"/>`,
+		`<property name="artifact.1" value="/tmp/ui/trace.out"/>`,
+	} {
+		if !strings.Contains(buf.String(), prop) {
+			t.Fatalf("no %s in JUnit output:\n%s", prop, buf.String())
+		}
+	}
+
+	data, err := xml.Marshal(suites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prop := `<property name="artifact.1" value="/tmp/ui/trace.out"></property>`
+	if !bytes.Contains(data, []byte(prop)) {
+		t.Fatalf("no %s in marshaled XML:\n%s", prop, data)
+	}
+}
+
+func Test_shuffleSeedTest2JSON(t *testing.T) {
+	filename := "../_data/in/test2json-shuffle.out"
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	suites, err := ParseTest2JSON(file, "")
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	if len(suites) != 1 {
+		t.Fatalf("got %d suites instead of 1", len(suites))
+	}
+	suite := suites[0]
+	if suite.ShuffleSeed != "1234567890" {
+		t.Fatalf("shuffle seed %q, expected %q", suite.ShuffleSeed, "1234567890")
+	}
+	if len(suite.Properties) != 1 || suite.Properties[0] != (Property{ShuffleSeedProperty, "1234567890"}) {
+		t.Fatalf("bad properties: %v", suite.Properties)
+	}
+	if n := len(suite.Tests); n != 4 {
+		t.Fatalf("got %d tests instead of 4", n)
+	}
+}
+
+func Test_fuzz(t *testing.T) {
+	for _, name := range []string{"gotest-fuzz.out", "gotest-fuzz-corpus.out"} {
+		filename := "../_data/in/" + name
+		suites, err := loadGotest(filename, t)
+		if err != nil {
+			t.Fatalf("error loading %s - %s", filename, err)
+		}
+
+		var failed *Test
+		for _, test := range suites[0].Tests {
+			if test.Status == Failed && !test.isParentTest {
+				failed = test
+			}
+		}
+		if failed == nil {
+			t.Fatalf("%s: no failed fuzz test", name)
+		}
+		if !strings.Contains(failed.Message, `bad input "x000"`) {
+			t.Fatalf("%s: bad message for %s: %q", name, failed.Name, failed.Message)
+		}
+		input := Property{"fuzz.input", "testdata/fuzz/FuzzReverse/1de061fa29cfbb3d"}
+		if len(failed.Properties) != 1 || failed.Properties[0] != input {
+			t.Fatalf("%s: bad properties for %s: %v", name, failed.Name, failed.Properties)
+		}
+	}
+}
+
+func Test_subtestOutput(t *testing.T) {
+	out := `=== RUN   TestSub
+=== RUN   TestSub/a
+=== RUN   TestSub/b
+    sub_test.go:7: boom
+=== NAME  TestSub
+    sub_test.go:8: parent
+--- FAIL: TestSub (0.00s)
+    --- PASS: TestSub/a (0.00s)
+    --- FAIL: TestSub/b (0.00s)
+FAIL
+FAIL	example.com/sub	0.003s
+`
+	suites, err := ParseGotest(strings.NewReader(out), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := suites[0].Tests
+	if len(tests) != 3 {
+		t.Fatalf("got %d tests instead of 3", len(tests))
+	}
+	if msg := tests[2].Message; msg != "    sub_test.go:7: boom" {
+		t.Fatalf("bad %s message: %q", tests[2].Name, msg)
+	}
+}
+
+func Test_example(t *testing.T) {
+	filename := "../_data/in/gotest-example.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	tests := suites[0].Tests
+	if len(tests) != 4 {
+		t.Fatalf("got %d tests instead of 4", len(tests))
+	}
+	bad := tests[2]
+	if bad.Name != "ExampleBad" || bad.Status != Failed || bad.Kind() != ExampleKind {
+		t.Fatalf("bad example test: %+v", bad)
+	}
+	expected := `got "hello\nthere", want "hello\nworld"`
+	if bad.FailureMessage != expected {
+		t.Fatalf("failure message %q, expected %q", bad.FailureMessage, expected)
+	}
+
+	message := exampleFailureMessage("got:\nb\na\nwant (unordered):\na\nc\n")
+	if expected := `got "b\na", want (unordered) "a\nc"`; message != expected {
+		t.Fatalf("unordered failure message %q, expected %q", message, expected)
+	}
+}
+
+func Test_benchmarkMetrics(t *testing.T) {
+	filename := "../_data/in/gotest-bench-sub.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	suite := suites[0]
+	bench := findTest(suite, "BenchmarkSizes/size=16")
+	if bench == nil || bench.Benchmark == nil {
+		t.Fatalf("BenchmarkSizes/size=16 not found")
+	}
+	expected := map[string]float64{"ns/op": 74.33, "MB/s": 215.26, "frac/op": 0.000012, "items/op": 123456789}
+	if !reflect.DeepEqual(bench.Benchmark.Metrics, expected) {
+		t.Fatalf("bad metrics: %v", bench.Benchmark.Metrics)
+	}
+
+	if parent := findTest(suite, "BenchmarkSizes"); parent == nil || !parent.isParentTest {
+		t.Fatalf("BenchmarkSizes is not a parent: %+v", parent)
+	}
+	if parent := findTest(suite, "BenchmarkSubFail"); parent == nil || parent.Status != Failed {
+		t.Fatalf("BenchmarkSubFail should fail: %+v", parent)
+	}
+	broken := findTest(suite, "BenchmarkBroken")
+	if broken == nil || broken.Status != Failed || broken.Benchmark != nil {
+		t.Fatalf("BenchmarkBroken should fail with no metrics: %+v", broken)
+	}
+	if broken.FailureMessage != "cannot set up" {
+		t.Fatalf("bad failure message: %q", broken.FailureMessage)
+	}
+
+	metrics, err := parseBenchMetrics("1.5e+06 ns/op\t0.25 x/op")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics["ns/op"] != 1.5e6 || metrics["x/op"] != 0.25 {
+		t.Fatalf("bad metrics: %v", metrics)
+	}
+	if _, err := parseBenchMetrics("12 ns/op 3"); err == nil {
+		t.Fatalf("parsed odd number of fields")
+	}
+}
@@ -0,0 +1,51 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// notRunMessage is the failure message of tests in SkeletonFromList
+const notRunMessage = "test was not run"
+
+// ParseList parses "go test -list" output and returns the test names
+func ParseList(input io.Reader) ([]string, error) {
+	var names []string
+	scanner := NewLineScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case gtListRE.MatchString(line):
+			names = append(names, line)
+		case gtSuiteRE.MatchString(line), gtNoFilesRE.MatchString(line):
+			// ok  	example.com/pkg	0.003s
+			continue
+		default:
+			return nil, fmt.Errorf("%d: not a test name - %q", scanner.Line(), line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// SkeletonFromList returns a suite for package pkg with the tests called
+// names, all marked as not run (failed with NotRun set). Merge the skeleton
+// with test results to report tests that didn't run as failures.
+func SkeletonFromList(names []string, pkg string) *Suite {
+	suite := &Suite{Name: pkg, Time: "0"}
+	for _, name := range names {
+		suite.Tests = append(suite.Tests, &Test{
+			Name:           name,
+			Time:           "0",
+			Status:         Failed,
+			FailureMessage: notRunMessage,
+			NotRun:         true,
+		})
+	}
+	return suite
+}
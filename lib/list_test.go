@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	input := "TestAdd\nTestSub\nExampleAdd\nBenchmarkAdd\nok  \texample.com/calc\t0.003s\n"
+	names, err := ParseList(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"TestAdd", "TestSub", "ExampleAdd", "BenchmarkAdd"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("got %q, expected %q", names, expected)
+	}
+
+	if _, err := ParseList(strings.NewReader("TestAdd\n--- FAIL: TestAdd (0.00s)\n")); err == nil {
+		t.Fatal("no error on test output")
+	}
+}
+
+func TestSkeletonFromList(t *testing.T) {
+	skeleton := SkeletonFromList([]string{"TestAdd", "TestSub", "TestMul"}, "example.com/calc")
+	if skeleton.Name != "example.com/calc" || len(skeleton.Tests) != 3 {
+		t.Fatalf("bad skeleton: %+v", skeleton)
+	}
+	for _, test := range skeleton.Tests {
+		if !test.NotRun || test.Status != Failed {
+			t.Fatalf("%s: not marked as not run", test.Name)
+		}
+	}
+
+	results := Suites{{Name: "example.com/calc", Time: "0.01", Tests: []*Test{
+		{Name: "TestAdd", Status: Passed},
+		{Name: "TestSub", Status: Failed},
+	}}}
+	merged, err := Merge(Suites{skeleton}, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 || merged[0].Len() != 3 {
+		t.Fatalf("bad merge: %+v", merged)
+	}
+	stats := merged.Stats()
+	if stats["pass"] != 1 || stats["fail"] != 2 {
+		t.Fatalf("bad stats: %v", stats)
+	}
+	if test := merged[0].Tests[2]; test.Name != "TestMul" || !test.NotRun {
+		t.Fatalf("bad not run test: %+v", test)
+	}
+	if skeleton.Tests[0].Status != Failed {
+		t.Fatal("skeleton modified by Merge")
+	}
+}
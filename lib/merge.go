@@ -0,0 +1,219 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// How Merge handles a test that appears in both inputs with different
+// statuses
+const (
+	MergeError  = "error"  // Return an error
+	MergeSuffix = "suffix" // Keep both, the second one with a "#N" suffix
+	MergeWorst  = "worst"  // Keep the one with the worst status
+	MergeLatest = "latest" // Keep the one in the second input (e.g. a rerun)
+)
+
+// statusRank orders statuses from best to worst for MergeWorst
+var statusRank = map[Status]int{
+	Passed:        0,
+	Skipped:       1,
+	UnknownStatus: 2,
+	Failed:        3,
+	Errored:       4,
+}
+
+// Merge returns the union of suites in a and b. Suites with the same name are
+// merged to one suite. A test that appears in both with the same status is
+// kept once, if the status differ Merge returns an error. Tests that are
+// NotRun are replaced by results of the same test.
+// a and b are not modified.
+func Merge(a, b Suites) (Suites, error) {
+	return MergeWithPolicy(a, b, MergeError)
+}
+
+// MergeWithPolicy is Merge where tests with different statuses in a and b
+// are handled according to policy (one of the Merge* constants)
+func MergeWithPolicy(a, b Suites, policy string) (Suites, error) {
+	switch policy {
+	case MergeError, MergeSuffix, MergeWorst, MergeLatest:
+	default:
+		return nil, fmt.Errorf("unknown merge policy: %q", policy)
+	}
+
+	var merged Suites
+	byName := make(map[string]*Suite)
+
+	for _, suites := range []Suites{a, b} {
+		for _, suite := range suites {
+			dest, ok := byName[suite.Name]
+			if !ok {
+				dest = copySuite(suite)
+				byName[suite.Name] = dest
+				merged = append(merged, dest)
+				continue
+			}
+			if err := mergeSuite(dest, suite, policy); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// copySuite returns a copy of suite with its own Tests slice
+func copySuite(suite *Suite) *Suite {
+	dest := *suite
+	dest.Tests = make([]*Test, len(suite.Tests))
+	copy(dest.Tests, suite.Tests)
+	return &dest
+}
+
+// replaceTest replaces old with test in suite
+func replaceTest(suite *Suite, old, test *Test) {
+	for i, t := range suite.Tests {
+		if t == old {
+			suite.Tests[i] = test
+		}
+	}
+}
+
+// mergeSuite adds tests from src to dest, policy is how to handle tests with
+// different statuses
+func mergeSuite(dest, src *Suite, policy string) error {
+	tests := make(map[string]*Test)
+	for _, test := range dest.Tests {
+		tests[test.Name] = test
+	}
+
+	for _, test := range src.Tests {
+		prev, ok := tests[test.Name]
+		if !ok {
+			dest.Tests = append(dest.Tests, test)
+			tests[test.Name] = test
+			continue
+		}
+		if test.NotRun {
+			continue
+		}
+		if prev.NotRun {
+			// Results replace the SkeletonFromList test
+			replaceTest(dest, prev, test)
+			tests[test.Name] = test
+			continue
+		}
+		if policy == MergeLatest {
+			replaceTest(dest, prev, test)
+			tests[test.Name] = test
+			continue
+		}
+		if prev.Status == test.Status {
+			continue
+		}
+
+		switch policy {
+		case MergeWorst:
+			if statusRank[test.Status] > statusRank[prev.Status] {
+				replaceTest(dest, prev, test)
+				tests[test.Name] = test
+			}
+		case MergeSuffix:
+			renamed := *test
+			for n := 2; tests[renamed.Name] != nil; n++ {
+				renamed.Name = fmt.Sprintf("%s#%d", test.Name, n)
+			}
+			dest.Tests = append(dest.Tests, &renamed)
+			tests[renamed.Name] = &renamed
+		default:
+			return fmt.Errorf("%s/%s: status mismatch", src.Name, test.Name)
+		}
+	}
+
+	mergeSuiteInfo(dest, src)
+	return nil
+}
+
+// mergeSuiteInfo merges suite level information (time, coverage and status)
+// from src to dest
+func mergeSuiteInfo(dest, src *Suite) {
+	// Shards run in parallel, suite time is the longest one
+	destTime, _ := strconv.ParseFloat(dest.Time, 64)
+	srcTime, err := strconv.ParseFloat(src.Time, 64)
+	if err == nil && srcTime > destTime {
+		dest.Time = src.Time
+	}
+	if !dest.HasCoverage && src.HasCoverage {
+		dest.Coverage, dest.HasCoverage = src.Coverage, true
+	}
+	if src.Status == "FAIL" {
+		dest.Status = src.Status
+	}
+}
+
+// MergeShards returns the union of suites from shards. Suites with the same
+// name are merged to one suite. Tests that appear in more than one shard are
+// kept with a "#shardN" suffix (N is the shard number, starting at 1) and a
+// warning is returned for each of them. shards are not modified.
+func MergeShards(shards []Suites) (Suites, []string) {
+	// shard numbers of every test
+	found := make(map[testKey][]int)
+	var keys []testKey
+	for i, shard := range shards {
+		for _, suite := range shard {
+			for _, test := range suite.Tests {
+				key := testKey{suite.Name, test.Name}
+				nums := found[key]
+				if len(nums) == 0 {
+					keys = append(keys, key)
+				}
+				if len(nums) == 0 || nums[len(nums)-1] != i+1 {
+					found[key] = append(nums, i+1)
+				}
+			}
+		}
+	}
+
+	var merged Suites
+	byName := make(map[string]*Suite)
+	for i, shard := range shards {
+		for _, suite := range shard {
+			dest, ok := byName[suite.Name]
+			if !ok {
+				dest = copySuite(suite)
+				dest.Tests = nil
+				byName[suite.Name] = dest
+				merged = append(merged, dest)
+			} else {
+				mergeSuiteInfo(dest, suite)
+			}
+
+			for _, test := range suite.Tests {
+				if len(found[testKey{suite.Name, test.Name}]) > 1 {
+					renamed := *test
+					renamed.Name = fmt.Sprintf("%s#shard%d", test.Name, i+1)
+					test = &renamed
+				}
+				dest.Tests = append(dest.Tests, test)
+			}
+		}
+	}
+
+	var warnings []string
+	for _, key := range keys {
+		if nums := found[key]; len(nums) > 1 {
+			warnings = append(warnings, fmt.Sprintf("%s/%s: found in shards %s", key.suite, key.test, joinInts(nums)))
+		}
+	}
+	return merged, warnings
+}
+
+// joinInts returns nums as a comma separated string
+func joinInts(nums []int) string {
+	strs := make([]string, len(nums))
+	for i, n := range nums {
+		strs[i] = strconv.Itoa(n)
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,171 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	a := Suites{
+		{Name: "shared", Time: "0.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+			{Name: "TestB", Status: Failed},
+		}},
+		{Name: "onlyA", Tests: []*Test{{Name: "TestA", Status: Passed}}},
+	}
+	b := Suites{
+		{Name: "shared", Time: "1.5", Tests: []*Test{
+			{Name: "TestB", Status: Failed},
+			{Name: "TestC", Status: Skipped},
+		}},
+		{Name: "onlyB", Tests: []*Test{{Name: "TestA", Status: Passed}}},
+	}
+
+	merged, err := Merge(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 3 {
+		t.Fatalf("got %d suites instead of 3", len(merged))
+	}
+	shared := merged[0]
+	if shared.Len() != 3 || shared.NumFailed() != 1 || shared.NumSkipped() != 1 {
+		t.Fatalf("bad shared suite: %d tests, %d failed, %d skipped",
+			shared.Len(), shared.NumFailed(), shared.NumSkipped())
+	}
+	if shared.Time != "1.5" {
+		t.Fatalf("bad shared suite time %q", shared.Time)
+	}
+	if len(a[0].Tests) != 2 {
+		t.Fatalf("Merge modified its input")
+	}
+
+	b[0].Tests[0].Status = Passed
+	if _, err := Merge(a, b); err == nil {
+		t.Fatalf("no error on status mismatch")
+	}
+}
+
+func TestMergeShards(t *testing.T) {
+	shard1 := Suites{
+		{Name: "pkg", Time: "1.0", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+			{Name: "TestDup", Status: Passed},
+		}},
+	}
+	shard2 := Suites{
+		{Name: "pkg", Time: "2.0", Tests: []*Test{
+			{Name: "TestB", Status: Failed},
+			{Name: "TestDup", Status: Failed},
+		}},
+		{Name: "other", Tests: []*Test{{Name: "TestA", Status: Passed}}},
+	}
+
+	merged, warnings := MergeShards([]Suites{shard1, shard2})
+	if len(merged) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(merged))
+	}
+	pkg := merged[0]
+	var names []string
+	for _, test := range pkg.Tests {
+		names = append(names, test.Name)
+	}
+	expected := []string{"TestA", "TestDup#shard1", "TestB", "TestDup#shard2"}
+	if len(names) != len(expected) {
+		t.Fatalf("bad tests: %v", names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Fatalf("bad tests: %v", names)
+		}
+	}
+	if pkg.Time != "2.0" {
+		t.Fatalf("bad suite time %q", pkg.Time)
+	}
+	if len(warnings) != 1 || warnings[0] != "pkg/TestDup: found in shards 1, 2" {
+		t.Fatalf("bad warnings: %v", warnings)
+	}
+	if shard1[0].Tests[1].Name != "TestDup" || len(shard1[0].Tests) != 2 {
+		t.Fatal("MergeShards modified its input")
+	}
+}
+
+func TestMergeWithPolicy(t *testing.T) {
+	newSuites := func() (Suites, Suites) {
+		a := Suites{
+			{Name: "pkg/a", Time: "1.0", Tests: []*Test{
+				{Name: "TestA", Status: Passed},
+				{Name: "TestB", Status: Failed},
+			}},
+		}
+		b := Suites{
+			{Name: "pkg/a", Time: "2.0", Tests: []*Test{
+				{Name: "TestA", Status: Errored},
+				{Name: "TestB", Status: Failed},
+				{Name: "TestC", Status: Passed},
+			}},
+			{Name: "pkg/b", Tests: []*Test{
+				{Name: "TestD", Status: Skipped},
+			}},
+		}
+		return a, b
+	}
+
+	// Disjoint
+	a, b := newSuites()
+	merged, err := MergeWithPolicy(a, b[1:], MergeError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 || merged[0].Len() != 2 || merged[1].Len() != 1 {
+		t.Fatalf("bad disjoint merge: %v", merged)
+	}
+
+	// Conflicting
+	a, b = newSuites()
+	if _, err := MergeWithPolicy(a, b, MergeError); err == nil {
+		t.Fatal("no error on status mismatch")
+	}
+
+	a, b = newSuites()
+	merged, err = MergeWithPolicy(a, b, MergeWorst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := merged.Stats()
+	if merged[0].Len() != 3 || stats["error"] != 1 || stats["pass"] != 1 || stats["fail"] != 1 {
+		t.Fatalf("bad worst merge: %v", stats)
+	}
+	if merged[0].Time != "2.0" {
+		t.Fatalf("suite time is %s, should be 2.0", merged[0].Time)
+	}
+
+	a, b = newSuites()
+	merged, err = MergeWithPolicy(a, b, MergeSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, test := range merged[0].Tests {
+		names = append(names, test.Name)
+	}
+	if strings.Join(names, ",") != "TestA,TestB,TestA#2,TestC" {
+		t.Fatalf("bad suffix merge: %v", names)
+	}
+	if b[0].Tests[0].Name != "TestA" || a[0].Len() != 2 || b[0].Len() != 3 {
+		t.Fatal("inputs modified by merge")
+	}
+
+	a, b = newSuites()
+	merged, err = MergeWithPolicy(a, b, MergeLatest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged[0].Len() != 3 || merged[0].Tests[0] != b[0].Tests[0] || merged[0].Tests[1] != b[0].Tests[1] {
+		t.Fatalf("bad latest merge: %v", merged[0].Tests)
+	}
+
+	if _, err := MergeWithPolicy(a, b, "nope"); err == nil {
+		t.Fatal("no error on unknown policy")
+	}
+}
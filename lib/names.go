@@ -0,0 +1,121 @@
+package lib
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxNameLength is the default maximal length of sanitized names
+	DefaultMaxNameLength = 200
+	// MinMaxNameLength is the minimal maximal length of sanitized names, long
+	// enough for some of the name and the hash suffix
+	MinMaxNameLength = 16
+	// OriginalNameProperty is the property with the name of a test or suite
+	// whose name was changed by SanitizeNames
+	OriginalNameProperty = "name.original"
+
+	// nameHashLen is the number of hex digits of the hash of truncated names
+	nameHashLen = 8
+)
+
+// SanitizeNames changes suite and test names in s that are not safe for XML
+// consumers: characters that are invalid in XML (or render badly, e.g.
+// control characters) are replaced with "_", names longer than maxLen bytes
+// are truncated with a hash suffix of the full name, and names that are the
+// same as a sibling's after that get a "~2", "~3" ... suffix (names that
+// don't change are kept, the suffix goes to the changed ones). The original
+// name of a changed suite or test is kept in an OriginalNameProperty
+// property. maxLen <= 0 means no limit.
+func (s Suites) SanitizeNames(maxLen int) {
+	suiteNames := make([]string, len(s))
+	for i, suite := range s {
+		suiteNames[i] = suite.Name
+	}
+	for i, name := range sanitizeNames(suiteNames, maxLen) {
+		suite := s[i]
+		if name != suite.Name {
+			suite.Properties = append(suite.Properties, Property{OriginalNameProperty, suite.Name})
+			suite.Name = name
+		}
+
+		testNames := make([]string, len(suite.Tests))
+		for j, test := range suite.Tests {
+			testNames[j] = test.Name
+		}
+		for j, name := range sanitizeNames(testNames, maxLen) {
+			test := suite.Tests[j]
+			if name != test.Name {
+				test.Properties = append(test.Properties, Property{OriginalNameProperty, test.Name})
+				test.Name = name
+			}
+		}
+	}
+}
+
+// sanitizeNames returns names sanitized and unique, names that are valid and
+// not duplicates are reserved before changed names get "~N" suffixes
+func sanitizeNames(names []string, maxLen int) []string {
+	out := make([]string, len(names))
+	done := make([]bool, len(names))
+	seen := make(map[string]bool)
+	for i, name := range names {
+		if !seen[name] && sanitizeName(name, maxLen) == name {
+			out[i], done[i] = name, true
+			seen[name] = true
+		}
+	}
+	for i, name := range names {
+		if !done[i] {
+			out[i] = uniqueName(sanitizeName(name, maxLen), seen, maxLen)
+		}
+	}
+	return out
+}
+
+// sanitizeName returns name with invalid characters replaced and truncated
+// to maxLen bytes
+func sanitizeName(name string, maxLen int) string {
+	clean := strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || unicode.IsControl(r) || r == '\uFFFE' || r == '\uFFFF' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if maxLen <= 0 || len(clean) <= maxLen {
+		return clean
+	}
+	suffix := fmt.Sprintf("~%x", sha1.Sum([]byte(name)))[:nameHashLen+1]
+	return truncateName(clean, maxLen, suffix)
+}
+
+// truncateName returns name with suffix, name is truncated so the result is
+// at most maxLen bytes (maxLen <= 0 means no limit)
+func truncateName(name string, maxLen int, suffix string) string {
+	if maxLen <= 0 || len(name)+len(suffix) <= maxLen {
+		return name + suffix
+	}
+	cut := maxLen - len(suffix)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut] + suffix
+}
+
+// uniqueName returns name, or name with a "~N" suffix if it's in seen, and
+// adds it to seen. The suffix is counted in maxLen.
+func uniqueName(name string, seen map[string]bool, maxLen int) string {
+	unique := name
+	for n := 2; seen[unique]; n++ {
+		unique = truncateName(name, maxLen, fmt.Sprintf("~%d", n))
+	}
+	seen[unique] = true
+	return unique
+}
@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeNames(t *testing.T) {
+	long := "TestTable/" + strings.Repeat("x", 40)
+	suites := Suites{
+		{Name: "example.com/a", Tests: []*Test{
+			{Name: "TestTable/a\"<b>"},
+			{Name: "TestTable/tab\there"},
+			{Name: "TestTable/tab_here"},
+			{Name: "TestTable/bad\xffutf8"},
+			{Name: long + "1"},
+			{Name: long + "2"},
+			{Name: "TestTable/é" + strings.Repeat("é", 20)},
+		}},
+		{Name: "example.com/a"},
+	}
+	suites.SanitizeNames(32)
+
+	expected := []string{
+		"TestTable/a\"<b>",     // escaped by the XML writer
+		"TestTable/tab_here~2", // "TestTable/tab_here" is kept
+		"TestTable/tab_here",
+		"TestTable/bad_utf8",
+	}
+	for i, name := range expected {
+		if test := suites[0].Tests[i]; test.Name != name {
+			t.Errorf("%d: got %q, expected %q", i, test.Name, name)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, test := range suites[0].Tests {
+		if len(test.Name) > 32 {
+			t.Errorf("%q: longer than 32", test.Name)
+		}
+		if names[test.Name] {
+			t.Errorf("%q: not unique", test.Name)
+		}
+		names[test.Name] = true
+	}
+	truncated := suites[0].Tests[4]
+	if !strings.HasPrefix(truncated.Name, "TestTable/xxx") || truncated.Name == suites[0].Tests[5].Name {
+		t.Errorf("bad truncated names: %q, %q", truncated.Name, suites[0].Tests[5].Name)
+	}
+	if len(truncated.Properties) != 1 || truncated.Properties[0] != (Property{OriginalNameProperty, long + "1"}) {
+		t.Errorf("original name not kept: %v", truncated.Properties)
+	}
+	if len(suites[0].Tests[0].Properties) != 0 {
+		t.Errorf("property of unchanged name: %v", suites[0].Tests[0].Properties)
+	}
+
+	if suites[1].Name != "example.com/a~2" || suites[1].Properties[0].Value != "example.com/a" {
+		t.Errorf("bad duplicate suite: %+v", suites[1])
+	}
+
+	// Suffixes of duplicates are counted in the maximal length
+	full := strings.Repeat("y", 32)
+	suites = Suites{{Tests: []*Test{{Name: full}, {Name: full}, {Name: full}}}}
+	suites.SanitizeNames(32)
+	for i, exp := range []string{full, full[:30] + "~2", full[:30] + "~3"} {
+		if name := suites[0].Tests[i].Name; name != exp {
+			t.Errorf("%d: got %q, expected %q", i, name, exp)
+		}
+	}
+
+	test := &Test{Name: long}
+	Suites{{Tests: []*Test{test}}}.SanitizeNames(0)
+	if test.Name != long {
+		t.Errorf("truncated without limit: %q", test.Name)
+	}
+}
@@ -1,7 +1,70 @@
 package lib
 
+import "time"
+
 // Options is library options
 var Options struct {
-	// FailOnRace will mark test a failed if there is a race
+	// FailOnRace will mark test as errored if there is a race (the default)
 	FailOnRace bool
+	// ShowEmptyPackages will report packages without test files as empty
+	// suites
+	ShowEmptyPackages bool
+	// MaxOutputBytes limits the captured output of each test, 0 means no limit
+	MaxOutputBytes int
+	// OutputFor is which tests keep their output: "fail", "fail+skip" or
+	// "all" (the default)
+	OutputFor string
+	// ScannerBuffer is the maximal input line length, 0 means
+	// DefaultScannerBuffer
+	ScannerBuffer int
+	// IgnoreBuildOutput drops output of the go tool (e.g. "go: downloading
+	// ...") mixed with test output, and lines that are not JSON in "go test
+	// -json" output
+	IgnoreBuildOutput bool
+	// EscapeOutput will XML escape test output instead of putting it in a
+	// CDATA section
+	EscapeOutput bool
+	// DurationFormat is the format of times in the XML output (see
+	// FormatDuration), empty means seconds
+	DurationFormat string
+	// Location is the time zone of timestamps in the output (see
+	// FormatTimestamp), nil means UTC
+	Location *time.Location
+	// TimePrecision is the number of digits after the decimal point of times
+	// in seconds (see FormatSeconds)
+	TimePrecision int
+	// Warnf, if set, is called with problems in the input that don't stop
+	// parsing or writing (e.g. invalid test times)
+	Warnf func(format string, args ...interface{})
+	// XMLNamespace, if set, is the default namespace of the XML output
+	XMLNamespace string
+	// Properties are added to the root element of XMLMultiTemplate
+	Properties []Property
+	// TotalTime is how the run time of all suites in XMLMultiTemplate is
+	// computed: "sum" (the default) of suite times, or "wall" clock time
+	// from the earliest suite start to the latest suite finish (only if all
+	// suites have Started and Finished, see TotalTimeFormats)
+	TotalTime string
+	// PackageName is the suite name of output without a package summary
+	// line (e.g. of a standalone test binary)
+	PackageName string
+	// Progress, if set, counts tests and packages as they are parsed
+	Progress *Progress
+	// OnTestEnd, if set, is called with every test when its result is parsed,
+	// before the rest of the input. The test may still change (e.g. get more
+	// output) until parsing ends.
+	OnTestEnd func(*Test)
+	// OnSuiteEnd, if set, is called with every suite when its package summary
+	// line is parsed. "go test -json" packages are parsed when they end.
+	OnSuiteEnd func(*Suite)
+	// MaxFailures stops parsing once more than MaxFailures tests failed, the
+	// tests read so far are reported with a StoppedEarlyProperty. Negative
+	// (the default) means no limit.
+	MaxFailures int
+}
+
+func init() {
+	Options.FailOnRace = true
+	Options.TimePrecision = DefaultTimePrecision
+	Options.MaxFailures = -1
 }
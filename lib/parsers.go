@@ -1,32 +1,204 @@
-// Package lib is exposing parsers and output generation
+// Package lib is exposing parsers and output generation, the go2xunit command
+// is a thin command line interface over it.
+//
+// Parse "go test -v" output with ParseGotest (or gocheck output with
+// ParseGocheck) and write a JUnit XML report with WriteJUnit:
+//
+//	suites, err := lib.ParseGotest(os.Stdin, "")
+//	if err != nil {
+//		return err
+//	}
+//	return lib.WriteJUnit(os.Stdout, suites, time.Now())
+//
+// Every package is a Suite (Suite.Name is the package path) with its Tests.
+// Subtests are in the same suite, named "Parent/child" after their parent
+// test. Test.Status, Test.Elapsed and Test.Message (the test output) are the
+// test results. Global options (e.g. Options.FailOnRace) are in Options.
 package lib
 
 import (
 	"fmt"
 	"io"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
-	matchDatarace = regexp.MustCompile("^WARNING: DATA RACE$").MatchString
+	hasDatarace = regexp.MustCompile("(?m)^WARNING: DATA RACE$").MatchString
+	isPanic     = gtPanicRE.MatchString
+	isTimeout   = gtTimeoutRE.MatchString
+
+	truncatedOutputRE = regexp.MustCompile(`\n\.\.\. \[(\d+) bytes truncated\] \.\.\.\n`)
 )
 
-// hasDatarace checks if there's a data race warning in the line
-func hasDatarace(lines []string) bool {
-	for _, line := range lines {
-		if matchDatarace(line) {
+const (
+	timeoutMessage   = "test timed out (no result recorded)"
+	truncatedMessage = "no result recorded (input truncated?)"
+
+	truncatedOutputFmt = "\n... [%d bytes truncated] ...\n"
+
+	// maxFailureMessage is the maximal length (in runes) of
+	// Test.FailureMessage
+	maxFailureMessage = 200
+)
+
+// StoppedEarlyProperty is the suite property set when parsing stopped after
+// Options.MaxFailures failures
+const StoppedEarlyProperty = "stopped.early"
+
+// tooManyFailures returns true if failures is more than maxFailed (negative
+// means no limit)
+func tooManyFailures(failures, maxFailed int) bool {
+	return maxFailed >= 0 && failures > maxFailed
+}
+
+// markStoppedEarly adds a StoppedEarlyProperty to suites that don't have it
+func markStoppedEarly(suites []*Suite) {
+	for _, suite := range suites {
+		if !hasProperty(suite.Properties, StoppedEarlyProperty) {
+			suite.Properties = append(suite.Properties, Property{StoppedEarlyProperty, "true"})
+		}
+	}
+}
+
+// hasProperty returns true if props has a property called name
+func hasProperty(props []Property, name string) bool {
+	for _, prop := range props {
+		if prop.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ShuffleSeedProperty is the suite property with the "go test -shuffle" seed,
+// named after the test binary flag
+const ShuffleSeedProperty = "test.shuffle"
+
+// hasTest returns true if test is in suite
+func hasTest(suite *Suite, test *Test) bool {
+	for _, t := range suite.Tests {
+		if t == test {
 			return true
 		}
 	}
 	return false
 }
 
+// hasSubtests returns true if suite has subtests of the test called name
+func hasSubtests(suite *Suite, name string) bool {
+	for _, t := range suite.Tests {
+		if strings.HasPrefix(t.Name, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// subtestsEnded returns true if all tests in subTests have a result
+func subtestsEnded(subTests map[string]*Test) bool {
+	for _, test := range subTests {
+		if test.Status == UnknownStatus {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseFunc is a parser function (e.g. ParseGotest)
+type ParseFunc func(rd io.Reader, suitePrefix string) (Suites, error)
+
+// parseSettings are the settings of a parse that are not in Options, so
+// ParseContext can pass them to the parsers explicitly
+type parseSettings struct {
+	// progress counts the parsed tests and packages (nil for none)
+	progress *Progress
+	// maxFailures stops parsing once more than maxFailures tests failed
+	// (negative means no limit)
+	maxFailures int
+}
+
+// defaultParseSettings returns the settings of a parse by the ParseFunc
+// parsers (e.g. ParseGotest), which are in Options
+func defaultParseSettings() parseSettings {
+	return parseSettings{progress: Options.Progress, maxFailures: Options.MaxFailures}
+}
+
+// settingsParseFunc is a parser with explicit parse settings
+type settingsParseFunc func(rd io.Reader, suitePrefix string, settings parseSettings) (Suites, error)
+
+// parseWith parses rd with parse and settings. Parsers that are not of this
+// package (e.g. wrappers) don't get settings, they parse with Options.
+func parseWith(parse ParseFunc, rd io.Reader, suitePrefix string, settings parseSettings) (Suites, error) {
+	parsers := map[uintptr]settingsParseFunc{
+		reflect.ValueOf(ParseGotest).Pointer():    parseGotest,
+		reflect.ValueOf(ParseGocheck).Pointer():   parseGocheck,
+		reflect.ValueOf(ParseTest2JSON).Pointer(): parseTest2JSON,
+	}
+	if withSettings, ok := parsers[reflect.ValueOf(parse).Pointer()]; ok {
+		return withSettings(rd, suitePrefix, settings)
+	}
+	return parse(rd, suitePrefix)
+}
+
+// MultiError is a list of errors
+type MultiError []error
+
+func (me MultiError) Error() string {
+	msgs := make([]string, len(me))
+	for i, err := range me {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ParseAll parses all readers concurrently with parse and returns the suites
+// from all of them (in readers order) merged with Merge. Errors from all
+// readers are returned as a MultiError.
+func ParseAll(parse ParseFunc, suitePrefix string, readers ...io.Reader) (Suites, error) {
+	results := make([]Suites, len(readers))
+	errs := make([]error, len(readers))
+	var wg sync.WaitGroup
+	for i, rd := range readers {
+		wg.Add(1)
+		go func(i int, rd io.Reader) {
+			defer wg.Done()
+			results[i], errs[i] = parse(rd, suitePrefix)
+		}(i, rd)
+	}
+	wg.Wait()
+
+	var merr MultiError
+	for i, err := range errs {
+		if err != nil {
+			merr = append(merr, fmt.Errorf("input %d: %s", i+1, err))
+		}
+	}
+	if len(merr) > 0 {
+		return nil, merr
+	}
+
+	var suites Suites
+	for _, result := range results {
+		var err error
+		if suites, err = Merge(suites, result); err != nil {
+			return nil, err
+		}
+	}
+	return suites, nil
+}
+
 // Token2Status return matching status for token
 func Token2Status(token string) Status {
 	switch token {
-	case "FAIL", "PANIC":
+	case "FAIL":
 		return Failed
+	case "PANIC":
+		return Errored
 	case "PASS":
 		return Passed
 	case "SKIP", "MISS":
@@ -35,6 +207,378 @@ func Token2Status(token string) Status {
 	return UnknownStatus
 }
 
+// markParentTests marks tests with subtests as parent tests, at every nesting
+// level (e.g. TestA/b is the parent of TestA/b/c)
+func markParentTests(suites []*Suite) {
+	for _, suite := range suites {
+		parents := make(map[string]bool)
+		for _, test := range suite.Tests {
+			names := namePrefixes(test.Name)
+			for _, name := range names[:len(names)-1] {
+				parents[name] = true
+			}
+		}
+		for _, test := range suite.Tests {
+			if parents[test.Name] {
+				test.isParentTest = true
+			}
+		}
+	}
+}
+
+// markErrors changes the status of failed tests whose output shows a panic
+// or a runtime crash to Errored. If Options.FailOnRace is set, tests whose
+// output has a data race report are marked as Errored as well.
+func markErrors(suites []*Suite) {
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.Status == Failed && isPanic(test.Message) {
+				test.Status = Errored
+			}
+			if Options.FailOnRace && !test.isParentTest && hasDatarace(test.Message) {
+				test.Status = Errored
+			}
+		}
+	}
+}
+
+// failureMessage returns a one line summary of failed test output. This is
+// the panic line for panics, the testify error line for testify assertions or
+// the first "file_test.go:NN: ..." line. Otherwise it's the first line with
+// text other than a "file_test.go:NN:" prefix.
+func failureMessage(output string) string {
+	lines := strings.Split(output, "\n")
+	var message, logLine string
+	for _, line := range lines {
+		switch {
+		case gtPanicRE.MatchString(line):
+			message = strings.TrimSpace(line)
+		case testifyErrorRE.MatchString(line):
+			message = strings.TrimSpace(testifyErrorRE.FindStringSubmatch(line)[1])
+		case logLine == "" && gtLogLineRE.MatchString(line):
+			loc := gtLogLineRE.FindStringIndex(line)
+			logLine = strings.TrimSpace(line[loc[1]:])
+		}
+		if message != "" {
+			break
+		}
+	}
+
+	if message == "" {
+		message = logLine
+	}
+	if message == "" {
+		for _, line := range lines {
+			line = strings.TrimSpace(gtLogLineRE.ReplaceAllString(line, ""))
+			if line != "" {
+				message = line
+				break
+			}
+		}
+	}
+
+	if runes := []rune(message); len(runes) > maxFailureMessage {
+		message = string(runes[:maxFailureMessage]) + "..."
+	}
+	return message
+}
+
+// setFailureMessages sets FailureMessage of failed and errored tests
+func setFailureMessages(suites []*Suite) {
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.Status != Failed && test.Status != Errored {
+				continue
+			}
+			if test.Kind() == ExampleKind {
+				if message := exampleFailureMessage(test.Message); message != "" {
+					test.FailureMessage = message
+					continue
+				}
+			}
+			test.FailureMessage = failureMessage(test.Message)
+		}
+	}
+}
+
+// exampleFailureMessage returns the got/want output mismatch of a failed
+// example as one line, or "" if output has no mismatch
+func exampleFailureMessage(output string) string {
+	tokens := gtExampleDiffRE.FindStringSubmatch(output)
+	if tokens == nil {
+		return ""
+	}
+	want := "want"
+	if tokens[2] != "" {
+		want = "want (unordered)"
+	}
+	return fmt.Sprintf("got %q, %s %q", tokens[1], want, strings.TrimRight(tokens[3], "\n"))
+}
+
+// setCoverage sets suite coverage if line has a coverage report
+func setCoverage(suite *Suite, line string) bool {
+	tokens := gtCoverageRE.FindStringSubmatch(line)
+	if tokens == nil {
+		return false
+	}
+	if tokens[2] == "" {
+		// coverage: [no statements]
+		return true
+	}
+	percent, err := strconv.ParseFloat(tokens[2], 64)
+	if err != nil {
+		return false
+	}
+	suite.Coverage = percent / 100
+	suite.HasCoverage = true
+	return true
+}
+
+// setCoverageProperties adds a "coverage.statements" property (percent) to
+// suites with coverage
+func setCoverageProperties(suites []*Suite) {
+	for _, suite := range suites {
+		if suite.HasCoverage {
+			value := fmt.Sprintf("%.1f", suite.Coverage*100)
+			suite.Properties = append(suite.Properties, Property{"coverage.statements", value})
+		}
+	}
+}
+
+// skipReason returns the skip message from skipped test output, which is the
+// last non-empty line without the "file.go:NN: " prefix
+func skipReason(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if loc := gtLogLineRE.FindStringIndex(lines[i]); loc != nil {
+			return strings.TrimSpace(lines[i][loc[1]:])
+		}
+		return line
+	}
+	return ""
+}
+
+// keepOutput returns true if output should be attached to test according to
+// Options.OutputFor
+func keepOutput(test *Test, output string) bool {
+	switch test.Status {
+	case Failed, Errored, UnknownStatus:
+		return true
+	case Skipped:
+		if Options.OutputFor == "fail+skip" {
+			return true
+		}
+	}
+
+	if Options.FailOnRace && hasDatarace(output) {
+		return true
+	}
+	return Options.OutputFor != "fail" && Options.OutputFor != "fail+skip"
+}
+
+// truncateOutput truncates out to about max bytes, keeping the first and
+// last max/2 bytes with a marker showing how many bytes were dropped.
+// Markers from previous truncations in the dropped part are accounted for.
+// It returns true if out was truncated.
+func truncateOutput(out string, max int) (string, bool) {
+	if max <= 0 || len(out) <= max {
+		return out, false
+	}
+
+	head := max / 2
+	tail := len(out) - (max - head)
+	middle := out[head:tail]
+	dropped := len(middle)
+	for _, tokens := range truncatedOutputRE.FindAllStringSubmatch(middle, -1) {
+		n, _ := strconv.Atoi(tokens[1])
+		dropped += n - len(tokens[0])
+	}
+
+	return out[:head] + fmt.Sprintf(truncatedOutputFmt, dropped) + out[tail:], true
+}
+
+// limitOutput truncates test message to Options.MaxOutputBytes
+func limitOutput(test *Test) {
+	var truncated bool
+	test.Message, truncated = truncateOutput(test.Message, Options.MaxOutputBytes)
+	if truncated {
+		test.OutputTruncated = true
+	}
+}
+
+// capLines returns lines joined to a single truncated line if they're
+// more than max bytes
+func capLines(lines []string, max int) []string {
+	if max <= 0 {
+		return lines
+	}
+	size := 0
+	for _, line := range lines {
+		size += len(line) + 1
+	}
+	if size <= max {
+		return lines
+	}
+	out, _ := truncateOutput(strings.Join(lines, "\n"), max)
+	return []string{out}
+}
+
+// topLevelTime returns the total time of the tests in suite that are not
+// subtests, in seconds
+func topLevelTime(suite *Suite) string {
+	var total time.Duration
+	for _, test := range suite.Tests {
+		if !strings.Contains(test.Name, "/") {
+			total += test.Elapsed()
+		}
+	}
+	return FormatSeconds(total)
+}
+
+// markTruncated marks tests in suite that have no result as errored with
+// a message saying input was truncated
+func markTruncated(suite *Suite) {
+	truncated := false
+	for _, test := range suite.Tests {
+		if test.Status != UnknownStatus && !test.Incomplete {
+			continue
+		}
+		truncated = true
+		test.Status = Errored
+		test.Time = "0"
+		test.Incomplete = true
+		if test.Message == "" {
+			test.Message = truncatedMessage
+		} else {
+			test.Message = truncatedMessage + "\n" + test.Message
+		}
+	}
+	if truncated {
+		suite.Status = "FAIL"
+	}
+}
+
+// isGoToolOutput returns true if line should be ignored as go tool output
+// (see Options.IgnoreBuildOutput)
+func isGoToolOutput(line string) bool {
+	return Options.IgnoreBuildOutput && goToolRE.MatchString(line)
+}
+
+// isBuildOutput returns true if line can be a part of compiler output (i.e.
+// it's not go test output)
+func isBuildOutput(line string) bool {
+	if line == "PASS" || line == "FAIL" {
+		return false
+	}
+	for _, re := range []*regexp.Regexp{gtStartRE, gtEndRE, gtSuiteRE, gtNoFilesRE} {
+		if re.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// noFilesSuite returns an empty suite for a package without test files
+func noFilesSuite(name string) *Suite {
+	return &Suite{
+		Name:       name,
+		Time:       "0",
+		Status:     "ok",
+		Properties: []Property{{"no test files", "true"}},
+	}
+}
+
+// buildFailedSuite returns a suite for a package that failed to build, it
+// has a single errored test with the build output
+func buildFailedSuite(name, stage string, output []string, line string) *Suite {
+	message := line
+	if len(output) > 0 {
+		message = strings.Join(output, "\n")
+	}
+	test := &Test{
+		Name:    fmt.Sprintf("[%s failed]", stage),
+		Time:    "0",
+		Message: message,
+		Status:  Errored,
+
+		isSynthetic: true,
+	}
+	return &Suite{Name: name, Time: "0", Status: "FAIL", Tests: []*Test{test}}
+}
+
+// newBenchmark returns a passed test from benchmark line tokens, or nil if
+// the metrics can't be parsed
+func newBenchmark(tokens []string) *Test {
+	n, _ := strconv.Atoi(tokens[3])
+	metrics, err := parseBenchMetrics(tokens[4])
+	if err != nil {
+		return nil
+	}
+	bench := &BenchmarkResult{
+		N:           n,
+		NsPerOp:     metrics["ns/op"],
+		BytesPerOp:  int64(metrics["B/op"]),
+		AllocsPerOp: int64(metrics["allocs/op"]),
+		Metrics:     metrics,
+	}
+
+	return &Test{
+		Name:      tokens[1],
+		Time:      FormatSeconds(durationOf(float64(n) * bench.NsPerOp / 1e9)),
+		Status:    Passed,
+		Benchmark: bench,
+	}
+}
+
+// parseBenchMetrics parses the "value unit" pairs of a benchmark line (e.g.
+// "243.5 ns/op  56 B/op  12.5 MB/s")
+func parseBenchMetrics(s string) (map[string]float64, error) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("bad benchmark metrics - %q", s)
+	}
+	metrics := make(map[string]float64)
+	for i := 0; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, err
+		}
+		metrics[fields[i+1]] = value
+	}
+	return metrics, nil
+}
+
+// findTest returns the last test called name in suite, or nil if there's none
+func findTest(suite *Suite, name string) *Test {
+	for i := len(suite.Tests) - 1; i >= 0; i-- {
+		if suite.Tests[i].Name == name {
+			return suite.Tests[i]
+		}
+	}
+	return nil
+}
+
+// addBenchmark adds bench to suite. Sub-benchmarks (e.g.
+// BenchmarkX/size=1024) are reported like subtests, after their parent
+// benchmark which is added if missing and takes the sum of their times.
+func addBenchmark(suite *Suite, bench *Test) {
+	names := namePrefixes(bench.Name)
+	for _, name := range names[:len(names)-1] {
+		parent := findTest(suite, name)
+		if parent == nil {
+			parent = &Test{Name: name, Time: "0", Status: Passed}
+			suite.Tests = append(suite.Tests, parent)
+		}
+		parent.isParentTest = true
+		parent.Time = FormatSeconds(parent.Elapsed() + bench.Elapsed())
+	}
+	suite.Tests = append(suite.Tests, bench)
+}
+
 // Returns previous test in a suite, for a given test. Returns error if previous
 // test doesn't exist.
 func getPreviousFailTest(suite *Suite, curTest *Test) (*Test, error) {
@@ -43,7 +587,7 @@ func getPreviousFailTest(suite *Suite, curTest *Test) (*Test, error) {
 		if test.Name == curTest.Name {
 			break
 		} else {
-			if test.Status == Failed {
+			if test.Status == Failed || test.Status == Errored {
 				previousFailTestIndex = testIndex
 			}
 		}
@@ -59,6 +603,11 @@ func getPreviousFailTest(suite *Suite, curTest *Test) (*Test, error) {
 // See data/gocheck.out for an example
 // TODO: Refactor to shorter ones
 func ParseGocheck(rd io.Reader, suitePrefix string) (Suites, error) {
+	return parseGocheck(rd, suitePrefix, defaultParseSettings())
+}
+
+// parseGocheck is ParseGocheck with settings
+func parseGocheck(rd io.Reader, suitePrefix string, settings parseSettings) (Suites, error) {
 	findStart := gcStartRE.FindStringSubmatch
 	findEnd := gcEndRE.FindStringSubmatch
 	findSuite := gcSuiteRE.FindStringSubmatch
@@ -70,9 +619,16 @@ func ParseGocheck(rd io.Reader, suitePrefix string) (Suites, error) {
 
 	var testName string
 	var out []string
+	// stopped is set if parsing stopped after more than maxFailed failures
+	maxFailed := settings.maxFailures
+	failures := 0
+	stopped := false
 
 	for scanner.Scan() {
 		line := scanner.Text()
+		if isGoToolOutput(line) {
+			continue
+		}
 
 		tokens := findStart(line)
 		if len(tokens) > 0 {
@@ -100,23 +656,40 @@ func ParseGocheck(rd io.Reader, suitePrefix string) (Suites, error) {
 				return nil, fmt.Errorf("%d: suite/name mismatch", scanner.Line())
 			}
 			test := &Test{Name: testName}
-			test.Message = strings.Join(out, "\n")
 			test.Time = tokens[4]
 			test.Status = Token2Status(tokens[1])
 			if test.Status == UnknownStatus {
 				return nil, fmt.Errorf("%d: unknown status %s", scanner.Line(), tokens[1])
 			}
+			if test.Status == Skipped {
+				if match := gcSkipReasonRE.FindStringSubmatch(line); match != nil {
+					test.SkipReason = match[1]
+				}
+			}
+			if message := strings.Join(out, "\n"); keepOutput(test, message) {
+				test.Message = message
+				limitOutput(test)
+			}
 
 			if suite == nil || suite.Name != suiteName {
 				suite = &Suite{Name: suitePrefix + suiteName}
 				suites = append(suites, suite)
 			}
 			suite.Tests = append(suite.Tests, test)
+			settings.progress.addTest(test.Status)
+			testEnded(test)
 
 			testName = ""
 			suiteName = ""
 			out = []string{}
 
+			if test.Status == Failed {
+				failures++
+				if tooManyFailures(failures, maxFailed) {
+					stopped = true
+					break
+				}
+			}
 			continue
 		}
 
@@ -130,6 +703,8 @@ func ParseGocheck(rd io.Reader, suitePrefix string) (Suites, error) {
 				suite.Status = tokens[1]
 				suite.Time = tokens[3]
 			}
+			settings.progress.addPackage()
+			suiteEnded(suite)
 
 			testName = ""
 			suiteName = ""
@@ -147,19 +722,110 @@ func ParseGocheck(rd io.Reader, suitePrefix string) (Suites, error) {
 		return nil, err
 	}
 
+	if stopped {
+		markStoppedEarly(suites)
+	}
+	setFailureMessages(suites)
+	setRunCounts(suites)
+	setCoverageProperties(suites)
+	setArtifacts(suites)
 	return Suites(suites), nil
 }
 
+// isFuzzRerun returns true if name is the last test in suite, a fuzz target
+// that found a failing input reports it as a nested test with its own name
+func isFuzzRerun(suite *Suite, name string) bool {
+	if !strings.HasPrefix(name, "Fuzz") || len(suite.Tests) == 0 {
+		return false
+	}
+	return suite.Tests[len(suite.Tests)-1].Name == name
+}
+
+// testEnded calls Options.OnTestEnd with test if it's set
+func testEnded(test *Test) {
+	if Options.OnTestEnd != nil {
+		Options.OnTestEnd(test)
+	}
+}
+
+// suiteEnded calls Options.OnSuiteEnd with suite if it's set
+func suiteEnded(suite *Suite) {
+	if Options.OnSuiteEnd != nil {
+		Options.OnSuiteEnd(suite)
+	}
+}
+
+// setArtifacts sets the Artifacts of tests in suites from their output
+func setArtifacts(suites []*Suite) {
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			test.Artifacts = nil
+			for _, line := range strings.Split(test.Message, "\n") {
+				if tokens := gtArtifactRE.FindStringSubmatch(line); tokens != nil {
+					test.Artifacts = append(test.Artifacts, artifactPath(tokens[3]))
+				}
+			}
+		}
+	}
+}
+
+// artifactPath returns the path of an ARTIFACT line value, either the path
+// itself or key=value fields with a path field
+func artifactPath(value string) string {
+	value = strings.TrimSpace(value)
+	for _, field := range strings.Fields(value) {
+		if strings.HasPrefix(field, "path=") {
+			return strings.TrimPrefix(field, "path=")
+		}
+	}
+	return value
+}
+
+// setFuzzInputs adds a "fuzz.input" property with the path of the failing
+// input to failed fuzz tests. The path is either reported by "go test -fuzz"
+// or, for a failed corpus entry (FuzzXxx/name), testdata/fuzz/FuzzXxx/name.
+func setFuzzInputs(suites []*Suite) {
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.Status != Failed || !strings.HasPrefix(test.Name, "Fuzz") {
+				continue
+			}
+			input := ""
+			if tokens := gtFuzzInputRE.FindStringSubmatch(test.Message); tokens != nil {
+				input = tokens[1]
+			} else if i := strings.Index(test.Name, "/"); i > 0 && !strings.HasPrefix(test.Name[i+1:], "seed#") {
+				input = "testdata/fuzz/" + test.Name
+			}
+			if input != "" {
+				test.Properties = append(test.Properties, Property{"fuzz.input", input})
+			}
+		}
+	}
+}
+
 // ParseGotest parser output of gotest
 // TODO: Make it shorter
 func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
+	return parseGotest(rd, suitePrefix, defaultParseSettings())
+}
+
+// parseGotest is ParseGotest with settings
+func parseGotest(rd io.Reader, suitePrefix string, settings parseSettings) (Suites, error) {
 	findStart := gtStartRE.FindStringSubmatch
+	findName := gtNameRE.FindStringSubmatch
 	findEnd := gtEndRE.FindStringSubmatch
 	findSuite := gtSuiteRE.FindStringSubmatch
-	isNoFiles := gtNoFilesRE.MatchString
-	isBuildFailed := gtBuildFailedRE.MatchString
+	findNoFiles := gtNoFilesRE.FindStringSubmatch
+	findNoTestsCoverage := gtNoTestsCoverageRE.FindStringSubmatch
+	findBuildFailed := gtBuildFailedRE.FindStringSubmatch
+	findBuildHeader := gtBuildHeaderRE.FindStringSubmatch
 	isExit := gtExitRE.MatchString
 	isErrorOutput := gcTestErrorRE.MatchString
+	findBench := gtBenchRE.FindStringSubmatch
+	findBenchEnd := gtBenchEndRE.FindStringSubmatch
+	isBenchHeader := gtBenchHeaderRE.MatchString
+	isFuzzProgress := gtFuzzProgressRE.MatchString
+	findShuffle := gtShuffleRE.FindStringSubmatch
 
 	suites := []*Suite{}
 	subTests := map[string]*Test{}
@@ -167,14 +833,58 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 	var curTest *Test
 	var curSuite *Suite
 	var out []string
+	// pkgOutput is set after the final PASS/FAIL line, output from there to
+	// the suite summary line is not part of any test
+	pkgOutput := false
+	// afterEnd is set if the output in out follows a test end line (older go
+	// versions print subtest output after it), not a test start line
+	afterEnd := false
 	suiteStack := SuiteStack{}
+	// Compiler output per package, buildPkg is the package we're reading
+	// compiler output for
+	buildOut := map[string][]string{}
+	buildPkg := ""
+	// stopped is set if parsing stopped after more than maxFailed failures
+	maxFailed := settings.maxFailures
+	failures := 0
+	stopped := false
 
 	// Handles a test that ended with a panic.
 	handlePanic := func() {
-		curTest.Status = Failed
+		curTest.Status = Errored
 		curTest.Time = "0"
-		curSuite.Tests = append(curSuite.Tests, curTest)
+		curTest.Incomplete = true
+		// Subtests are already in the suite
+		if !hasTest(curSuite, curTest) {
+			curSuite.Tests = append(curSuite.Tests, curTest)
+		}
+		curTest = nil
+	}
+
+	// Handles a "panic: test timed out" report, every test that didn't finish
+	// is marked as errored and gets the report (which lists running tests and
+	// goroutine stacks).
+	handleTimeout := func() bool {
+		output := strings.Join(out, "\n")
+		if !isTimeout(output) {
+			return false
+		}
+		if curTest != nil && !hasTest(curSuite, curTest) {
+			curSuite.Tests = append(curSuite.Tests, curTest)
+		}
+		for _, test := range curSuite.Tests {
+			if test.Status == UnknownStatus {
+				test.Status = Errored
+				test.Time = "0"
+				test.Incomplete = true
+				test.Message = timeoutMessage + "\n" + output
+			}
+		}
+		curSuite.Status = "FAIL"
 		curTest = nil
+		parentTest = nil
+		out = []string{}
+		return true
 	}
 
 	// Appends output to the last test.
@@ -182,38 +892,112 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 		if len(out) > 0 && curSuite != nil && len(curSuite.Tests) > 0 {
 			message := strings.Join(out, "\n")
 			test := curSuite.Tests[len(curSuite.Tests)-1]
-			if test.isParentTest == false {
+			if test.isParentTest == false && keepOutput(test, message) {
 				if test.Message == "" {
 					test.Message = message
 				} else {
 					test.Message += "\n" + message
 				}
+				limitOutput(test)
 				test.AppendedErrorOutput = isErrorOutput(message)
 			}
 		}
 		out = []string{}
 	}
 
+	var profile coverProfile
 	scanner := NewLineScanner(rd)
 	for scanner.Scan() {
 		line := scanner.Text()
+		if isGoToolOutput(line) || profile.add(line) {
+			continue
+		}
+
+		if tokens := findNoFiles(line); tokens != nil {
+			if Options.ShowEmptyPackages {
+				suites = append(suites, noFilesSuite(suitePrefix+tokens[1]))
+			}
+			continue
+		}
+		if tokens := findNoTestsCoverage(line); tokens != nil {
+			if Options.ShowEmptyPackages {
+				suite := noFilesSuite(suitePrefix + tokens[1])
+				setCoverage(suite, line)
+				suites = append(suites, suite)
+			}
+			continue
+		}
 
-		// TODO: Only outside a suite/test, report as empty suite?
-		if isNoFiles(line) {
+		if tokens := findBuildFailed(line); tokens != nil {
+			pkg := tokens[1]
+			suite := buildFailedSuite(suitePrefix+pkg, tokens[2], buildOut[pkg], line)
+			suites = append(suites, suite)
+			buildPkg = ""
 			continue
 		}
 
-		if isBuildFailed(line) {
-			return nil, fmt.Errorf("%d: package build failed: %s", scanner.Line(), line)
+		if tokens := findBuildHeader(line); tokens != nil && curTest == nil {
+			buildPkg = tokens[1]
+			buildOut[buildPkg] = []string{line}
+			continue
+		}
+
+		if buildPkg != "" {
+			if isBuildOutput(line) {
+				buildOut[buildPkg] = append(buildOut[buildPkg], line)
+				continue
+			}
+			buildPkg = ""
 		}
 
 		if curSuite == nil {
 			curSuite = &Suite{}
 		}
+
+		if tokens := findShuffle(line); tokens != nil {
+			curSuite.ShuffleSeed = tokens[2]
+			curSuite.Properties = append(curSuite.Properties, Property{ShuffleSeedProperty, tokens[2]})
+			continue
+		}
+		if isBenchHeader(line) || isFuzzProgress(line) {
+			continue
+		}
+		if strings.HasPrefix(line, "coverage: ") && setCoverage(curSuite, line) {
+			continue
+		}
+		if tokens := findBench(line); tokens != nil {
+			if bench := newBenchmark(tokens); bench != nil {
+				appendError()
+				addBenchmark(curSuite, bench)
+				continue
+			}
+		}
+		if tokens := findBenchEnd(line); tokens != nil && curTest == nil {
+			// Failed (b.Fatal) or skipped benchmark, output before this
+			// line is its own
+			status := Token2Status(tokens[1])
+			if parent := findTest(curSuite, tokens[2]); parent != nil && parent.isParentTest {
+				parent.Status = status
+			} else {
+				bench := &Test{Name: tokens[2], Time: "0", Status: status, Message: strings.Join(out, "\n")}
+				if status == Skipped {
+					bench.SkipReason = skipReason(bench.Message)
+				}
+				addBenchmark(curSuite, bench)
+			}
+			out = []string{}
+			continue
+		}
+
 		tokens := findStart(line)
 		if tokens != nil {
 			subTest := false
-			if curTest != nil {
+			if curTest == nil && parentTest != nil && strings.HasPrefix(tokens[1], parentTest.Name+"/") {
+				// Next subtest after one ended ("go test -json" reports
+				// subtest results as they end)
+				parentTest.isParentTest = true
+				subTest = true
+			} else if curTest != nil {
 				// This occurs when the last test ended with a panic, or when subtests are found
 				if parentTest == nil && strings.HasPrefix(tokens[1], curTest.Name+"/") {
 					// First subtest after parent
@@ -233,6 +1017,7 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 				}
 			}
 			appendError()
+			afterEnd = false
 			curTest = &Test{
 				Name: tokens[1],
 			}
@@ -243,10 +1028,33 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 			continue
 		}
 
+		if tokens := findName(line); tokens != nil {
+			if tokens[1] == "" {
+				// Printed by a fuzz target after a failing input was found
+				continue
+			}
+			if curTest != nil && subTests[curTest.Name] == curTest {
+				// Output switches from the running subtest (go 1.20+)
+				appendError()
+				if parentTest != nil && tokens[1] == parentTest.Name {
+					curTest = parentTest
+				} else if subTest, ok := subTests[tokens[1]]; ok {
+					curTest = subTest
+				}
+				continue
+			}
+		}
+
 		tokens = findEnd(line)
 		if tokens != nil {
+			settings.progress.addTest(Token2Status(tokens[1]))
 			appendTest := true
 			if parentTest != nil && tokens[2] == parentTest.Name {
+				if curTest != parentTest {
+					// Output of the last subtest comes before its parent's
+					// end line (go 1.14+)
+					appendError()
+				}
 				curTest = parentTest
 				parentTest = nil
 				appendTest = false
@@ -257,6 +1065,9 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 				parentTest = nil
 				subTests = map[string]*Test{}
 				if curTest == nil {
+					if isFuzzRerun(curSuite, tokens[2]) {
+						continue
+					}
 					if suiteStack.count > 0 {
 						prevSuite := suiteStack.Pop()
 						suites = append(suites, curSuite)
@@ -275,22 +1086,26 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 			if curTest.Status == UnknownStatus {
 				return nil, fmt.Errorf("%d: unknown status - %s", scanner.Line(), tokens[1])
 			}
-			if Options.FailOnRace && hasDatarace(out) {
-				curTest.Status = Failed
-			}
 			curTest.Time = tokens[3]
 
 			if len(out) > 0 {
 				message := strings.Join(out, "\n")
+				if curTest.Status == Skipped {
+					curTest.SkipReason = skipReason(message)
+				}
 				prevTest, err := getPreviousFailTest(curSuite, curTest)
 				var test *Test
-				if err == nil && prevTest.AppendedErrorOutput == false {
+				if err == nil && afterEnd && prevTest.AppendedErrorOutput == false {
 					test = prevTest
 				} else {
 					test = curTest
 				}
-				if test.isParentTest == false {
+				if test.isParentTest == false && keepOutput(test, message) {
+					if test.Message != "" {
+						test.Message += "\n"
+					}
 					test.Message += message
+					limitOutput(test)
 					test.AppendedErrorOutput = isErrorOutput(message)
 				}
 			}
@@ -298,46 +1113,98 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 			if appendTest {
 				curSuite.Tests = append(curSuite.Tests, curTest)
 			}
+			testEnded(curTest)
+			if curTest.Status == Failed && !hasSubtests(curSuite, curTest.Name) {
+				// Parents fail with their subtests, only leaves are counted
+				failures++
+			}
 			curTest = nil
 			out = []string{}
+			afterEnd = true
+			if tooManyFailures(failures, maxFailed) && subtestsEnded(subTests) {
+				// Results of the subtests of the last test follow its end
+				// line, they're read before stopping
+				stopped = true
+				break
+			}
 			continue
 		}
 
 		tokens = findSuite(line)
 		if tokens != nil {
-			if curTest != nil {
+			if !handleTimeout() && curTest != nil {
 				// This occurs when the last test ended with a panic.
 				handlePanic()
 			}
+			if pkgOutput && Options.FailOnRace && hasDatarace(strings.Join(out, "\n")) {
+				// Race outside of any test (e.g. in TestMain)
+				curSuite.Tests = append(curSuite.Tests, &Test{
+					Name:    "DATA RACE",
+					Time:    "0",
+					Message: strings.Join(out, "\n"),
+					Status:  Errored,
+
+					isSynthetic: true,
+				})
+				out = []string{}
+			}
+			pkgOutput = false
 			appendError()
 			curSuite.Name = suitePrefix + tokens[2]
 			curSuite.Time = tokens[3]
+			setCoverage(curSuite, line)
 			suites = append(suites, curSuite)
+			settings.progress.addPackage()
+			suiteEnded(curSuite)
 			curSuite = nil
 			continue
 		}
 
-		if isExit(line) || (line == "FAIL") || (line == "PASS") {
+		if (line == "FAIL") || (line == "PASS") {
+			if curTest == nil {
+				appendError()
+				pkgOutput = true
+			}
+			continue
+		}
+
+		if isExit(line) {
 			continue
 		}
 
 		out = append(out, line)
+		if n := len(out); n&(n-1) == 0 {
+			// Check size only when number of lines doubles
+			out = capLines(out, Options.MaxOutputBytes)
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	if curTest != nil {
-		// This occurs when the last test fatal'd outside of the `go test` runner.
-		handlePanic()
+	if curSuite != nil && !handleTimeout() {
+		panicked := isPanic(strings.Join(out, "\n"))
+		if curTest != nil {
+			// This occurs when the last test fatal'd outside of the `go test`
+			// runner, or when the input was cut
+			handlePanic()
+			appendError()
+		}
+		if !panicked {
+			markTruncated(curSuite)
+		}
 	}
 
 	// If there were no suites found, but everything else went OK, return a
 	// generic suite.
 	if len(suites) == 0 && curSuite != nil {
 		if curSuite.Name == "" {
-			curSuite.Name = suitePrefix
+			curSuite.Name = suitePrefix + Options.PackageName
+		}
+		if curSuite.Time == "" {
+			// No package summary line (e.g. a test binary)
+			curSuite.Time = topLevelTime(curSuite)
 		}
 		// Catch any post-failure messages from the last test
 		appendError()
@@ -346,6 +1213,17 @@ func ParseGotest(rd io.Reader, suitePrefix string) (Suites, error) {
 	if curSuite != nil && len(curSuite.Tests) > 0 {
 		suites = append(suites, curSuite)
 	}
+	if stopped {
+		markStoppedEarly(suites)
+	}
 
+	markParentTests(suites)
+	markErrors(suites)
+	setFailureMessages(suites)
+	setRunCounts(suites)
+	setCoverageProperties(suites)
+	setCoverProfiles(suites, profile, suitePrefix)
+	setFuzzInputs(suites)
+	setArtifacts(suites)
 	return Suites(suites), nil
 }
@@ -0,0 +1,144 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Status glyphs of the pretty format
+const (
+	glyphPass  = "✓"
+	glyphFail  = "✗"
+	glyphError = "!"
+	glyphSkip  = "○"
+	glyphOther = "?"
+)
+
+// PrettyWriter writes a colored console report: a line per test as it
+// finishes (subtests indented under their parent), a line per package with
+// its counts and time once it ends, and then failed tests with their output
+// and the totals. Call Test and Suite as results are parsed (see
+// Options.OnTestEnd and Options.OnSuiteEnd) and Finish with all the suites.
+type PrettyWriter struct {
+	w     io.Writer
+	color bool
+	err   error // First write error
+}
+
+// NewPrettyWriter returns a PrettyWriter to w, with ANSI colors if color is
+// true
+func NewPrettyWriter(w io.Writer, color bool) *PrettyWriter {
+	return &PrettyWriter{w: w, color: color}
+}
+
+// WritePretty writes suites to w in the pretty format of PrettyWriter
+func WritePretty(w io.Writer, suites Suites, color bool) error {
+	pw := NewPrettyWriter(w, color)
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			pw.Test(test)
+		}
+		pw.Suite(suite)
+	}
+	return pw.Finish(suites)
+}
+
+// printf writes to pw.w with color (none if empty), after a write error it
+// does nothing
+func (pw *PrettyWriter) printf(color, format string, args ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	text := fmt.Sprintf(format, args...)
+	if pw.color && color != "" {
+		text = color + text + colorReset
+	}
+	_, pw.err = io.WriteString(pw.w, text)
+}
+
+// Test writes the line of a finished test
+func (pw *PrettyWriter) Test(test *Test) {
+	glyph, color := glyphOther, ""
+	switch test.Status {
+	case Passed:
+		glyph, color = glyphPass, colorGreen
+	case Failed:
+		glyph, color = glyphFail, colorRed
+	case Errored:
+		glyph, color = glyphError, colorRed
+	case Skipped:
+		glyph, color = glyphSkip, colorYellow
+	}
+
+	indent := strings.Repeat("  ", 1+strings.Count(test.Name, "/"))
+	pw.printf("", "%s", indent)
+	pw.printf(color, "%s", glyph)
+	pw.printf("", " %s (%s)\n", test.Name, test.Elapsed())
+}
+
+// Suite writes the line of a finished package
+func (pw *PrettyWriter) Suite(suite *Suite) {
+	pw.printf(suiteColor(suite), "%s", suite.Name)
+	pw.printf("", ": %s (%s)\n", prettyCounts(suite.Len(), suite.NumFailed(), suite.NumErrors(), suite.NumSkipped()),
+		suite.Elapsed())
+}
+
+// Finish writes the failed tests of suites with their output and the totals,
+// it returns the first write error
+func (pw *PrettyWriter) Finish(suites Suites) error {
+	tests, failed, errors, skipped := 0, 0, 0, 0
+	for _, suite := range suites {
+		tests += suite.Len()
+		failed += suite.NumFailed()
+		errors += suite.NumErrors()
+		skipped += suite.NumSkipped()
+	}
+
+	if failed+errors > 0 {
+		pw.printf("", "\n")
+		pw.printf(colorRed, "FAILURES")
+		pw.printf("", "\n")
+		for _, suite := range suites {
+			for _, test := range suite.Tests {
+				if test.isParentTest || (test.Status != Failed && test.Status != Errored) {
+					continue
+				}
+				pw.printf(colorRed, "--- %s %s", suite.Name, test.Name)
+				pw.printf("", "\n")
+				if msg := strings.TrimRight(test.Message, "\n"); msg != "" {
+					pw.printf("", "%s\n", indentLines(msg, "    "))
+				}
+			}
+		}
+	}
+
+	color := colorGreen
+	switch {
+	case failed+errors > 0:
+		color = colorRed
+	case skipped > 0:
+		color = colorYellow
+	}
+	pw.printf("", "\n")
+	pw.printf(color, "%s in %d packages", prettyCounts(tests, failed, errors, skipped), len(suites))
+	pw.printf("", "\n")
+	return pw.err
+}
+
+// prettyCounts returns the test counts text of the pretty format
+func prettyCounts(tests, failed, errors, skipped int) string {
+	return fmt.Sprintf("%d tests, %d failed, %d errors, %d skipped", tests, failed, errors, skipped)
+}
+
+// indentLines returns text with indent before every line that is not
+// already indented ("go test" output is)
+func indentLines(text, indent string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
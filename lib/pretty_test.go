@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWritePretty(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/ok", Time: "1.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed, Time: "0.5"},
+			{Name: "TestB", Status: Skipped},
+		}},
+		{Name: "example.com/bad", Time: "0.2", Tests: []*Test{
+			{Name: "TestA", Status: Failed, Message: "    a_test.go:9: failed\n", isParentTest: true},
+			{Name: "TestA/sub", Status: Failed, Message: "    a_test.go:9: failed\n"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePretty(&buf, suites, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := `  ✓ TestA (500ms)
+  ○ TestB (0s)
+example.com/ok: 2 tests, 0 failed, 0 errors, 1 skipped (1.5s)
+  ✗ TestA (0s)
+    ✗ TestA/sub (0s)
+example.com/bad: 2 tests, 2 failed, 0 errors, 0 skipped (200ms)
+
+FAILURES
+--- example.com/bad TestA/sub
+    a_test.go:9: failed
+
+4 tests, 2 failed, 0 errors, 1 skipped in 2 packages
+`
+	if buf.String() != expected {
+		t.Fatalf("bad output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := WritePretty(&buf, suites, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{colorGreen + glyphPass + colorReset, colorRed + "example.com/bad" + colorReset} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("%q not in colored output:\n%s", s, buf.String())
+		}
+	}
+}
+
+func TestPrettyStream(t *testing.T) {
+	file, err := os.Open("../_data/in/test2json-parallel.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	pw := NewPrettyWriter(&buf, false)
+	Options.OnTestEnd, Options.OnSuiteEnd = pw.Test, pw.Suite
+	defer func() { Options.OnTestEnd, Options.OnSuiteEnd = nil, nil }()
+
+	suites, err := ParseTest2JSON(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Finish(suites); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every package line follows its tests, before the next package tests
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 12 {
+		t.Fatalf("bad output:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[4], "example.com/par/p1: 4 tests") ||
+		!strings.HasPrefix(lines[9], "example.com/par/p2: 4 tests") {
+		t.Fatalf("bad package lines:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProgressInterval is the default minimal time between Progress.Notify
+// calls
+const DefaultProgressInterval = 250 * time.Millisecond
+
+// Progress counts parsed tests and packages, it's safe for concurrent use.
+// Set ParseOptions.Progress to track parsing with ParseContext, or set
+// Options.Progress and read the input with Reader to count bytes and lines.
+type Progress struct {
+	// Notify, if set, is called with the progress while parsing, at most
+	// every Interval (DefaultProgressInterval if 0). It's called from the
+	// parsing goroutine.
+	Notify   func(ProgressStats)
+	Interval time.Duration
+
+	bytes    int64
+	lines    int64
+	tests    int64
+	failures int64
+	packages int64
+	// lastNotify is the time of the last Notify call, in Unix nanoseconds
+	lastNotify int64
+}
+
+// ProgressStats is a snapshot of Progress
+type ProgressStats struct {
+	Bytes    int64 // Input bytes read
+	Lines    int   // Input lines read
+	Tests    int   // Parsed tests
+	Failures int   // Parsed failed (or errored) tests
+	Packages int   // Parsed packages
+}
+
+// Tests returns the number of parsed tests
+func (p *Progress) Tests() int {
+	return int(atomic.LoadInt64(&p.tests))
+}
+
+// Packages returns the number of parsed packages
+func (p *Progress) Packages() int {
+	return int(atomic.LoadInt64(&p.packages))
+}
+
+// Stats returns the current progress
+func (p *Progress) Stats() ProgressStats {
+	return ProgressStats{
+		Bytes:    atomic.LoadInt64(&p.bytes),
+		Lines:    int(atomic.LoadInt64(&p.lines)),
+		Tests:    p.Tests(),
+		Failures: int(atomic.LoadInt64(&p.failures)),
+		Packages: p.Packages(),
+	}
+}
+
+// Reader returns r, counting the bytes and lines read from it in p
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	return &progressReader{r, p}
+}
+
+// progressReader is an io.Reader that counts bytes and lines in a Progress
+type progressReader struct {
+	r io.Reader
+	p *Progress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	if n > 0 {
+		atomic.AddInt64(&pr.p.bytes, int64(n))
+		atomic.AddInt64(&pr.p.lines, int64(bytes.Count(b[:n], []byte{'\n'})))
+		pr.p.notify()
+	}
+	return n, err
+}
+
+// addTest counts a parsed test with status, it does nothing if p is nil
+func (p *Progress) addTest(status Status) {
+	if p != nil {
+		atomic.AddInt64(&p.tests, 1)
+		if status == Failed || status == Errored {
+			atomic.AddInt64(&p.failures, 1)
+		}
+		p.notify()
+	}
+}
+
+// addPackage counts a parsed package, it does nothing if p is nil
+func (p *Progress) addPackage() {
+	if p != nil {
+		atomic.AddInt64(&p.packages, 1)
+		p.notify()
+	}
+}
+
+// notify calls p.Notify if Interval passed since the last call
+func (p *Progress) notify() {
+	if p.Notify == nil {
+		return
+	}
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&p.lastNotify)
+	if now-last < int64(interval) || !atomic.CompareAndSwapInt64(&p.lastNotify, last, now) {
+		return
+	}
+	p.Notify(p.Stats())
+}
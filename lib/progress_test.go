@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	progress := &Progress{}
+	Options.Progress = progress
+	defer func() { Options.Progress = nil }()
+
+	filename := "../_data/in/gotest-multi.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	tests := 0
+	for _, suite := range suites {
+		tests += len(suite.Tests)
+	}
+	if progress.Tests() != tests || progress.Packages() != len(suites) {
+		t.Fatalf("progress: %d tests in %d packages, expected %d in %d",
+			progress.Tests(), progress.Packages(), tests, len(suites))
+	}
+}
+
+func TestParseContextProgress(t *testing.T) {
+	data, err := ioutil.ReadFile("../_data/in/test2json-gotest.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	progress := &Progress{}
+	opts := ParseOptions{Progress: progress}
+	suites, err := ParseContext(context.Background(), ParseTest2JSON, bytes.NewReader(data), "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Options.Progress != nil {
+		t.Fatalf("Options.Progress was set")
+	}
+
+	tests := 0
+	for _, suite := range suites {
+		tests += len(suite.Tests)
+	}
+	stats := progress.Stats()
+	if stats.Tests != tests || stats.Packages != len(suites) || stats.Bytes != int64(len(data)) {
+		t.Fatalf("bad progress %+v, expected %d tests in %d packages (%d bytes)",
+			stats, tests, len(suites), len(data))
+	}
+}
+
+func TestProgressStats(t *testing.T) {
+	data, err := ioutil.ReadFile("../_data/in/gotest.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var notified []ProgressStats
+	progress := &Progress{
+		Notify:   func(stats ProgressStats) { notified = append(notified, stats) },
+		Interval: time.Hour,
+	}
+	Options.Progress = progress
+	defer func() { Options.Progress = nil }()
+
+	suites, err := ParseGotest(progress.Reader(bytes.NewReader(data)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := progress.Stats()
+	if stats.Bytes != int64(len(data)) {
+		t.Fatalf("bytes: %d, expected %d", stats.Bytes, len(data))
+	}
+	if lines := bytes.Count(data, []byte("\n")); stats.Lines != lines {
+		t.Fatalf("lines: %d, expected %d", stats.Lines, lines)
+	}
+	counts := suites.Stats()
+	if failures := counts["fail"] + counts["error"]; stats.Failures != failures {
+		t.Fatalf("failures: %d, expected %d", stats.Failures, failures)
+	}
+	// Throttled to one call per Interval
+	if len(notified) != 1 {
+		t.Fatalf("%d notifications, expected 1", len(notified))
+	}
+}
+
+func benchmarkParseGotest(b *testing.B, progress *Progress) {
+	data, err := ioutil.ReadFile("../_data/in/gotest-multi.out")
+	if err != nil {
+		b.Fatal(err)
+	}
+	Options.Progress = progress
+	defer func() { Options.Progress = nil }()
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseGotest(bytes.NewReader(data), ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseGotest(b *testing.B) {
+	b.Run("off", func(b *testing.B) { benchmarkParseGotest(b, nil) })
+	b.Run("on", func(b *testing.B) {
+		benchmarkParseGotest(b, &Progress{Notify: func(ProgressStats) {}})
+	})
+}
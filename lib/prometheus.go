@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promLabelRE matches valid Prometheus label names
+var promLabelRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// promReservedLabels are the label names of WritePrometheus series, constant
+// labels can't use them
+var promReservedLabels = map[string]bool{
+	"package": true,
+	"status":  true,
+	"test":    true,
+}
+
+// promStatuses are the status label values of go_tests_total
+var promStatuses = []string{"pass", "fail", "error", "skip", otherStatus}
+
+// CheckPrometheusLabels returns an error if labels can't be constant labels of
+// WritePrometheus
+func CheckPrometheusLabels(labels []Property) error {
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		switch {
+		case !promLabelRE.MatchString(label.Name) || strings.HasPrefix(label.Name, "__"):
+			return fmt.Errorf("bad metrics label name: %q", label.Name)
+		case promReservedLabels[label.Name]:
+			return fmt.Errorf("metrics label %q is reserved", label.Name)
+		case seen[label.Name]:
+			return fmt.Errorf("duplicate metrics label: %q", label.Name)
+		}
+		seen[label.Name] = true
+	}
+	return nil
+}
+
+// WritePrometheus writes metrics of suites in the Prometheus text exposition
+// format (e.g. for the node_exporter textfile collector): the number of tests
+// by package and status, the elapsed time of packages and runTime. Parents of
+// subtests are not counted. labels are added to every series, with perTest
+// there's also a series of the elapsed time of every test.
+func WritePrometheus(w io.Writer, suites Suites, runTime time.Time, labels []Property, perTest bool) error {
+	if err := CheckPrometheusLabels(labels); err != nil {
+		return err
+	}
+
+	// Suites of the same package (e.g. merged shards) are one series
+	var names []string
+	counts := make(map[string]map[string]int)
+	elapsed := make(map[string]time.Duration)
+	for _, suite := range suites {
+		if _, ok := counts[suite.Name]; !ok {
+			names = append(names, suite.Name)
+			counts[suite.Name] = make(map[string]int)
+		}
+		elapsed[suite.Name] += suite.Elapsed()
+		for _, test := range suite.Tests {
+			if !test.isParentTest {
+				counts[suite.Name][promStatus(test)]++
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	promHeader(&buf, "go_tests_total", "counter", "Number of tests by package and status.")
+	for _, name := range names {
+		for _, status := range promStatuses {
+			promSample(&buf, "go_tests_total", labels, strconv.Itoa(counts[name][status]),
+				"package", name, "status", status)
+		}
+	}
+
+	promHeader(&buf, "go_test_duration_seconds", "gauge", "Elapsed time of the package tests.")
+	for _, name := range names {
+		promSample(&buf, "go_test_duration_seconds", labels, promSeconds(elapsed[name]), "package", name)
+	}
+
+	if perTest {
+		promHeader(&buf, "go_test_case_duration_seconds", "gauge", "Elapsed time of a test.")
+		seen := make(map[string]bool)
+		for _, suite := range suites {
+			for _, test := range suite.Tests {
+				// Reruns (-count) of a test are one series, the first run
+				key := suite.Name + "\x00" + test.Name
+				if test.isParentTest || seen[key] {
+					continue
+				}
+				seen[key] = true
+				promSample(&buf, "go_test_case_duration_seconds", labels, promSeconds(test.Elapsed()),
+					"package", suite.Name, "test", test.Name, "status", promStatus(test))
+			}
+		}
+	}
+
+	promHeader(&buf, "go_test_run_timestamp_seconds", "gauge", "Time of the test run in seconds since the epoch.")
+	promSample(&buf, "go_test_run_timestamp_seconds", labels, strconv.FormatInt(runTime.Unix(), 10))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// promStatus returns the status label value of test
+func promStatus(test *Test) string {
+	if status, ok := statusNames[test.Status]; ok {
+		return status
+	}
+	return otherStatus
+}
+
+// promSeconds returns d in seconds as a Prometheus sample value
+func promSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// promHeader writes the HELP and TYPE lines of a metric
+func promHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// promSample writes a sample line of metric name, pairs are label names and
+// values that come before the constant labels
+func promSample(buf *bytes.Buffer, name string, labels []Property, value string, pairs ...string) {
+	buf.WriteString(name)
+	if len(pairs)+len(labels) > 0 {
+		var parts []string
+		for i := 0; i+1 < len(pairs); i += 2 {
+			parts = append(parts, pairs[i]+`="`+promEscape(pairs[i+1])+`"`)
+		}
+		for _, label := range labels {
+			parts = append(parts, label.Name+`="`+promEscape(label.Value)+`"`)
+		}
+		buf.WriteString("{" + strings.Join(parts, ",") + "}")
+	}
+	buf.WriteString(" " + value + "\n")
+}
+
+// promEscaper escapes label values of the Prometheus text format
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promEscape returns value escaped for a Prometheus label value
+func promEscape(value string) string {
+	return promEscaper.Replace(value)
+}
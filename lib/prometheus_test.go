@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	promSampleRE = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{(?:[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\[\\"n])*",?)*\})? (\S+)$`)
+	promHeaderRE = regexp.MustCompile(`^# (HELP|TYPE) ([a-zA-Z_:][a-zA-Z0-9_:]*) (.+)$`)
+)
+
+// checkPrometheus checks text like "promtool check metrics": every line is a
+// comment or a sample, samples of a metric come after its HELP and TYPE and
+// together, series are unique and the text ends with a newline
+func checkPrometheus(t *testing.T, text string) {
+	if !strings.HasSuffix(text, "\n") {
+		t.Fatalf("no newline at end:\n%s", text)
+	}
+	types := make(map[string]string)
+	done := make(map[string]bool)
+	series := make(map[string]bool)
+	current := ""
+	for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if match := promHeaderRE.FindStringSubmatch(line); match != nil {
+			if match[2] != current {
+				if done[match[2]] {
+					t.Fatalf("line %d: %s is not grouped", i+1, match[2])
+				}
+				done[current] = true
+				current = match[2]
+			}
+			if match[1] == "TYPE" {
+				types[match[2]] = match[3]
+			}
+			continue
+		}
+		match := promSampleRE.FindStringSubmatch(line)
+		if match == nil {
+			t.Fatalf("line %d: bad sample: %q", i+1, line)
+		}
+		if match[1] != current || types[current] == "" {
+			t.Fatalf("line %d: %s sample with no HELP and TYPE", i+1, match[1])
+		}
+		if series[match[1]+match[2]] {
+			t.Fatalf("line %d: duplicate series: %q", i+1, line)
+		}
+		series[match[1]+match[2]] = true
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/a", Time: "1.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed, Time: "0.5"},
+			{Name: "TestB", Status: Failed, isParentTest: true},
+			{Name: "TestB/\"quoted\"\\", Status: Failed, Time: "0.25"},
+		}},
+		{Name: "example.com/a", Time: "0.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+		}},
+		{Name: "example.com/b", Tests: []*Test{
+			{Name: "TestC", Status: Skipped},
+			{Name: "TestD"},
+		}},
+	}
+	runTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	labels := []Property{{Name: "job", Value: "ci\n"}}
+
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, suites, runTime, labels, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	checkPrometheus(t, out)
+	for _, line := range []string{
+		`go_tests_total{package="example.com/a",status="pass",job="ci\n"} 2`,
+		`go_tests_total{package="example.com/a",status="fail",job="ci\n"} 1`,
+		`go_tests_total{package="example.com/b",status="other",job="ci\n"} 1`,
+		`go_test_duration_seconds{package="example.com/a",job="ci\n"} 2`,
+		`go_test_run_timestamp_seconds{job="ci\n"} 1577934245`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("%s not in:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "go_test_case_duration_seconds") {
+		t.Fatalf("per test series:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := WritePrometheus(&buf, suites, runTime, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	out = buf.String()
+	checkPrometheus(t, out)
+	line := `go_test_case_duration_seconds{package="example.com/a",test="TestB/\"quoted\"\\",status="fail"} 0.25`
+	if !strings.Contains(out, line+"\n") {
+		t.Fatalf("%s not in:\n%s", line, out)
+	}
+
+	for _, bad := range []string{"status", "1st", "__name", "a-b"} {
+		if err := WritePrometheus(&buf, suites, runTime, []Property{{Name: bad}}, false); err == nil {
+			t.Fatalf("label %q: no error", bad)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ReadSkipAllowList reads test name patterns from r, one per line. Patterns
+// are "package/TestName" globs (see path.Match), empty lines and lines
+// starting with # are ignored.
+func ReadSkipAllowList(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for lnum := 1; scanner.Scan(); lnum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("%d: bad pattern %q: %s", lnum, line, err)
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// skipAllowed returns true if the full test name (package/TestName) matches
+// one of patterns
+func skipAllowed(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// disallowedSkips returns skipped tests in suites that are not in the allow
+// list
+func disallowedSkips(suites Suites, allow []string) []*Test {
+	var tests []*Test
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.Status != Skipped || skipAllowed(suite.Name+"/"+test.Name, allow) {
+				continue
+			}
+			tests = append(tests, test)
+		}
+	}
+	return tests
+}
+
+// FailSkipped marks skipped tests that are not in the allow list as failed,
+// with the skip reason as failure message. It returns the failed tests.
+func FailSkipped(suites Suites, allow []string) []*Test {
+	tests := disallowedSkips(suites, allow)
+	for _, test := range tests {
+		test.Status = Failed
+		test.FailureMessage = test.SkipReason
+		if test.FailureMessage == "" {
+			test.FailureMessage = "skipped"
+		}
+	}
+	return tests
+}
+
+// DisableSkipped marks skipped tests that are not in the allow list as
+// disabled. It returns the disabled tests.
+func DisableSkipped(suites Suites, allow []string) []*Test {
+	tests := disallowedSkips(suites, allow)
+	for _, test := range tests {
+		test.Disabled = true
+	}
+	return tests
+}
@@ -0,0 +1,47 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadSkipAllowList(t *testing.T) {
+	patterns, err := ReadSkipAllowList(strings.NewReader("# comment\n\npkg/TestA\n  pkg/sub/Test*  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 2 || patterns[0] != "pkg/TestA" || patterns[1] != "pkg/sub/Test*" {
+		t.Fatalf("bad patterns: %q", patterns)
+	}
+
+	if _, err := ReadSkipAllowList(strings.NewReader("pkg/Test[\n")); err == nil {
+		t.Fatal("no error on bad pattern")
+	}
+}
+
+func TestFailSkipped(t *testing.T) {
+	suites := Suites{
+		{Name: "pkg", Tests: []*Test{
+			{Name: "TestA", Status: Skipped, SkipReason: "not yet"},
+			{Name: "TestB", Status: Skipped},
+			{Name: "TestC", Status: Passed},
+		}},
+	}
+
+	failed := FailSkipped(suites, []string{"pkg/TestB"})
+	if len(failed) != 1 || failed[0].Name != "TestA" {
+		t.Fatalf("bad failed tests: %v", failed)
+	}
+	if failed[0].Status != Failed || failed[0].FailureMessage != "not yet" {
+		t.Fatalf("bad failed test: %+v", failed[0])
+	}
+
+	disabled := DisableSkipped(suites, nil)
+	if len(disabled) != 1 || disabled[0].Name != "TestB" {
+		t.Fatalf("bad disabled tests: %v", disabled)
+	}
+	if suite := suites[0]; suite.NumSkipped() != 0 || suite.NumDisabled() != 1 || suite.NumOther() != 0 {
+		t.Fatalf("bad counts: skipped=%d, disabled=%d, other=%d",
+			suite.NumSkipped(), suite.NumDisabled(), suite.NumOther())
+	}
+}
@@ -0,0 +1,123 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// slackTextLimit is the maximal length of section block text
+	slackTextLimit = 3000
+	// slackMessageLimit is the maximal length of a test message in a section
+	slackMessageLimit = 200
+)
+
+// slackPayload is a Slack message with Block Kit blocks
+type slackPayload struct {
+	Text   string       `json:"text"` // notification fallback
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// slackBlock is a header, section or context block
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+// slackText is a text object
+type slackText struct {
+	Type string `json:"type"` // plain_text or mrkdwn
+	Text string `json:"text"`
+}
+
+// WriteSlack writes suites as a Slack Block Kit message payload: a header with
+// the overall status, a section per package with failed tests and a context
+// with the number of tests by status.
+func WriteSlack(w io.Writer, suites Suites) error {
+	stats := suites.Stats()
+	total := 0
+	for _, n := range stats {
+		total += n
+	}
+	failed := stats["fail"] + stats["error"]
+
+	title := fmt.Sprintf(":white_check_mark: All %d tests passed", total)
+	if failed > 0 {
+		title = fmt.Sprintf(":x: %d of %d tests failed", failed, total)
+	}
+	payload := slackPayload{
+		Text:   title,
+		Blocks: []slackBlock{{Type: "header", Text: &slackText{"plain_text", title}}},
+	}
+
+	for _, suite := range suites {
+		if text := slackFailures(suite); text != "" {
+			payload.Blocks = append(payload.Blocks, slackBlock{
+				Type: "section",
+				Text: &slackText{"mrkdwn", text},
+			})
+		}
+	}
+
+	counts := fmt.Sprintf("%d tests: %d passed, %d failed, %d errors, %d skipped",
+		total, stats["pass"], stats["fail"], stats["error"], stats["skip"])
+	payload.Blocks = append(payload.Blocks, slackBlock{
+		Type:     "context",
+		Elements: []slackText{{"mrkdwn", counts}},
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(payload)
+}
+
+// slackFailures returns the mrkdwn list of failed tests in suite (empty if
+// none failed), truncated to the section text limit
+func slackFailures(suite *Suite) string {
+	var lines []string
+	for _, test := range suite.Tests {
+		if test.isParentTest || (test.Status != Failed && test.Status != Errored) {
+			continue
+		}
+		line := fmt.Sprintf("• `%s`", slackEscape(test.Name))
+		if msg := slackMessage(test); msg != "" {
+			line += ": " + slackEscape(msg)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	text := fmt.Sprintf("*%s*", slackEscape(suite.Name))
+	for i, line := range lines {
+		more := fmt.Sprintf("\n… and %d more", len(lines)-i)
+		if len(text)+len(line)+1+len(more) > slackTextLimit {
+			return text + more
+		}
+		text += "\n" + line
+	}
+	return text
+}
+
+// slackMessage returns the first line of the test failure message, or of its
+// output if there's no failure message
+func slackMessage(test *Test) string {
+	msg := strings.TrimSpace(test.FailureMessage)
+	if msg == "" {
+		msg = strings.TrimSpace(test.Message)
+	}
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	if runes := []rune(msg); len(runes) > slackMessageLimit {
+		msg = string(runes[:slackMessageLimit]) + "…"
+	}
+	return msg
+}
+
+// slackEscape escapes the control characters of Slack mrkdwn
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
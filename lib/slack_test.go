@@ -0,0 +1,62 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteSlack(t *testing.T) {
+	suites := Suites{
+		{Name: "pkg/a", Tests: []*Test{
+			{Name: "TestOK", Status: Passed},
+			{Name: "TestBad", Status: Failed, Message: "a_test.go:10: got 1 <want 2>\nmore"},
+		}},
+		{Name: "pkg/b", Tests: []*Test{
+			{Name: "TestSkip", Status: Skipped},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSlack(&buf, suites); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Blocks []struct {
+			Type string `json:"type"`
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &payload); err != nil {
+		t.Fatalf("bad JSON: %s\n%s", err, buf.String())
+	}
+	var types []string
+	for _, block := range payload.Blocks {
+		types = append(types, block.Type)
+	}
+	if strings.Join(types, ",") != "header,section,context" {
+		t.Fatalf("bad blocks %v:\n%s", types, buf.String())
+	}
+	if text := payload.Blocks[0].Text.Text; !strings.Contains(text, "1 of 3 tests failed") {
+		t.Fatalf("bad header: %q", text)
+	}
+	text := payload.Blocks[1].Text.Text
+	if !strings.Contains(text, "*pkg/a*") || !strings.Contains(text, "`TestBad`: a_test.go:10: got 1 &lt;want 2&gt;") {
+		t.Fatalf("bad section: %q", text)
+	}
+	if strings.Contains(text, "TestOK") || strings.Contains(text, "more") {
+		t.Fatalf("section with passed test or full output: %q", text)
+	}
+
+	buf.Reset()
+	if err := WriteSlack(&buf, Suites{suites[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "All 1 tests passed") || strings.Contains(buf.String(), `"section"`) {
+		t.Fatalf("bad passing payload:\n%s", buf.String())
+	}
+}
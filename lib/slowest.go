@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Slowest returns the n tests with the longest elapsed time, slowest first.
+// Parent tests of subtests and tests with no elapsed time are not included,
+// ties are broken by name. There are none if n <= 0.
+func Slowest(suites Suites, n int) []*Test {
+	return slowest(suites, n, func(test *Test) bool { return !test.isParentTest })
+}
+
+// SlowestRollup is like Slowest but reports top level tests, subtests are
+// rolled up to their top level test
+func SlowestRollup(suites Suites, n int) []*Test {
+	return slowest(suites, n, func(test *Test) bool { return !strings.Contains(test.Name, "/") })
+}
+
+// slowest returns the n slowest tests that match pred (none if n <= 0)
+func slowest(suites Suites, n int, pred func(*Test) bool) []*Test {
+	if n <= 0 {
+		return nil
+	}
+
+	var tests []*Test
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if pred(test) && test.Elapsed() > 0 {
+				tests = append(tests, test)
+			}
+		}
+	}
+
+	sort.SliceStable(tests, func(i, j int) bool {
+		ei, ej := tests[i].Elapsed(), tests[j].Elapsed()
+		if ei != ej {
+			return ei > ej
+		}
+		return tests[i].Name < tests[j].Name
+	})
+	if n < len(tests) {
+		tests = tests[:n]
+	}
+	return tests
+}
+
+// AddSlowestProperties adds tests (as returned from Slowest) as
+// "slowest.<rank>" properties of their suites, the property value is the
+// test name and elapsed time in seconds
+func AddSlowestProperties(suites Suites, tests []*Test) {
+	rank := make(map[*Test]int)
+	for i, test := range tests {
+		rank[test] = i + 1
+	}
+
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if r, ok := rank[test]; ok {
+				suite.Properties = append(suite.Properties, Property{
+					Name:  fmt.Sprintf("slowest.%d", r),
+					Value: test.Name + " " + FormatSeconds(test.Elapsed()),
+				})
+			}
+		}
+	}
+}
+
+// ExceedingTime returns tests that took longer than max, and marks them with
+// a "time.exceeded" property. Parent tests of subtests, skipped tests and
+// package level results are not checked.
+func ExceedingTime(suites Suites, max time.Duration) []*Test {
+	var tests []*Test
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.isParentTest || test.isSynthetic || test.Status == Skipped {
+				continue
+			}
+			if test.Elapsed() > max {
+				test.Properties = append(test.Properties, Property{"time.exceeded", max.String()})
+				tests = append(tests, test)
+			}
+		}
+	}
+	return tests
+}
+
+// SuitesExceedingTime returns suites that took longer than max, and marks
+// them with a "time.exceeded" property
+func SuitesExceedingTime(suites Suites, max time.Duration) []*Suite {
+	var slow []*Suite
+	for _, suite := range suites {
+		if suite.Elapsed() > max {
+			suite.Properties = append(suite.Properties, Property{"time.exceeded", max.String()})
+			slow = append(slow, suite)
+		}
+	}
+	return slow
+}
+
+// slowTest is a JSON record of a slow test
+type slowTest struct {
+	Name    string  `json:"name"`
+	Package string  `json:"package"`
+	Time    float64 `json:"time"` // seconds
+}
+
+// WriteSlowest writes tests (as returned from Slowest) with their suite names
+// to w as a table, or as JSON if asJSON is true
+func WriteSlowest(w io.Writer, suites Suites, tests []*Test, asJSON bool) error {
+	packages := make(map[*Test]string)
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			packages[test] = suite.Name
+		}
+	}
+
+	if asJSON {
+		records := make([]slowTest, 0, len(tests))
+		for _, test := range tests {
+			records = append(records, slowTest{test.Name, packages[test], test.Elapsed().Seconds()})
+		}
+		return json.NewEncoder(w).Encode(records)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEST\tPACKAGE\tTIME")
+	for _, test := range tests {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", test.Name, packages[test], test.Elapsed())
+	}
+	return tw.Flush()
+}
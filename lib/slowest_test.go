@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowest(t *testing.T) {
+	suites := Suites{
+		{Name: "a", Tests: []*Test{
+			{Name: "TestFast", Time: "0.1"},
+			{Name: "TestSlow", Time: "3.0"},
+			{Name: "TestParent", Time: "5.0", isParentTest: true},
+			{Name: "TestParent/sub", Time: "5.0"},
+		}},
+		{Name: "b", Tests: []*Test{
+			{Name: "TestMedium", Time: "2.0"},
+			{Name: "TestSkipped", Time: "0.0"},
+		}},
+	}
+
+	slow := Slowest(suites, 3)
+	expected := []string{"TestParent/sub", "TestSlow", "TestMedium"}
+	if len(slow) != len(expected) {
+		t.Fatalf("bad number of tests: %d != %d", len(slow), len(expected))
+	}
+	for i, name := range expected {
+		if slow[i].Name != name {
+			t.Fatalf("%d: %s != %s", i, slow[i].Name, name)
+		}
+	}
+
+	if n := len(Slowest(suites, 10)); n != 4 {
+		t.Fatalf("bad number of tests: %d", n)
+	}
+	for _, n := range []int{0, -1} {
+		if slow := Slowest(suites, n); len(slow) != 0 {
+			t.Fatalf("%d: got %d tests", n, len(slow))
+		}
+		if slow := SlowestRollup(suites, n); len(slow) != 0 {
+			t.Fatalf("%d: got %d rollup tests", n, len(slow))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSlowest(&buf, suites, Slowest(suites, 1), true); err != nil {
+		t.Fatal(err)
+	}
+	var records []slowTest
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0] != (slowTest{"TestParent/sub", "a", 5}) {
+		t.Fatalf("bad JSON output: %s", buf.String())
+	}
+}
+
+func TestSlowestRollup(t *testing.T) {
+	suites := Suites{
+		{Name: "a", Tests: []*Test{
+			{Name: "TestB", Time: "1.0"},
+			{Name: "TestA", Time: "1.0"},
+			{Name: "TestParent", Time: "5.0", isParentTest: true},
+			{Name: "TestParent/sub", Time: "5.0"},
+			{Name: "TestZero", Time: "0.00"},
+		}},
+	}
+
+	slow := SlowestRollup(suites, 10)
+	var names []string
+	for _, test := range slow {
+		names = append(names, test.Name)
+	}
+	if strings.Join(names, ",") != "TestParent,TestA,TestB" {
+		t.Fatalf("bad slowest tests: %v", names)
+	}
+
+	AddSlowestProperties(suites, slow[:2])
+	props := suites[0].Properties
+	if len(props) != 2 || props[0] != (Property{"slowest.2", "TestA 1.000"}) || props[1].Name != "slowest.1" {
+		t.Fatalf("bad properties: %v", props)
+	}
+}
+
+func TestExceedingTime(t *testing.T) {
+	suites := Suites{
+		{Name: "a", Time: "3.0", Tests: []*Test{
+			{Name: "TestEqual", Time: "1.0", Status: Passed},
+			{Name: "TestOver", Time: "1.001", Status: Failed},
+			{Name: "TestSkipped", Time: "2.0", Status: Skipped},
+			{Name: "TestParent", Time: "2.0", Status: Passed, isParentTest: true},
+			{Name: "TestParent/sub", Time: "2.0", Status: Passed},
+		}},
+		{Name: "b", Time: "1.0"},
+	}
+
+	slow := ExceedingTime(suites, time.Second)
+	if len(slow) != 2 || slow[0].Name != "TestOver" || slow[1].Name != "TestParent/sub" {
+		t.Fatalf("bad slow tests: %v", slow)
+	}
+	if props := slow[0].Properties; len(props) != 1 || props[0] != (Property{"time.exceeded", "1s"}) {
+		t.Fatalf("bad properties: %v", props)
+	}
+
+	slowSuites := SuitesExceedingTime(suites, time.Second)
+	if len(slowSuites) != 1 || slowSuites[0].Name != "a" {
+		t.Fatalf("bad slow suites: %v", slowSuites)
+	}
+}
@@ -0,0 +1,255 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TestSummary is summary statistics of a test run
+type TestSummary struct {
+	Count    int
+	Pass     int
+	Fail     int
+	Error    int
+	Skip     int
+	Disabled int // Skipped tests reported as disabled (not counted in Skip)
+	Other    int // Unknown status
+
+	// Elapsed time statistics of tests that ran (skipped tests are ignored)
+	MeanElapsed   time.Duration
+	MedianElapsed time.Duration
+	P95Elapsed    time.Duration
+	MaxElapsed    time.Duration
+	TotalElapsed  time.Duration
+}
+
+// Summary returns summary statistics of tests in suites. Parent tests of
+// subtests are not counted.
+func Summary(suites Suites) TestSummary {
+	var summary TestSummary
+	var times []time.Duration
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.isParentTest {
+				continue
+			}
+			summary.Count++
+			switch test.Status {
+			case Passed:
+				summary.Pass++
+			case Failed:
+				summary.Fail++
+			case Errored:
+				summary.Error++
+			case Skipped:
+				if test.Disabled {
+					summary.Disabled++
+				} else {
+					summary.Skip++
+				}
+				continue
+			default:
+				summary.Other++
+			}
+			times = append(times, test.Elapsed())
+		}
+	}
+
+	if len(times) == 0 {
+		return summary
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	for _, t := range times {
+		summary.TotalElapsed += t
+	}
+	n := len(times)
+	summary.MeanElapsed = summary.TotalElapsed / time.Duration(n)
+	if n%2 == 1 {
+		summary.MedianElapsed = times[n/2]
+	} else {
+		summary.MedianElapsed = (times[n/2-1] + times[n/2]) / 2
+	}
+	// nearest rank
+	summary.P95Elapsed = times[(95*n+99)/100-1]
+	summary.MaxElapsed = times[n-1]
+	return summary
+}
+
+// ANSI color escape codes
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// suiteColor returns the ANSI color of suite summary line
+func suiteColor(suite *Suite) string {
+	switch {
+	case suite.NumFailed() > 0 || suite.NumErrors() > 0:
+		return colorRed
+	case suite.NumSkipped() > 0:
+		return colorYellow
+	}
+	return colorGreen
+}
+
+// WriteSummary writes a table with number of tests, failures, errors, skips
+// and elapsed time per suite to w. If color is true, suite lines are colored
+// with ANSI escape codes: red if there are failures, yellow if there are
+// skipped tests and green otherwise.
+func WriteSummary(w io.Writer, suites Suites, color bool) error {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tTESTS\tFAILURES\tERRORS\tSKIPPED\tTIME")
+	for _, suite := range suites {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", suite.Name, suite.Len(),
+			suite.NumFailed(), suite.NumErrors(), suite.NumSkipped(), suite.Elapsed())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Color after formatting, escape codes break tabwriter alignment
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, line := range lines {
+		if color && i > 0 && i <= len(suites) {
+			line = suiteColor(suites[i-1]) + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSummaryLine writes a one line summary of suites to w: the number of
+// tests by status (the same as the XML report totals), packages and the total
+// elapsed time, followed by the names (package/test) of up to maxFailed failed
+// or errored tests (all if maxFailed is negative)
+func WriteSummaryLine(w io.Writer, suites Suites, maxFailed int) error {
+	stats := suites.Stats()
+	var elapsed time.Duration
+	for _, suite := range suites {
+		elapsed += suite.Elapsed()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "go2xunit: %d tests, %d failed, %d errors, %d skipped in %d packages (%s)\n",
+		statsTotal(stats), stats["fail"], stats["error"], stats["skip"], len(suites), elapsed)
+
+	failed := failedTests(suites)
+	for i, test := range failed {
+		if i == maxFailed {
+			fmt.Fprintf(&buf, "  and %d more\n", len(failed)-i)
+			break
+		}
+		status := "FAIL"
+		if test.status == Errored {
+			status = "ERROR"
+		}
+		fmt.Fprintf(&buf, "  %s %s\n", status, test.name)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// statsTotal returns the number of tests in stats (see Suites.Stats)
+func statsTotal(stats map[string]int) int {
+	total := 0
+	for _, n := range stats {
+		total += n
+	}
+	return total
+}
+
+// failedTest is a failed or errored test in failedTests
+type failedTest struct {
+	name   string // package/test
+	status Status
+}
+
+// failedTests returns the failed or errored tests in suites, parents of
+// subtests are omitted
+func failedTests(suites Suites) []failedTest {
+	var failed []failedTest
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.isParentTest || (test.Status != Failed && test.Status != Errored) {
+				continue
+			}
+			name := test.Name
+			if suite.Name != "" {
+				name = suite.Name + "/" + name
+			}
+			failed = append(failed, failedTest{name, test.Status})
+		}
+	}
+	return failed
+}
+
+// failedNames returns the names (package/test) of failed or errored tests,
+// parents of subtests are omitted
+func failedNames(suites Suites) []string {
+	var names []string
+	for _, test := range failedTests(suites) {
+		names = append(names, test.name)
+	}
+	return names
+}
+
+// RunSummary is the summary of a run as JSON (e.g. the -webhook payload)
+type RunSummary struct {
+	Tests    int     `json:"tests"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	Errors   int     `json:"errors"`
+	Skipped  int     `json:"skipped"`
+	Packages int     `json:"packages"`
+	Elapsed  float64 `json:"elapsed"` // Total elapsed time of packages in seconds
+	// Failures are the names (package/test) of up to maxFailed failed tests
+	Failures     []string          `json:"failures"`
+	MoreFailures int               `json:"more_failures"` // Failed tests not in Failures
+	Properties   map[string]string `json:"properties,omitempty"`
+}
+
+// NewRunSummary returns the RunSummary of suites with up to maxFailed failed
+// test names (all if maxFailed is negative) and props
+func NewRunSummary(suites Suites, maxFailed int, props []Property) RunSummary {
+	stats := suites.Stats()
+	run := RunSummary{
+		Tests:    statsTotal(stats),
+		Passed:   stats["pass"],
+		Failed:   stats["fail"],
+		Errors:   stats["error"],
+		Skipped:  stats["skip"],
+		Packages: len(suites),
+		Failures: failedNames(suites),
+	}
+	var elapsed time.Duration
+	for _, suite := range suites {
+		elapsed += suite.Elapsed()
+	}
+	run.Elapsed = elapsed.Seconds()
+	if run.Failures == nil {
+		run.Failures = []string{}
+	}
+	if maxFailed >= 0 && len(run.Failures) > maxFailed {
+		run.MoreFailures = len(run.Failures) - maxFailed
+		run.Failures = run.Failures[:maxFailed]
+	}
+	if len(props) > 0 {
+		run.Properties = make(map[string]string, len(props))
+		for _, prop := range props {
+			run.Properties[prop.Name] = prop.Value
+		}
+	}
+	return run
+}
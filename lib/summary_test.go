@@ -0,0 +1,167 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSummary(t *testing.T) {
+	filename := "../_data/in/gotest-durations.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	summary := Summary(suites)
+	if summary.Count != 11 || summary.Pass != 9 || summary.Fail != 1 || summary.Skip != 1 {
+		t.Fatalf("bad counts: %+v", summary)
+	}
+
+	near := func(d, expected time.Duration) bool {
+		diff := d - expected
+		return diff < time.Millisecond && diff > -time.Millisecond
+	}
+	if !near(summary.MedianElapsed, 55*time.Millisecond) {
+		t.Fatalf("bad median: %s", summary.MedianElapsed)
+	}
+	if !near(summary.P95Elapsed, 500*time.Millisecond) {
+		t.Fatalf("bad p95: %s", summary.P95Elapsed)
+	}
+	if !near(summary.MaxElapsed, 500*time.Millisecond) {
+		t.Fatalf("bad max: %s", summary.MaxElapsed)
+	}
+	if !near(summary.TotalElapsed, 950*time.Millisecond) {
+		t.Fatalf("bad total: %s", summary.TotalElapsed)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/ok", Time: "1.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+			{Name: "TestB", Status: Passed},
+		}},
+		{Name: "example.com/bad", Time: "0.2", Tests: []*Test{
+			{Name: "TestA", Status: Failed},
+			{Name: "TestB", Status: Errored},
+			{Name: "TestC", Status: Skipped},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, suites, false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("bad summary:\n%s", buf.String())
+	}
+	expected := [][]string{
+		{"example.com/ok", "2", "0", "0", "0", "1.5s"},
+		{"example.com/bad", "3", "1", "1", "1", "200ms"},
+	}
+	for i, fields := range expected {
+		got := strings.Fields(lines[i+1])
+		if strings.Join(got, " ") != strings.Join(fields, " ") {
+			t.Fatalf("line %d: %v != %v", i+1, got, fields)
+		}
+	}
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Fatal("color codes without color")
+	}
+
+	buf.Reset()
+	if err := WriteSummary(&buf, suites, true); err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasPrefix(lines[1], colorGreen) || !strings.HasPrefix(lines[2], colorRed) {
+		t.Fatalf("bad colors: %q", lines)
+	}
+}
+
+func TestWriteSummaryLine(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/ok", Time: "1.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+			{Name: "TestB", Status: Skipped},
+		}},
+		{Name: "example.com/bad", Time: "0.2", Tests: []*Test{
+			{Name: "TestA", Status: Failed, isParentTest: true},
+			{Name: "TestA/sub", Status: Failed},
+			{Name: "TestB", Status: Errored},
+			{Name: "TestC", Status: Failed},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSummaryLine(&buf, suites, 2); err != nil {
+		t.Fatal(err)
+	}
+	expected := "go2xunit: 6 tests, 3 failed, 1 errors, 1 skipped in 2 packages (1.7s)\n" +
+		"  FAIL example.com/bad/TestA/sub\n" +
+		"  ERROR example.com/bad/TestB\n" +
+		"  and 1 more\n"
+	if buf.String() != expected {
+		t.Fatalf("bad summary:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := WriteSummaryLine(&buf, suites, -1); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 {
+		t.Fatalf("not all failures listed:\n%s", buf.String())
+	}
+
+	// Same totals as the XML report, nested parents are not listed
+	parsed, err := loadGotest("../_data/in/gotest-subtests-mixed.out", t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := newTestResults(parsed, time.Now())
+	buf.Reset()
+	if err := WriteSummaryLine(&buf, parsed, -1); err != nil {
+		t.Fatal(err)
+	}
+	expected = fmt.Sprintf("go2xunit: %d tests, %d failed, %d errors, %d skipped in 1 packages (41ms)\n",
+		results.Len, results.NumFailed, results.NumErrors, results.NumSkipped+results.NumDisabled) +
+		"  FAIL example.com/mixed/TestTable/small/one\n" +
+		"  FAIL example.com/mixed/TestTable/large/big\n"
+	if buf.String() != expected {
+		t.Fatalf("bad summary:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestNewRunSummary(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/ok", Time: "1.5", Tests: []*Test{
+			{Name: "TestA", Status: Passed},
+			{Name: "TestB", Status: Skipped},
+		}},
+		{Name: "example.com/bad", Time: "0.5", Tests: []*Test{
+			{Name: "TestA", Status: Failed},
+			{Name: "TestB", Status: Errored},
+			{Name: "TestC", Status: Failed},
+		}},
+	}
+
+	run := NewRunSummary(suites, 2, []Property{{Name: "job", Value: "ci"}})
+	if run.Tests != 5 || run.Passed != 1 || run.Failed != 2 || run.Errors != 1 || run.Skipped != 1 ||
+		run.Packages != 2 || run.Elapsed != 2 {
+		t.Fatalf("bad counts: %+v", run)
+	}
+	if strings.Join(run.Failures, ",") != "example.com/bad/TestA,example.com/bad/TestB" || run.MoreFailures != 1 {
+		t.Fatalf("bad failures: %v (%d more)", run.Failures, run.MoreFailures)
+	}
+	if run.Properties["job"] != "ci" {
+		t.Fatalf("bad properties: %v", run.Properties)
+	}
+
+	if run = NewRunSummary(suites, -1, nil); len(run.Failures) != 3 || run.MoreFailures != 0 || run.Properties != nil {
+		t.Fatalf("all failures: %+v", run)
+	}
+}
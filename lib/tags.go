@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// UntaggedTag is the tag of packages that are not in the tag map of
+// GroupByTag
+const UntaggedTag = "untagged"
+
+// XMLTaggedTemplate is the template of WriteTaggedXML, a <testsuites> element
+// of every tag in a <testsuites> root element
+const XMLTaggedTemplate = `
+<testsuites{{with $.Namespace}} xmlns="{{. | escape}}"{{end}}>
+{{range .Groups}}<testsuites name="{{.Tag | escape}}" tests="{{.Results.Len}}" failures="{{.Results.NumFailed}}" errors="{{.Results.NumErrors}}" skipped="{{.Results.NumSkipped}}"{{if .Results.NumDisabled}} disabled="{{.Results.NumDisabled}}"{{end}} time="{{.Results.Time | duration}}">{{template "suites" .Results}}</testsuites>
+{{end}}</testsuites>
+`
+
+// ReadTagMap reads a JSON object of package path to tag from rd
+func ReadTagMap(rd io.Reader) (map[string]string, error) {
+	var tags map[string]string
+	if err := json.NewDecoder(rd).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("bad tag map: %s", err)
+	}
+	return tags, nil
+}
+
+// GroupByTag returns suites grouped by the tag of their package in tagMap,
+// suites of packages that are not in tagMap are under UntaggedTag
+func GroupByTag(suites Suites, tagMap map[string]string) map[string]Suites {
+	groups := make(map[string]Suites)
+	for _, suite := range suites {
+		tag, ok := tagMap[suite.Name]
+		if !ok {
+			tag = UntaggedTag
+		}
+		groups[tag] = append(groups[tag], suite)
+	}
+	return groups
+}
+
+// taggedResults is a tag group of XMLTaggedTemplate
+type taggedResults struct {
+	Tag     string
+	Results *TestResults
+}
+
+// WriteTaggedXML writes XML of suites grouped by tag (see GroupByTag) to w,
+// every tag is a <testsuites name="tag"> element. Tags are sorted by name
+// with UntaggedTag last.
+func WriteTaggedXML(w io.Writer, groups map[string]Suites, testTime time.Time) error {
+	if len(groups) == 0 {
+		return fmt.Errorf("no suites")
+	}
+	tags := make([]string, 0, len(groups))
+	for tag := range groups {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if (tags[i] == UntaggedTag) != (tags[j] == UntaggedTag) {
+			return tags[j] == UntaggedTag
+		}
+		return tags[i] < tags[j]
+	})
+
+	data := struct {
+		Namespace string
+		Groups    []taggedResults
+	}{Namespace: Options.XMLNamespace}
+	for _, tag := range tags {
+		data.Groups = append(data.Groups, taggedResults{tag, newTestResults(groups[tag], testTime)})
+	}
+
+	t, err := newXMLTemplate().Parse(`{{define "suites"}}` + XUnitTemplate + `{{end}}`)
+	if err == nil {
+		t, err = t.Parse(xml.Header + XMLTaggedTemplate)
+	}
+	if err != nil {
+		return fmt.Errorf("error in parse: %v", err)
+	}
+	if err := t.Execute(w, data); err != nil {
+		return fmt.Errorf("error in execute: %v", err)
+	}
+	return nil
+}
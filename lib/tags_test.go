@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupByTag(t *testing.T) {
+	suites := Suites{
+		{Name: "pkg/a", Tests: []*Test{{Name: "TestA", Status: Passed}}},
+		{Name: "pkg/b", Tests: []*Test{{Name: "TestB", Status: Failed}}},
+		{Name: "pkg/c", Tests: []*Test{{Name: "TestC", Status: Passed}}},
+	}
+	tagMap, err := ReadTagMap(strings.NewReader(`{"pkg/a": "unit", "pkg/b": "integration", "pkg/x": "unit"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := GroupByTag(suites, tagMap)
+	if len(groups) != 3 || groups["unit"][0] != suites[0] || groups["integration"][0] != suites[1] ||
+		groups[UntaggedTag][0] != suites[2] {
+		t.Fatalf("bad groups: %v", groups)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTaggedXML(&buf, groups, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var root struct {
+		Groups []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+			Suites   []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"testsuite"`
+		} `xml:"testsuites"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &root); err != nil {
+		t.Fatalf("bad XML: %s\n%s", err, buf.String())
+	}
+
+	expected := []struct{ tag, pkg string }{{"integration", "pkg/b"}, {"unit", "pkg/a"}, {UntaggedTag, "pkg/c"}}
+	if len(root.Groups) != len(expected) {
+		t.Fatalf("bad XML:\n%s", buf.String())
+	}
+	for i, group := range root.Groups {
+		if group.Name != expected[i].tag || len(group.Suites) != 1 || group.Suites[0].Name != expected[i].pkg {
+			t.Fatalf("group %d: %+v, expected %s with %s", i, group, expected[i].tag, expected[i].pkg)
+		}
+	}
+	if root.Groups[0].Failures != 1 || root.Groups[1].Tests != 1 {
+		t.Fatalf("bad counts:\n%s", buf.String())
+	}
+
+	if _, err := ReadTagMap(strings.NewReader(`["pkg/a"]`)); err == nil {
+		t.Fatalf("no error for bad tag map")
+	}
+}
@@ -0,0 +1,422 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// === PAUSE TestA
+// === CONT  TestA
+var t2jPauseRE = regexp.MustCompile("^=== (PAUSE|CONT)[[:space:]]")
+
+// test2jsonEvent is an event of "go test -json" or "go tool test2json"
+// output, only the fields we use
+type test2jsonEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64 // seconds
+	Output  string
+
+	// ImportPath is the package of build events (go 1.24+), e.g.
+	// "example.com/foo [example.com/foo.test]"
+	ImportPath string
+	// FailedBuild is the ImportPath of the failed build of a package "fail"
+	FailedBuild string
+}
+
+// test2jsonPackage is the output of a package in test2json events
+type test2jsonPackage struct {
+	out bytes.Buffer
+	// Output of running top level tests, parallel tests output is
+	// interleaved so it's kept until the test ends
+	running  []string
+	testsOut map[string]*bytes.Buffer
+	// own is the output of every (sub)test by its name, without the test
+	// start and end lines
+	own map[string]*bytes.Buffer
+	// testStarts are the start times of (sub)tests by name
+	testStarts map[string]time.Time
+	// ran is set if any test of the package started
+	ran bool
+	// buildStarted is set by a "build" event of the package, buildImportPath
+	// is its ImportPath (if any)
+	buildStarted    bool
+	buildImportPath string
+
+	started, finished time.Time
+
+	// parsed are the suites of the package once it's parsed
+	parsed Suites
+	done   bool
+}
+
+// hasSubtests returns true if subtests of the test called name ran
+func (pkg *test2jsonPackage) hasSubtests(name string) bool {
+	for test := range pkg.testStarts {
+		if strings.HasPrefix(test, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds the output of event
+func (pkg *test2jsonPackage) add(event *test2jsonEvent) {
+	if !event.Time.IsZero() {
+		if pkg.started.IsZero() {
+			pkg.started = event.Time
+		}
+		pkg.finished = event.Time
+	}
+
+	if event.Test == "" {
+		pkg.flush()
+		pkg.out.WriteString(event.Output)
+		return
+	}
+
+	test := event.Test
+	if i := strings.Index(test, "/"); i >= 0 {
+		test = test[:i]
+	}
+	out, ok := pkg.testsOut[test]
+	if !ok {
+		out = &bytes.Buffer{}
+		pkg.testsOut[test] = out
+		pkg.running = append(pkg.running, test)
+	}
+	if !t2jPauseRE.MatchString(event.Output) {
+		out.WriteString(event.Output)
+	}
+
+	if event.Action == "run" {
+		pkg.testStarts[event.Test] = event.Time
+		pkg.ran = true
+	}
+
+	own, ok := pkg.own[event.Test]
+	if !ok {
+		own = &bytes.Buffer{}
+		pkg.own[event.Test] = own
+	}
+	if !isTestFraming(event.Output) {
+		own.WriteString(event.Output)
+	}
+
+	switch event.Action {
+	case "pass", "fail", "skip":
+		if event.Test == test {
+			pkg.out.Write(out.Bytes())
+			pkg.remove(test)
+		}
+	}
+}
+
+// buildFailed adds the build failure of package name with the compiler output
+// out (nil if none) before the package output. If no test ran and the output
+// has no "[build failed]" line, one is added.
+func (pkg *test2jsonPackage) buildFailed(name string, out *bytes.Buffer) {
+	var text bytes.Buffer
+	if out != nil {
+		// The text parser matches compiler output to the package by the
+		// header, the failed build may be of a dependency
+		fmt.Fprintf(&text, "# %s\n", name)
+		for _, line := range strings.SplitAfter(out.String(), "\n") {
+			if !gtBuildHeaderRE.MatchString(line) {
+				text.WriteString(line)
+			}
+		}
+	}
+	pkg.flush()
+	text.Write(pkg.out.Bytes())
+	if !pkg.ran && !gtBuildFailedRE.MatchString(lastLine(pkg.out.String())) {
+		fmt.Fprintf(&text, "FAIL\t%s [build failed]\n", name)
+	}
+	pkg.out = text
+}
+
+// lastLine returns the last non empty line of s
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	return lines[len(lines)-1]
+}
+
+// isTestFraming returns true if line is a test start, end, pause, continue or
+// name line
+func isTestFraming(line string) bool {
+	return gtStartRE.MatchString(line) || gtEndRE.MatchString(line) ||
+		gtNameRE.MatchString(line) || t2jPauseRE.MatchString(line)
+}
+
+// remove removes test from running tests
+func (pkg *test2jsonPackage) remove(test string) {
+	delete(pkg.testsOut, test)
+	for i, name := range pkg.running {
+		if name == test {
+			pkg.running = append(pkg.running[:i], pkg.running[i+1:]...)
+			return
+		}
+	}
+}
+
+// flush writes the output of tests that didn't end (e.g. panic or timeout)
+func (pkg *test2jsonPackage) flush() {
+	for _, test := range pkg.running {
+		pkg.out.Write(pkg.testsOut[test].Bytes())
+	}
+	pkg.running = nil
+	pkg.testsOut = make(map[string]*bytes.Buffer)
+}
+
+// ParseTest2JSON parses "go test -json" or "go tool test2json" output.
+// Output of a standalone test binary has no package and no package summary
+// line, its suite is called Options.PackageName. Unlike ParseGotest, it
+// handles parallel tests.
+func ParseTest2JSON(rd io.Reader, suitePrefix string) (Suites, error) {
+	return parseTest2JSON(rd, suitePrefix, defaultParseSettings())
+}
+
+// parseTest2JSON is ParseTest2JSON with settings
+func parseTest2JSON(rd io.Reader, suitePrefix string, settings parseSettings) (Suites, error) {
+	// Packages in order of appearance
+	var names []string
+	packages := make(map[string]*test2jsonPackage)
+	// Compiler output by build ImportPath
+	buildOut := make(map[string]*bytes.Buffer)
+	// stopped is set if parsing stopped after more than maxFailed failures
+	maxFailed := settings.maxFailures
+	failures := 0
+	stopped := false
+	var profile coverProfile
+
+	scanner := NewLineScanner(rd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] != '{' && profile.add(line) {
+			// Coverage profile along with the events
+			continue
+		}
+		if line[0] != '{' && Options.IgnoreBuildOutput {
+			// Not JSON (e.g. "go build" output or a test binary preamble)
+			continue
+		}
+		var event test2jsonEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return nil, fmt.Errorf("bad test2json event - %s", err)
+		}
+
+		switch event.Action {
+		case "build-output":
+			out, ok := buildOut[event.ImportPath]
+			if !ok {
+				out = &bytes.Buffer{}
+				buildOut[event.ImportPath] = out
+			}
+			out.WriteString(event.Output)
+			continue
+		case "build-fail":
+			continue
+		}
+
+		pkg, ok := packages[event.Package]
+		if !ok {
+			pkg = &test2jsonPackage{
+				testsOut:   make(map[string]*bytes.Buffer),
+				own:        make(map[string]*bytes.Buffer),
+				testStarts: make(map[string]time.Time),
+			}
+			packages[event.Package] = pkg
+			names = append(names, event.Package)
+		}
+		if event.Action == "build" {
+			pkg.buildStarted = true
+			pkg.buildImportPath = event.ImportPath
+			continue
+		}
+		isEnd := event.Test == "" && (event.Action == "pass" || event.Action == "fail")
+		switch {
+		case isEnd && event.FailedBuild != "":
+			pkg.buildFailed(event.Package, buildOut[event.FailedBuild])
+		case isEnd && event.Action == "fail" && pkg.buildStarted && !pkg.ran:
+			// Built but failed with no tests running, the build failed
+			pkg.buildFailed(event.Package, buildOut[pkg.buildImportPath])
+		}
+		pkg.add(&event)
+
+		if isEnd && event.Package == "" && Options.PackageName != "" {
+			// Test binary, add the summary line "go test" would print
+			status := "ok  "
+			if event.Action == "fail" {
+				status = "FAIL"
+			}
+			fmt.Fprintf(&pkg.out, "%s\t%s\t%.3fs\n", status, Options.PackageName, event.Elapsed)
+		}
+		if isEnd {
+			// Parsed as soon as it ends for Options.OnTestEnd and OnSuiteEnd
+			if err := pkg.parse(event.Package, suitePrefix, settings); err != nil {
+				return nil, err
+			}
+		}
+
+		if event.Action == "fail" && event.Test != "" && !pkg.hasSubtests(event.Test) {
+			// Parents fail with their subtests, only leaves are counted
+			failures++
+			if tooManyFailures(failures, maxFailed) {
+				stopped = true
+				break
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Output of a package that is still running (see ParseFrom) has no
+	// package summary line
+	var suites Suites
+	for _, name := range names {
+		pkg := packages[name]
+		if !pkg.done {
+			if err := pkg.parse(name, suitePrefix, settings); err != nil {
+				return nil, err
+			}
+		}
+		suites = append(suites, pkg.parsed...)
+	}
+	if stopped {
+		markStoppedEarly(suites)
+	}
+	markErrors(suites)
+	setFailureMessages(suites)
+	setArtifacts(suites)
+	setCoverProfiles(suites, profile, suitePrefix)
+	return suites, nil
+}
+
+// parse parses the output of package name, packages are parsed separately
+func (pkg *test2jsonPackage) parse(name, suitePrefix string, settings parseSettings) error {
+	pkg.flush()
+	// Failures are counted by the events
+	settings.maxFailures = -1
+	parsed, err := parseGotest(&pkg.out, suitePrefix, settings)
+	if err != nil {
+		return err
+	}
+
+	suiteName := suitePrefix + name
+	if name == "" {
+		suiteName = suitePrefix + Options.PackageName
+	}
+	for _, suite := range parsed {
+		if suite.Name == suitePrefix+Options.PackageName {
+			suite.Name = suiteName
+		}
+		if suite.Name != suiteName {
+			continue
+		}
+		suite.Started, suite.Finished = pkg.started, pkg.finished
+		pkg.setOutput(suite)
+		for _, test := range suite.Tests {
+			test.Started = pkg.testStarts[test.Name]
+		}
+	}
+	pkg.parsed = parsed
+	pkg.done = true
+	return nil
+}
+
+// setOutput sets the output of every test in suite to its own output. The
+// text parser can't tell parent test output between or after its subtests
+// from subtest output, the events can. Tests that didn't end (e.g. timed out)
+// and benchmarks keep the parsed output.
+func (pkg *test2jsonPackage) setOutput(suite *Suite) {
+	for _, test := range suite.Tests {
+		own, ok := pkg.own[test.Name]
+		if !ok || test.Incomplete || test.isSynthetic || test.Benchmark != nil {
+			continue
+		}
+
+		message := strings.TrimRight(own.String(), "\n")
+		test.Message = ""
+		test.AppendedErrorOutput = false
+		if message != "" && keepOutput(test, message) {
+			test.Message = message
+			limitOutput(test)
+			test.AppendedErrorOutput = gcTestErrorRE.MatchString(message)
+		}
+		if test.Status == Skipped {
+			test.SkipReason = skipReason(message)
+		}
+	}
+}
+
+// ParseFrom parses "go test -json" events in r from offset, for processing
+// output that is still being written. Only complete lines are parsed, up to
+// the last line where no top level test is running, so every test is parsed
+// in one call. It returns the parsed suites and the offset to parse the next
+// events from (offset if there are none yet).
+func ParseFrom(r io.ReadSeeker, offset int64) (Suites, int64, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, offset, err
+	}
+
+	end := 0
+	running := make(map[[2]string]bool)
+	for pos := 0; pos < len(data); {
+		i := bytes.IndexByte(data[pos:], '\n')
+		if i < 0 {
+			break // incomplete line
+		}
+		line := data[pos : pos+i]
+		pos += i + 1
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || (trimmed[0] != '{' && Options.IgnoreBuildOutput) {
+			if len(running) == 0 {
+				end = pos
+			}
+			continue
+		}
+
+		var event test2jsonEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, offset, fmt.Errorf("bad test2json event at offset %d - %s", offset+int64(pos-i-1), err)
+		}
+		if event.Test != "" && !strings.Contains(event.Test, "/") {
+			key := [2]string{event.Package, event.Test}
+			switch event.Action {
+			case "run":
+				running[key] = true
+			case "pass", "fail", "skip":
+				delete(running, key)
+			}
+		}
+		if len(running) == 0 {
+			end = pos
+		}
+	}
+	if end == 0 {
+		return nil, offset, nil
+	}
+
+	suites, err := ParseTest2JSON(bytes.NewReader(data[:end]), "")
+	if err != nil {
+		return nil, offset, err
+	}
+	return suites, offset + int64(end), nil
+}
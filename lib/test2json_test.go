@@ -0,0 +1,292 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTest2JSON(t *testing.T) {
+	defer func(name string) { Options.PackageName = name }(Options.PackageName)
+
+	cases := []struct {
+		file    string
+		pkgName string
+		suite   string
+	}{
+		{"test2json-gotest.out", "", "example.com/fz"},
+		{"test2json-binary.out", "example.com/fz", "example.com/fz"},
+		{"test2json-binary.out", "", ""},
+	}
+	for _, tc := range cases {
+		Options.PackageName = tc.pkgName
+		file, err := os.Open("../_data/in/" + tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suites, err := ParseTest2JSON(file, "")
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", tc.file, err)
+		}
+
+		if len(suites) != 1 {
+			t.Fatalf("%s: got %d suites instead of 1", tc.file, len(suites))
+		}
+		suite := suites[0]
+		if suite.Name != tc.suite || suite.Time == "" {
+			t.Fatalf("%s: bad suite %q (time %q)", tc.file, suite.Name, suite.Time)
+		}
+		if suite.Len() != 5 || suite.NumFailed() != 2 || suite.NumSkipped() != 1 {
+			t.Fatalf("%s: bad counts: %v", tc.file, Suites(suites).Stats())
+		}
+		if sub := findTest(suite, "TestSub/b"); sub == nil || !strings.Contains(sub.Message, "boom") || strings.Contains(sub.Message, "later") {
+			t.Fatalf("%s: bad TestSub/b: %+v", tc.file, sub)
+		}
+	}
+
+	if _, err := ParseTest2JSON(strings.NewReader("=== RUN TestA\n"), ""); err == nil {
+		t.Fatalf("parsed text as test2json")
+	}
+}
+
+func TestTotalTime(t *testing.T) {
+	defer func(total string) { Options.TotalTime = total }(Options.TotalTime)
+
+	file, err := os.Open("../_data/in/test2json-parallel.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	suites, err := ParseTest2JSON(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 2 || suites[0].Len()+suites[1].Len() != 8 {
+		t.Fatalf("bad parallel tests: %v", Suites(suites).Stats())
+	}
+
+	totalTime := func(format string) float64 {
+		Options.TotalTime = format
+		results := &TestResults{Suites: suites}
+		results.calcTotals()
+		value, err := strconv.ParseFloat(results.Time, 64)
+		if err != nil {
+			t.Fatalf("%s: bad time %q", format, results.Time)
+		}
+		return value
+	}
+
+	// 8 parallel tests of 0.2s in 2 parallel packages
+	sum, wall := totalTime("sum"), totalTime("wall")
+	if sum < 0.4 || sum > 0.8 {
+		t.Fatalf("bad sum of suite times: %v", sum)
+	}
+	if wall <= 0 || wall >= sum {
+		t.Fatalf("bad wall time: %v (sum %v)", wall, sum)
+	}
+}
+
+func TestParseTest2JSONSubtestOutput(t *testing.T) {
+	file, err := os.Open("../_data/in/test2json-subtests.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	suites, err := ParseTest2JSON(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string]string{
+		"TestParent":   "    attr_test.go:6: parent before\n    attr_test.go:8: parent between\n    attr_test.go:10: parent after",
+		"TestParent/a": "    attr_test.go:7: in a",
+		"TestParent/b": "    attr_test.go:9: b failed",
+		"TestOther":    "    attr_test.go:13: other",
+	}
+	for name, message := range messages {
+		test := findTest(suites[0], name)
+		if test == nil {
+			t.Fatalf("%s not found", name)
+		}
+		if test.Message != message {
+			t.Errorf("%s: message %q, expected %q", name, test.Message, message)
+		}
+	}
+	if test := findTest(suites[0], "TestParent/b"); test.FailureMessage != "b failed" {
+		t.Errorf("bad failure message: %q", test.FailureMessage)
+	}
+}
+
+func TestTest2JSONTimestamps(t *testing.T) {
+	defer func(loc *time.Location) { Options.Location = loc }(Options.Location)
+
+	// Shards in different zones
+	input := `{"Time":"2020-07-01T04:30:00.5+02:00","Action":"run","Package":"example.com/a","Test":"TestA"}
+{"Time":"2020-07-01T04:30:00.5+02:00","Action":"output","Package":"example.com/a","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Time":"2020-07-01T04:30:01+02:00","Action":"output","Package":"example.com/a","Test":"TestA","Output":"--- PASS: TestA (0.50s)\n"}
+{"Time":"2020-07-01T04:30:01+02:00","Action":"pass","Package":"example.com/a","Test":"TestA","Elapsed":0.5}
+{"Time":"2020-07-01T04:30:01+02:00","Action":"output","Package":"example.com/a","Output":"ok  \texample.com/a\t0.500s\n"}
+{"Time":"2020-06-30T22:30:00Z","Action":"run","Package":"example.com/b","Test":"TestB"}
+{"Time":"2020-06-30T22:30:00Z","Action":"output","Package":"example.com/b","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Time":"2020-06-30T22:30:00Z","Action":"output","Package":"example.com/b","Test":"TestB","Output":"--- PASS: TestB (0.00s)\n"}
+{"Time":"2020-06-30T22:30:00Z","Action":"output","Package":"example.com/b","Output":"ok  \texample.com/b\t0.001s\n"}
+`
+	suites, err := ParseTest2JSON(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(suites))
+	}
+
+	Options.Location = nil
+	cases := []struct {
+		t        time.Time
+		expected string
+	}{
+		{suites[0].Started, "2020-07-01T02:30:00.5Z"},
+		{suites[0].Tests[0].Started, "2020-07-01T02:30:00.5Z"},
+		{suites[1].Started, "2020-06-30T22:30:00Z"},
+		{time.Time{}, ""},
+	}
+	for _, tc := range cases {
+		if ts := FormatTimestamp(tc.t); ts != tc.expected {
+			t.Errorf("got %q, expected %q", ts, tc.expected)
+		}
+	}
+	if _, offset := suites[0].Started.Zone(); offset != 2*60*60 {
+		t.Fatalf("parsed time zone changed: %s", suites[0].Started)
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	Options.Location = loc
+	if ts := FormatTimestamp(suites[1].Started); ts != "2020-06-30T18:30:00-04:00" {
+		t.Fatalf("bad timestamp in %s: %q", loc, ts)
+	}
+}
+
+func TestTest2JSONBuildFailed(t *testing.T) {
+	file, err := os.Open("../_data/in/test2json-buildfailed.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	suites, err := ParseTest2JSON(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(suites))
+	}
+	bad := suites[0]
+	if bad.Name != "example.com/bld/bad" || bad.NumErrors() != 1 || !strings.Contains(bad.Tests[0].Message, "undefined: undefined") {
+		t.Fatalf("bad build failure: %+v %+v", bad, bad.Tests[0])
+	}
+	if suites[1].NumPassed() != 1 {
+		t.Fatalf("bad passing package: %+v", suites[1])
+	}
+
+	// Failed dependency build, without a "[build failed]" line
+	input := `{"ImportPath":"example.com/dep","Action":"build-output","Output":"# example.com/dep\n"}
+{"ImportPath":"example.com/dep","Action":"build-output","Output":"dep.go:3:1: syntax error\n"}
+{"ImportPath":"example.com/dep","Action":"build-fail"}
+{"Action":"start","Package":"example.com/a"}
+{"Action":"fail","Package":"example.com/a","Elapsed":0,"FailedBuild":"example.com/dep"}
+`
+	suites, err = ParseTest2JSON(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 1 || suites[0].Name != "example.com/a" || suites[0].NumErrors() != 1 {
+		t.Fatalf("bad dependency build failure: %+v", suites)
+	}
+	if msg := suites[0].Tests[0].Message; !strings.Contains(msg, "dep.go:3:1: syntax error") {
+		t.Fatalf("bad build output: %q", msg)
+	}
+
+	// "build" events, failed package with no tests running
+	file, err = os.Open("../_data/in/test2json-build-action.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	suites, err = ParseTest2JSON(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 2 {
+		t.Fatalf("got %d suites instead of 2", len(suites))
+	}
+	broken := suites[0]
+	if broken.Name != "example.com/bld/broken" || broken.NumErrors() != 1 || !strings.Contains(broken.Tests[0].Message, "undefined: missing") {
+		t.Fatalf("bad build failure: %+v", broken)
+	}
+	if suites[1].NumPassed() != 1 || suites[1].NumErrors() != 0 {
+		t.Fatalf("bad passing package: %+v", suites[1])
+	}
+}
+
+func TestParseFrom(t *testing.T) {
+	data, err := ioutil.ReadFile("../_data/in/test2json-gotest.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := ioutil.TempFile("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// First batch ends in the middle of TestSub, with half a line
+	half := bytes.Index(data, []byte(`"Test":"TestSub/b"`))
+	if _, err := file.Write(data[:half]); err != nil {
+		t.Fatal(err)
+	}
+	first, offset, err := ParseFrom(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset <= 0 || offset >= int64(half) {
+		t.Fatalf("bad offset %d", offset)
+	}
+
+	if _, err := file.Write(data[half:]); err != nil {
+		t.Fatal(err)
+	}
+	second, end, err := ParseFrom(file, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end != int64(len(data)) {
+		t.Fatalf("end offset %d, expected %d", end, len(data))
+	}
+
+	names := make(map[string]bool)
+	for i, suites := range []Suites{first, second} {
+		if len(suites) != 1 || suites[0].Name != "example.com/fz" || suites[0].Len() == 0 {
+			t.Fatalf("call %d: bad suites %v", i+1, suites)
+		}
+		for _, test := range suites[0].Tests {
+			if names[test.Name] {
+				t.Fatalf("%s parsed twice", test.Name)
+			}
+			names[test.Name] = true
+		}
+	}
+	if len(names) != 5 {
+		t.Fatalf("parsed %d tests, expected 5", len(names))
+	}
+
+	if suites, again, err := ParseFrom(file, end); err != nil || suites != nil || again != end {
+		t.Fatalf("no new events: %v %d %v", suites, again, err)
+	}
+}
@@ -0,0 +1,39 @@
+package lib
+
+import "time"
+
+// FormatTimestamp returns t as RFC 3339 in Options.Location (UTC if not set),
+// or "" if t is zero. Parsed times keep their original zone, it's changed
+// only on output.
+func FormatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	loc := Options.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(time.RFC3339Nano)
+}
+
+// NormalizeTimestamps converts the start and finish times of every suite and
+// test in s to UTC, so they are the same whatever the zone of the machine that
+// ran the tests
+func NormalizeTimestamps(s Suites) {
+	for _, suite := range s {
+		suite.Started = utc(suite.Started)
+		suite.Finished = utc(suite.Finished)
+	}
+	s.Walk(func(path []string, test *Test) error {
+		test.Started = utc(test.Started)
+		return nil
+	})
+}
+
+// utc returns t in UTC, a zero t is kept zero
+func utc(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC()
+}
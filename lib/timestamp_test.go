@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeTimestamps(t *testing.T) {
+	zone := time.FixedZone("UTC+3", 3*60*60)
+	started := time.Date(2020, 5, 1, 12, 0, 0, 0, zone)
+	suites := Suites{
+		{
+			Name:     "example.com/a",
+			Started:  started,
+			Finished: started.Add(time.Second),
+			Tests: []*Test{
+				{Name: "TestA", Started: started, isParentTest: true},
+				{Name: "TestA/sub", Started: started.Add(time.Millisecond)},
+				{Name: "TestB"},
+			},
+		},
+	}
+
+	NormalizeTimestamps(suites)
+	suite := suites[0]
+	for _, tm := range []time.Time{suite.Started, suite.Finished, suite.Tests[0].Started, suite.Tests[1].Started} {
+		if tm.Location() != time.UTC {
+			t.Fatalf("%v not in UTC", tm)
+		}
+	}
+	if !suite.Started.Equal(started) || suite.Started.Hour() != 9 {
+		t.Fatalf("bad suite start: %v", suite.Started)
+	}
+	if !suite.Tests[2].Started.IsZero() {
+		t.Fatalf("zero time changed: %v", suite.Tests[2].Started)
+	}
+}
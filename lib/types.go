@@ -1,5 +1,13 @@
 package lib
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Status is test status
 type Status int
 
@@ -9,14 +17,145 @@ const (
 	Failed
 	Skipped
 	Passed
+	Errored
 )
 
-// Test data structure
+// statusNames are the Stats keys of known statuses
+var statusNames = map[Status]string{
+	Failed:  "fail",
+	Skipped: "skip",
+	Passed:  "pass",
+	Errored: "error",
+}
+
+// otherStatus is the Stats key of tests with unknown status
+const otherStatus = "other"
+
+// StatusName returns the Stats key of status: "pass", "fail", "error", "skip"
+// or "other"
+func StatusName(status Status) string {
+	if name, ok := statusNames[status]; ok {
+		return name
+	}
+	return otherStatus
+}
+
+// BenchmarkResult is the metrics reported by a benchmark
+type BenchmarkResult struct {
+	N           int     `yaml:"n,omitempty"`
+	NsPerOp     float64 `yaml:"ns_per_op,omitempty"`
+	BytesPerOp  int64   `yaml:"bytes_per_op,omitempty"`
+	AllocsPerOp int64   `yaml:"allocs_per_op,omitempty"`
+	// Metrics are all the reported metrics by unit (e.g. "ns/op", "MB/s" or
+	// custom b.ReportMetric units)
+	Metrics map[string]float64 `yaml:"metrics,omitempty"`
+}
+
+// standardMetrics are the metrics that have BenchmarkResult fields
+var standardMetrics = map[string]bool{
+	"ns/op":     true,
+	"B/op":      true,
+	"allocs/op": true,
+}
+
+// ExtraMetrics returns the metrics that don't have a BenchmarkResult field
+// (e.g. "MB/s") as properties, sorted by unit
+func (b *BenchmarkResult) ExtraMetrics() []Property {
+	var props []Property
+	for unit, value := range b.Metrics {
+		if !standardMetrics[unit] {
+			props = append(props, Property{unit, strconv.FormatFloat(value, 'f', -1, 64)})
+		}
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	return props
+}
+
+// Test data structure. The yaml tags are the fields of WriteYAML, the time is
+// in seconds (see Test.MarshalYAML).
 type Test struct {
-	Name, Time, Message string
-	Status              Status
-	AppendedErrorOutput	bool
-	isParentTest		bool
+	Name                string `yaml:"name"`
+	Time                string `yaml:"-"`
+	Message             string `yaml:"message,omitempty"`
+	Status              Status `yaml:"status"`
+	AppendedErrorOutput bool   `yaml:"-"`
+	isParentTest        bool
+	isSynthetic         bool // Package level result (e.g. build failure)
+
+	// Benchmark is set for benchmark results (nil for regular tests)
+	Benchmark *BenchmarkResult `yaml:"benchmark,omitempty"`
+	// Attachments are absolute paths of files attached to the test (see
+	// WriteAttachments)
+	Attachments []string `yaml:"attachments,omitempty"`
+	// Artifacts are paths of files the test reported with "ARTIFACT: <path>"
+	// or "ARTIFACT: name=<name> path=<path>" output lines
+	Artifacts []string `yaml:"artifacts,omitempty"`
+	// Started is the time the test started, only known for "go test -json"
+	// input (zero otherwise)
+	Started time.Time `yaml:"started,omitempty"`
+	// Flaky is set if the test status changed between runs
+	Flaky bool `yaml:"flaky,omitempty"`
+	// RunCount is the number of times the test ran (e.g. "go test -count=3")
+	RunCount int `yaml:"runs,omitempty"`
+	// Incomplete is set if the test started but no result was recorded for it
+	// (panic, timeout or truncated input)
+	Incomplete bool `yaml:"incomplete,omitempty"`
+	// OutputTruncated is set if Message was truncated to
+	// Options.MaxOutputBytes
+	OutputTruncated bool `yaml:"output_truncated,omitempty"`
+	// SkipReason is the message given to t.Skip (empty if none)
+	SkipReason string `yaml:"skip_reason,omitempty"`
+	// NotRun is set for tests from SkeletonFromList that have no results
+	NotRun bool `yaml:"not_run,omitempty"`
+	// Disabled is set for skipped tests that are reported as disabled
+	// instead of skipped
+	Disabled bool `yaml:"disabled,omitempty"`
+	// FailureMessage is a one line summary of failed (or errored) test
+	// output
+	FailureMessage string `yaml:"failure_message,omitempty"`
+	// Properties are extra test properties
+	Properties []Property `yaml:"properties,omitempty"`
+}
+
+// Property is a name/value pair attached to a suite
+type Property struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// Kinds of test functions, see Test.Kind
+const (
+	TestKind      = "test"
+	BenchmarkKind = "benchmark"
+	ExampleKind   = "example"
+	FuzzKind      = "fuzz"
+)
+
+// Kind returns the kind of the test function by its name (subtests have the
+// kind of their top level test)
+func (t *Test) Kind() string {
+	name := t.Name
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+	switch {
+	case t.Benchmark != nil || strings.HasPrefix(name, "Benchmark"):
+		return BenchmarkKind
+	case strings.HasPrefix(name, "Example"):
+		return ExampleKind
+	case strings.HasPrefix(name, "Fuzz"):
+		return FuzzKind
+	}
+	return TestKind
+}
+
+// Elapsed returns the test time as a duration (0 if time is unknown)
+func (t *Test) Elapsed() time.Duration {
+	seconds, err := strconv.ParseFloat(t.Time, 64)
+	if err != nil {
+		return 0
+	}
+	return durationOf(seconds)
 }
 
 // Suite of tests (found in some unit testing frameworks)
@@ -25,6 +164,34 @@ type Suite struct {
 	Time   string
 	Status string
 	Tests  []*Test
+
+	// Coverage is statement coverage (0-1) reported by "go test -cover", only
+	// valid if HasCoverage is set
+	Coverage    float64
+	HasCoverage bool
+	// CoverProfile are the blocks of the package files in a coverage profile
+	// ("go test -coverprofile") found in the input
+	CoverProfile []CoverBlock
+
+	// ShuffleSeed is the seed of "go test -shuffle" (empty if not shuffled),
+	// it's also the ShuffleSeedProperty property
+	ShuffleSeed string
+
+	// Started and Finished are the times of the first and last events of the
+	// package, only known for "go test -json" input (zero otherwise)
+	Started, Finished time.Time
+
+	Properties []Property
+}
+
+// Elapsed returns the package elapsed time of the suite, it's 0 if unknown
+// (e.g. cached results)
+func (suite *Suite) Elapsed() time.Duration {
+	seconds, err := strconv.ParseFloat(suite.Time, 64)
+	if err != nil {
+		return 0
+	}
+	return durationOf(seconds)
 }
 
 // NumPassed return number of passed tests in the suite
@@ -32,9 +199,21 @@ func (suite *Suite) NumPassed() int {
 	return suite.numStatus(Passed)
 }
 
-// NumSkipped return number of skipped tests in suite
+// NumSkipped return number of skipped tests in suite (disabled tests are not
+// counted)
 func (suite *Suite) NumSkipped() int {
-	return suite.numStatus(Skipped)
+	return suite.numStatus(Skipped) - suite.NumDisabled()
+}
+
+// NumDisabled return number of disabled tests in suite
+func (suite *Suite) NumDisabled() int {
+	count := 0
+	for _, test := range suite.Tests {
+		if test.Status == Skipped && test.Disabled {
+			count++
+		}
+	}
+	return count
 }
 
 // NumFailed return number of failed tests in suite
@@ -42,6 +221,17 @@ func (suite *Suite) NumFailed() int {
 	return suite.numStatus(Failed)
 }
 
+// NumErrors return number of errored (panicked/crashed) tests in suite
+func (suite *Suite) NumErrors() int {
+	return suite.numStatus(Errored)
+}
+
+// NumOther return number of tests in suite with unknown status (e.g. tests
+// that never reported a result)
+func (suite *Suite) NumOther() int {
+	return suite.Len() - suite.NumPassed() - suite.numStatus(Skipped) - suite.NumFailed() - suite.NumErrors()
+}
+
 // numStatus returns the number of tests in status
 func (suite *Suite) numStatus(status Status) int {
 	count := 0
@@ -61,16 +251,129 @@ func (suite *Suite) Len() int {
 // Suites is a list of suites
 type Suites []*Suite
 
-// HasFailures return true is there's at least one failing suite
+// HasFailures return true is there's at least one failing (or errored) suite
 func (s Suites) HasFailures() bool {
 	for _, suite := range s {
-		if suite.NumFailed() > 0 {
+		if suite.NumFailed() > 0 || suite.NumErrors() > 0 {
 			return true
 		}
 	}
 	return false
 }
 
+// Stats returns the number of tests by status, with keys "pass", "fail",
+// "error", "skip" and "other" (for unknown status)
+func (s Suites) Stats() map[string]int {
+	stats := map[string]int{otherStatus: 0}
+	for _, name := range statusNames {
+		stats[name] = 0
+	}
+
+	s.Walk(func(path []string, test *Test) error {
+		name, ok := statusNames[test.Status]
+		if !ok {
+			name = otherStatus
+		}
+		stats[name]++
+		return nil
+	})
+	return stats
+}
+
+// Durations returns the total elapsed time of tests in suite by status, keys
+// are the same as in Stats. Parent tests of subtests are not counted.
+func (suite *Suite) Durations() map[string]time.Duration {
+	durations := map[string]time.Duration{otherStatus: 0}
+	for _, name := range statusNames {
+		durations[name] = 0
+	}
+
+	for _, test := range suite.Tests {
+		if test.isParentTest {
+			continue
+		}
+		name, ok := statusNames[test.Status]
+		if !ok {
+			name = otherStatus
+		}
+		durations[name] += test.Elapsed()
+	}
+	return durations
+}
+
+// AddDurationProperties adds the total elapsed time of tests by status as
+// "time.<status>" properties of every suite
+func (s Suites) AddDurationProperties() {
+	for _, suite := range s {
+		durations := suite.Durations()
+		for _, name := range []string{"pass", "fail", "error", "skip", otherStatus} {
+			suite.Properties = append(suite.Properties, Property{
+				Name:  "time." + name,
+				Value: FormatSeconds(durations[name]),
+			})
+		}
+	}
+}
+
+// PassRate returns the percent of passed tests out of passed and failed (or
+// errored) tests, skipped tests are not counted. It's 100 if no tests ran.
+func (s Suites) PassRate() float64 {
+	passed, failed := 0, 0
+	for _, suite := range s {
+		passed += suite.NumPassed()
+		failed += suite.NumFailed() + suite.NumErrors()
+	}
+	if passed+failed == 0 {
+		return 100
+	}
+	return 100 * float64(passed) / float64(passed+failed)
+}
+
+// CheckFailUnder returns an error if pass rate is below threshold percent
+func CheckFailUnder(s Suites, threshold int) error {
+	if rate := s.PassRate(); rate < float64(threshold) {
+		return fmt.Errorf("pass rate %.1f%% is below %d%%", rate, threshold)
+	}
+	return nil
+}
+
+// StoppedEarly returns true if parsing stopped after Options.MaxFailures
+// failures
+func (s Suites) StoppedEarly() bool {
+	for _, suite := range s {
+		if hasProperty(suite.Properties, StoppedEarlyProperty) {
+			return true
+		}
+	}
+	return false
+}
+
+// NumIncomplete returns the number of tests with no recorded result
+func (s Suites) NumIncomplete() int {
+	count := 0
+	for _, suite := range s {
+		for _, test := range suite.Tests {
+			if test.Incomplete {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// NumOutputTruncated returns the number of tests with truncated output
+func (s Suites) NumOutputTruncated() int {
+	count := 0
+	for _, suite := range s {
+		for _, test := range suite.Tests {
+			if test.OutputTruncated {
+				count++
+			}
+		}
+	}
+	return count
+}
+
 // SuiteStack is a stack of test suites
 type SuiteStack struct {
 	nodes []*Suite
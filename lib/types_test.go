@@ -1,6 +1,9 @@
 package lib
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestEmptySuite(t *testing.T) {
 	suite := Suite{}
@@ -79,3 +82,105 @@ func TestMultipleSuits(t *testing.T) {
 		}
 	})
 }
+
+func TestFailUnder(t *testing.T) {
+	suite := &Suite{}
+	for i := 0; i < 8; i++ {
+		suite.Tests = append(suite.Tests, &Test{Status: Passed})
+	}
+	suite.Tests = append(suite.Tests, &Test{Status: Failed}, &Test{Status: Errored})
+	suite.Tests = append(suite.Tests, &Test{Status: Skipped})
+	suites := Suites{suite}
+
+	if rate := suites.PassRate(); rate != 80 {
+		t.Fatalf("pass rate is %f, should be 80", rate)
+	}
+	if err := CheckFailUnder(suites, 85); err == nil {
+		t.Fatal("no error for 80% pass rate with threshold 85")
+	}
+	if err := CheckFailUnder(suites, 79); err != nil {
+		t.Fatalf("error for 80%% pass rate with threshold 79: %s", err)
+	}
+	if err := CheckFailUnder(Suites{}, 100); err != nil {
+		t.Fatalf("error for no tests: %s", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	suite := &Suite{Tests: []*Test{
+		{Status: Passed},
+		{Status: Failed},
+		{Status: Errored},
+		{Status: Errored},
+		{Status: Skipped},
+		{}, // No status
+		{Status: Status(42)},
+	}}
+	stats := Suites{suite}.Stats()
+
+	expected := map[string]int{"pass": 1, "fail": 1, "error": 2, "skip": 1, "other": 2}
+	if len(stats) != len(expected) {
+		t.Fatalf("bad stats keys: %v", stats)
+	}
+	for key, count := range expected {
+		if stats[key] != count {
+			t.Fatalf("%s: %d != %d", key, stats[key], count)
+		}
+	}
+	if n := suite.NumOther(); n != 2 {
+		t.Fatalf("NumOther is %d, should be 2", n)
+	}
+
+	if stats := (Suites{}).Stats(); len(stats) != len(expected) || stats["error"] != 0 {
+		t.Fatalf("bad empty stats: %v", stats)
+	}
+}
+
+func TestDurations(t *testing.T) {
+	suite := &Suite{Tests: []*Test{
+		{Status: Passed, Time: "1.5"},
+		{Status: Passed, Time: "0.5"},
+		{Status: Failed, Time: "3"},
+		{Status: Failed, Time: "4", isParentTest: true},
+		{Status: Skipped, Time: "0"},
+		{Time: "0.25"},
+	}}
+	durations := suite.Durations()
+
+	expected := map[string]time.Duration{
+		"pass":  2 * time.Second,
+		"fail":  3 * time.Second,
+		"error": 0,
+		"skip":  0,
+		"other": 250 * time.Millisecond,
+	}
+	for key, d := range expected {
+		if durations[key] != d {
+			t.Fatalf("%s: %s != %s", key, durations[key], d)
+		}
+	}
+
+	Suites{suite}.AddDurationProperties()
+	if len(suite.Properties) != 5 || suite.Properties[0] != (Property{"time.pass", "2.000"}) {
+		t.Fatalf("bad properties: %v", suite.Properties)
+	}
+}
+
+func TestTestKind(t *testing.T) {
+	cases := []struct {
+		test *Test
+		kind string
+	}{
+		{&Test{Name: "TestAdd"}, TestKind},
+		{&Test{Name: "TestAdd/ExampleSub"}, TestKind},
+		{&Test{Name: "BenchmarkJoin"}, BenchmarkKind},
+		{&Test{Name: "Join", Benchmark: &BenchmarkResult{}}, BenchmarkKind},
+		{&Test{Name: "ExampleJoin_second"}, ExampleKind},
+		{&Test{Name: "FuzzReverse/seed#0"}, FuzzKind},
+	}
+	for _, tc := range cases {
+		if kind := tc.test.Kind(); kind != tc.kind {
+			t.Fatalf("%s: kind %q, expected %q", tc.test.Name, kind, tc.kind)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// test2jsonActions are the known actions of test2json events
+var test2jsonActions = map[string]bool{
+	"start":        true,
+	"run":          true,
+	"pause":        true,
+	"cont":         true,
+	"output":       true,
+	"bench":        true,
+	"pass":         true,
+	"fail":         true,
+	"skip":         true,
+	"build":        true,
+	"build-output": true,
+	"build-fail":   true,
+}
+
+// IsTest2JSON returns true if line is a test2json event with a known action,
+// which tells "go test -json" output from test output starting with JSON logs
+func IsTest2JSON(line []byte) bool {
+	var event test2jsonEvent
+	return json.Unmarshal(line, &event) == nil && test2jsonActions[event.Action]
+}
+
+// Validation is the result of ValidateTest2JSON
+type Validation struct {
+	Records  int            // Number of records (non empty lines)
+	Actions  map[string]int // Number of records by action
+	Packages int            // Number of packages
+	Tests    int            // Number of started tests
+	// EmptyPackages are packages with no tests, they are not problems
+	EmptyPackages []string
+	// Problems are the reasons the stream is not well formed, by line
+	Problems []ValidationProblem
+}
+
+// ValidationProblem is a problem found by ValidateTest2JSON
+type ValidationProblem struct {
+	Line    int
+	Package string
+	Test    string
+	Message string
+}
+
+func (p ValidationProblem) String() string {
+	where := strings.TrimSpace(p.Package + " " + p.Test)
+	if where == "" {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", p.Line, where, p.Message)
+}
+
+// OK returns true if the stream is well formed
+func (v *Validation) OK() bool {
+	return len(v.Problems) == 0
+}
+
+// validatePackage is the state of a package in ValidateTest2JSON
+type validatePackage struct {
+	running map[string]int // Start line of running tests
+	order   []string       // Running tests in start order
+	tests   int
+	endLine int // Line of the package pass/fail/skip record, 0 if none
+}
+
+// ValidateTest2JSON checks that rd is a well formed "go test -json" stream:
+// every record is JSON with a known action and a timestamp, every test that
+// started has a result (and doesn't start again before it), and every package
+// ends with a pass, fail or skip record. Problems in the stream are reported
+// in the Validation, the error is only for reading rd.
+func ValidateTest2JSON(rd io.Reader) (*Validation, error) {
+	v := &Validation{Actions: make(map[string]int)}
+	var names []string
+	packages := make(map[string]*validatePackage)
+	zeroTimes, firstZeroTime := 0, 0
+
+	scanner := NewLineScanner(rd)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		v.Records++
+
+		var event test2jsonEvent
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			v.Problems = append(v.Problems, ValidationProblem{Line: line, Message: "bad JSON record - " + err.Error()})
+			continue
+		}
+		v.Actions[event.Action]++
+		if event.Time.IsZero() {
+			zeroTimes++
+			if firstZeroTime == 0 {
+				firstZeroTime = line
+			}
+		}
+		problem := func(format string, args ...interface{}) {
+			v.Problems = append(v.Problems, ValidationProblem{
+				Line:    line,
+				Package: event.Package,
+				Test:    event.Test,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if !test2jsonActions[event.Action] {
+			problem("unknown action %q", event.Action)
+			continue
+		}
+		if event.Action == "build-output" || event.Action == "build-fail" {
+			continue
+		}
+
+		pkg, ok := packages[event.Package]
+		if !ok {
+			pkg = &validatePackage{running: make(map[string]int)}
+			packages[event.Package] = pkg
+			names = append(names, event.Package)
+		}
+		if pkg.endLine > 0 && event.Action != "output" {
+			problem("%s record after the package ended on line %d", event.Action, pkg.endLine)
+			continue
+		}
+
+		switch event.Action {
+		case "run":
+			if start, ok := pkg.running[event.Test]; ok {
+				problem("test started again before it ended (started on line %d)", start)
+				continue
+			}
+			pkg.running[event.Test] = line
+			pkg.order = append(pkg.order, event.Test)
+			pkg.tests++
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				pkg.endLine = line
+				continue
+			}
+			if _, ok := pkg.running[event.Test]; !ok {
+				problem("%s record of a test that didn't start", event.Action)
+				continue
+			}
+			delete(pkg.running, event.Test)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if zeroTimes > 0 {
+		v.Problems = append(v.Problems, ValidationProblem{
+			Line:    firstZeroTime,
+			Message: fmt.Sprintf("%d record(s) with no timestamp (use test2json -t)", zeroTimes),
+		})
+	}
+	for _, name := range names {
+		pkg := packages[name]
+		v.Packages++
+		v.Tests += pkg.tests
+		for _, test := range pkg.order {
+			if start, ok := pkg.running[test]; ok {
+				v.Problems = append(v.Problems, ValidationProblem{start, name, test, "test has no pass, fail or skip record"})
+			}
+		}
+		if pkg.endLine == 0 {
+			v.Problems = append(v.Problems, ValidationProblem{line, name, "", "package has no pass, fail or skip record"})
+		} else if pkg.tests == 0 {
+			v.EmptyPackages = append(v.EmptyPackages, name)
+		}
+	}
+	sort.SliceStable(v.Problems, func(i, j int) bool { return v.Problems[i].Line < v.Problems[j].Line })
+	return v, nil
+}
+
+// WriteValidation writes v as text: the record, package and test counts, the
+// problems and if the stream is well formed
+func WriteValidation(w io.Writer, v *Validation) error {
+	var actions []string
+	for action, n := range v.Actions {
+		actions = append(actions, fmt.Sprintf("%s=%d", action, n))
+	}
+	sort.Strings(actions)
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "records: %d (%s)\n", v.Records, strings.Join(actions, " "))
+	fmt.Fprintf(&buf, "packages: %d, tests: %d\n", v.Packages, v.Tests)
+	if len(v.EmptyPackages) > 0 {
+		fmt.Fprintf(&buf, "packages with no tests: %s\n", strings.Join(v.EmptyPackages, ", "))
+	}
+	for _, problem := range v.Problems {
+		fmt.Fprintln(&buf, problem)
+	}
+	if v.OK() {
+		buf.WriteString("well formed\n")
+	} else {
+		fmt.Fprintf(&buf, "%d problem(s)\n", len(v.Problems))
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateTest2JSON(t *testing.T) {
+	cases := []struct {
+		file     string
+		problems []string
+	}{
+		{"validate-ok.json", nil},
+		{"validate-unknown.json", []string{
+			`line 6: example.com/fz TestA: unknown action "attach"`,
+			"line 7: bad JSON record",
+		}},
+		{"validate-unterminated.json", []string{
+			"line 6: example.com/fz TestB: test has no pass, fail or skip record",
+		}},
+		{"validate-duplicate.json", []string{
+			"line 3: example.com/fz TestA: test started again before it ended (started on line 2)",
+		}},
+		{"validate-notime.json", []string{
+			"line 1: 8 record(s) with no timestamp",
+		}},
+		{"validate-nosummary.json", []string{
+			"line 5: example.com/fz: package has no pass, fail or skip record",
+		}},
+	}
+
+	for _, tc := range cases {
+		file, err := os.Open("../_data/in/" + tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := ValidateTest2JSON(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", tc.file, err)
+		}
+
+		if len(v.Problems) != len(tc.problems) {
+			t.Fatalf("%s: problems %v, expected %v", tc.file, v.Problems, tc.problems)
+		}
+		for i, problem := range v.Problems {
+			if !strings.HasPrefix(problem.String(), tc.problems[i]) {
+				t.Fatalf("%s: problem %q, expected %q", tc.file, problem, tc.problems[i])
+			}
+		}
+		if v.OK() != (len(tc.problems) == 0) {
+			t.Fatalf("%s: OK is %v", tc.file, v.OK())
+		}
+	}
+}
+
+func TestValidateCounts(t *testing.T) {
+	file, err := os.Open("../_data/in/validate-ok.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	v, err := ValidateTest2JSON(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Records != 26 || v.Actions["run"] != 4 || v.Actions["fail"] != 3 || v.Actions["skip"] != 2 {
+		t.Fatalf("bad counts: %d records, actions %v", v.Records, v.Actions)
+	}
+	if v.Packages != 2 || v.Tests != 4 {
+		t.Fatalf("%d packages, %d tests, expected 2 and 4", v.Packages, v.Tests)
+	}
+	if len(v.EmptyPackages) != 1 || v.EmptyPackages[0] != "example.com/emp/e" {
+		t.Fatalf("bad empty packages: %v", v.EmptyPackages)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteValidation(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	expected := "records: 26 (fail=3 output=14 pass=1 run=4 skip=2 start=2)\n" +
+		"packages: 2, tests: 4\n" +
+		"packages with no tests: example.com/emp/e\n" +
+		"well formed\n"
+	if buf.String() != expected {
+		t.Fatalf("bad output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
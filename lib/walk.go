@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"errors"
+	"strings"
+)
+
+// SkipChildren is returned by a Walk function to skip subtests of the
+// current test
+var SkipChildren = errors.New("skip children")
+
+// Walk calls fn for every test in suites, in order. Subtests are visited after
+// their parent test. path is the suite (package) name followed by the test
+// name parts (e.g. ["example.com/pkg", "TestA", "sub"] for TestA/sub). If fn
+// returns SkipChildren, subtests of the test are not visited. Any other error
+// stops the walk and is returned by Walk.
+func (s Suites) Walk(fn func(path []string, t *Test) error) error {
+	for _, suite := range s {
+		skipPrefix := ""
+		for _, test := range suite.Tests {
+			if skipPrefix != "" && strings.HasPrefix(test.Name, skipPrefix) {
+				continue
+			}
+			skipPrefix = ""
+
+			path := append([]string{suite.Name}, strings.Split(test.Name, "/")...)
+			err := fn(path, test)
+			if err == SkipChildren {
+				skipPrefix = test.Name + "/"
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Leaves returns the tests in suites that are not parents of subtests
+func (s Suites) Leaves() []*Test {
+	var leaves []*Test
+	s.Walk(func(path []string, t *Test) error {
+		if !t.isParentTest {
+			leaves = append(leaves, t)
+		}
+		return nil
+	})
+	return leaves
+}
+
+// errFound stops Walk in Find
+var errFound = errors.New("found")
+
+// Find returns the test called fullName (suite name and test name joined by
+// "/", e.g. "example.com/pkg/TestA/sub"), or nil if there's no such test
+func (s Suites) Find(fullName string) *Test {
+	var found *Test
+	s.Walk(func(path []string, t *Test) error {
+		if strings.Join(path, "/") == fullName {
+			found = t
+			return errFound
+		}
+		return nil
+	})
+	return found
+}
+
+// TruncateLeaves returns suites with only the first n leaf tests (tests that
+// are not parents of subtests) and their parent tests. Suites left without
+// tests are dropped. n <= 0 means no limit.
+func (s Suites) TruncateLeaves(n int) Suites {
+	if n <= 0 {
+		return s
+	}
+
+	var truncated Suites
+	for _, suite := range s {
+		if n == 0 {
+			break
+		}
+
+		kept := make(map[*Test]bool)
+		ancestors := make(map[string]bool)
+		for _, test := range suite.Tests {
+			if test.isParentTest || n == 0 {
+				continue
+			}
+			kept[test] = true
+			n--
+			for _, prefix := range namePrefixes(test.Name) {
+				ancestors[prefix] = true
+			}
+		}
+
+		var tests []*Test
+		for _, test := range suite.Tests {
+			if kept[test] || (test.isParentTest && ancestors[test.Name]) {
+				tests = append(tests, test)
+			}
+		}
+		if len(tests) == 0 {
+			continue
+		}
+		trimmed := *suite
+		trimmed.Tests = tests
+		truncated = append(truncated, &trimmed)
+	}
+	return truncated
+}
@@ -0,0 +1,121 @@
+package lib
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func walkSuites() Suites {
+	return Suites{
+		{Name: "example.com/a", Tests: []*Test{
+			{Name: "TestA", isParentTest: true},
+			{Name: "TestA/x", isParentTest: true},
+			{Name: "TestA/x/1"},
+			{Name: "TestA/y"},
+			{Name: "TestB"},
+		}},
+		{Name: "example.com/b", Tests: []*Test{
+			{Name: "TestC"},
+		}},
+	}
+}
+
+// walkPaths returns the paths visited by Walk, fn returns the error for each
+// test
+func walkPaths(suites Suites, fn func(t *Test) error) ([]string, error) {
+	var paths []string
+	err := suites.Walk(func(path []string, t *Test) error {
+		paths = append(paths, strings.Join(path, "|"))
+		return fn(t)
+	})
+	return paths, err
+}
+
+func TestWalk(t *testing.T) {
+	paths, err := walkPaths(walkSuites(), func(*Test) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "example.com/a|TestA example.com/a|TestA|x example.com/a|TestA|x|1 " +
+		"example.com/a|TestA|y example.com/a|TestB example.com/b|TestC"
+	if strings.Join(paths, " ") != expected {
+		t.Fatalf("got %q", paths)
+	}
+
+	// Pruning
+	paths, err = walkPaths(walkSuites(), func(t *Test) error {
+		if t.Name == "TestA/x" {
+			return SkipChildren
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 5 || paths[2] != "example.com/a|TestA|y" {
+		t.Fatalf("bad pruned walk: %q", paths)
+	}
+
+	// Early termination
+	stop := errors.New("stop")
+	paths, err = walkPaths(walkSuites(), func(t *Test) error {
+		if t.Name == "TestB" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(paths) != 5 {
+		t.Fatalf("bad stopped walk: %q (%v)", paths, err)
+	}
+
+	// Empty
+	paths, err = walkPaths(nil, func(*Test) error { return nil })
+	if err != nil || len(paths) != 0 {
+		t.Fatalf("bad empty walk: %q (%v)", paths, err)
+	}
+}
+
+func TestLeavesFind(t *testing.T) {
+	suites := walkSuites()
+
+	var names []string
+	for _, test := range suites.Leaves() {
+		names = append(names, test.Name)
+	}
+	if strings.Join(names, " ") != "TestA/x/1 TestA/y TestB TestC" {
+		t.Fatalf("bad leaves: %q", names)
+	}
+
+	if test := suites.Find("example.com/a/TestA/x/1"); test == nil || test.Name != "TestA/x/1" {
+		t.Fatalf("bad find: %v", test)
+	}
+	if test := suites.Find("example.com/a/TestD"); test != nil {
+		t.Fatalf("found missing test: %v", test)
+	}
+	if leaves := (Suites{}).Leaves(); len(leaves) != 0 {
+		t.Fatalf("leaves in empty suites: %v", leaves)
+	}
+}
+
+func TestTruncateLeaves(t *testing.T) {
+	suites := walkSuites()
+	if truncated := suites.TruncateLeaves(0); len(truncated) != len(suites) {
+		t.Fatalf("0 should not truncate")
+	}
+
+	truncated := suites.TruncateLeaves(2)
+	if len(truncated) != 1 {
+		t.Fatalf("got %d suites instead of 1", len(truncated))
+	}
+	var names []string
+	for _, test := range truncated[0].Tests {
+		names = append(names, test.Name)
+	}
+	if strings.Join(names, " ") != "TestA TestA/x TestA/x/1 TestA/y" {
+		t.Fatalf("bad truncated tests: %q", names)
+	}
+	if n := len(suites[0].Tests); n != 5 {
+		t.Fatalf("original suite changed, %d tests", n)
+	}
+}
@@ -0,0 +1,150 @@
+package lib
+
+// XML input (reading back xunit reports)
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type xunitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type xunitResult struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type xunitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []xunitProperty `xml:"properties>property"`
+	Skipped    *xunitResult    `xml:"skipped"`
+	Failure    *xunitResult    `xml:"failure"`
+	Error      *xunitResult    `xml:"error"`
+	SystemOut  string          `xml:"system-out"`
+}
+
+type xunitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Time       string          `xml:"time,attr"`
+	Coverage   string          `xml:"coverage,attr"`
+	Properties []xunitProperty `xml:"properties>property"`
+	Tests      []xunitTestCase `xml:"testcase"`
+}
+
+// trimBody removes the indentation XUnitTemplate adds around element text
+func trimBody(body string) string {
+	const prefix, suffix = "\n        ", "\n      "
+	if strings.HasPrefix(body, prefix) && strings.HasSuffix(body, suffix) && len(body) >= len(prefix)+len(suffix) {
+		return body[len(prefix) : len(body)-len(suffix)]
+	}
+	return strings.TrimSpace(body)
+}
+
+// newXUnitTest returns a Test from xunit testcase
+func newXUnitTest(tc xunitTestCase) *Test {
+	test := &Test{Name: tc.Name, Time: tc.Time, Status: Passed}
+	switch {
+	case tc.Error != nil:
+		test.Status = Errored
+		test.FailureMessage = tc.Error.Message
+		test.Message = trimBody(tc.Error.Body)
+	case tc.Failure != nil:
+		test.Status = Failed
+		test.FailureMessage = tc.Failure.Message
+		test.Message = trimBody(tc.Failure.Body)
+	case tc.Skipped != nil:
+		test.Status = Skipped
+		test.SkipReason = tc.Skipped.Message
+		test.Message = trimBody(tc.Skipped.Body)
+	default:
+		test.Message = tc.SystemOut
+	}
+
+	var bench BenchmarkResult
+	hasBench := false
+	for _, prop := range tc.Properties {
+		switch prop.Name {
+		case "flaky":
+			test.Flaky = prop.Value == "true"
+		case "ns/op":
+			bench.NsPerOp, _ = strconv.ParseFloat(prop.Value, 64)
+			hasBench = true
+		case "B/op":
+			bench.BytesPerOp, _ = strconv.ParseInt(prop.Value, 10, 64)
+		case "allocs/op":
+			bench.AllocsPerOp, _ = strconv.ParseInt(prop.Value, 10, 64)
+		default:
+			test.Properties = append(test.Properties, Property(prop))
+		}
+	}
+	if hasBench {
+		test.Benchmark = &bench
+		bench.Metrics = map[string]float64{
+			"ns/op":     bench.NsPerOp,
+			"B/op":      float64(bench.BytesPerOp),
+			"allocs/op": float64(bench.AllocsPerOp),
+		}
+		// Other metrics are written as properties with a unit name
+		var props []Property
+		for _, prop := range test.Properties {
+			value, err := strconv.ParseFloat(prop.Value, 64)
+			if err == nil && strings.Contains(prop.Name, "/") {
+				bench.Metrics[prop.Name] = value
+				continue
+			}
+			props = append(props, prop)
+		}
+		test.Properties = props
+	}
+	return test
+}
+
+// newXUnitSuite returns a Suite from xunit testsuite
+func newXUnitSuite(xs xunitSuite, suitePrefix string) *Suite {
+	suite := &Suite{Name: suitePrefix + xs.Name, Time: xs.Time, Status: "ok"}
+	if coverage, err := strconv.ParseFloat(xs.Coverage, 64); err == nil {
+		suite.Coverage, suite.HasCoverage = coverage, true
+	}
+	for _, prop := range xs.Properties {
+		suite.Properties = append(suite.Properties, Property(prop))
+	}
+	for _, tc := range xs.Tests {
+		suite.Tests = append(suite.Tests, newXUnitTest(tc))
+	}
+	if suite.NumFailed() > 0 || suite.NumErrors() > 0 {
+		suite.Status = "FAIL"
+	}
+	return suite
+}
+
+// ParseXUnit parses xunit XML (as generated with XUnitTemplate or
+// XMLMultiTemplate), returns a list of suites
+func ParseXUnit(rd io.Reader, suitePrefix string) (Suites, error) {
+	dec := xml.NewDecoder(rd)
+	var suites Suites
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "testsuite" {
+			continue
+		}
+		var xs xunitSuite
+		if err := dec.DecodeElement(&xs, &start); err != nil {
+			return nil, err
+		}
+		suites = append(suites, newXUnitSuite(xs, suitePrefix))
+	}
+	return suites, nil
+}
@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseXUnit(t *testing.T) {
+	for _, name := range []string{"gotest-multi.out", "gotest-mixed-output.out", "gotest-bench.out", "gotest-bench-sub.out", "gotest-panic-test.out"} {
+		filename := "../_data/in/" + name
+		suites, err := loadGotest(filename, t)
+		if err != nil {
+			t.Fatalf("error loading %s - %s", filename, err)
+		}
+
+		var buf bytes.Buffer
+		WriteXML(suites, &buf, XMLMultiTemplate, time.Now())
+		parsed, err := ParseXUnit(&buf, "")
+		if err != nil {
+			t.Fatalf("%s: can't parse XML - %s", name, err)
+		}
+
+		if len(parsed) != len(suites) {
+			t.Fatalf("%s: %d suites, expected %d", name, len(parsed), len(suites))
+		}
+		for i, suite := range suites {
+			got := parsed[i]
+			if got.Name != suite.Name || got.Len() != suite.Len() {
+				t.Fatalf("%s: bad suite %s (%d tests)", name, got.Name, got.Len())
+			}
+			for j, test := range suite.Tests {
+				p := got.Tests[j]
+				if p.Name != test.Name || p.Status != test.Status || p.Elapsed() != test.Elapsed() {
+					t.Fatalf("%s: bad test %+v, expected %+v", name, p, test)
+				}
+				if test.Status != Passed && p.Message != test.Message {
+					t.Fatalf("%s/%s: output %q, expected %q", name, test.Name, p.Message, test.Message)
+				}
+				if (p.Benchmark == nil) != (test.Benchmark == nil) {
+					t.Fatalf("%s/%s: benchmark not parsed", name, test.Name)
+				}
+				if test.Benchmark != nil && !reflect.DeepEqual(p.Benchmark.ExtraMetrics(), test.Benchmark.ExtraMetrics()) {
+					t.Fatalf("%s/%s: metrics %v, expected %v", name, test.Name, p.Benchmark.Metrics, test.Benchmark.Metrics)
+				}
+			}
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -14,47 +16,68 @@ import (
 const (
 	// XUnitTemplate is XML template for xunit style reporting
 	XUnitTemplate string = `
-{{range $suite := .Suites}}  <testsuite name="{{.Name | escape}}" tests="{{.Len}}" errors="0" failures="{{.NumFailed}}" skip="{{.NumSkipped}}">
-{{range  $test := $suite.Tests}}    <testcase classname="{{$suite.Name | escape}}" name="{{$test.Name | escape}}" time="{{$test.Time}}">
-{{if eq $test.Status $.Skipped }}      <skipped/> {{end}}
-{{if eq $test.Status $.Failed }}      <failure type="go.error" message="error">
-        <![CDATA[{{$test.Message}}]]>
-      </failure>{{end}}    </testcase>
+{{range $suite := .Suites}}  <testsuite{{with $.Namespace}} xmlns="{{. | escape}}"{{end}} name="{{.Name | escape}}" tests="{{.Len}}" errors="{{.NumErrors}}" failures="{{.NumFailed}}" skip="{{.NumSkipped}}"{{if .NumDisabled}} disabled="{{.NumDisabled}}"{{end}}{{if .Elapsed}} time="{{.Time | duration}}"{{end}}{{with .Started | timestamp}} timestamp="{{.}}"{{end}}{{if .HasCoverage}} coverage="{{printf "%.3f" .Coverage}}"{{end}}>
+{{if .Properties}}    <properties>
+{{range .Properties}}      <property name="{{.Name | escape}}" value="{{.Value | escape}}"/>
+{{end}}    </properties>
+{{end}}{{range  $test := $suite.Tests}}    <testcase classname="{{$suite.Name | escape}}" name="{{$test.Name | escape}}" time="{{$test.Time | duration}}">
+{{if or $test.Benchmark $test.Flaky $test.Artifacts $test.Properties}}      <properties>
+{{with $test.Benchmark}}        <property name="ns/op" value="{{.NsPerOp}}"/>
+        <property name="B/op" value="{{.BytesPerOp}}"/>
+        <property name="allocs/op" value="{{.AllocsPerOp}}"/>
+{{range .ExtraMetrics}}        <property name="{{.Name | escape}}" value="{{.Value}}"/>
+{{end}}{{end}}{{if $test.Flaky}}        <property name="flaky" value="true"/>
+{{end}}{{range $i, $path := $test.Artifacts}}        <property name="artifact.{{$i}}" value="{{$path | escape}}"/>
+{{end}}{{range $test.Properties}}        <property name="{{.Name | escape}}" value="{{.Value | escape}}"/>
+{{end}}      </properties>
+{{end}}{{if eq $test.Status $.Skipped }}      <skipped message="{{$test.SkipReason | escape}}"{{if $test.Message}}>
+        {{$test.Message | cdata}}
+      </skipped>{{else}}/>{{end}} {{end}}
+{{if eq $test.Status $.Failed }}      <failure type="go.error" message="{{$test.FailureMessage | escape}}">
+        {{$test.Message | cdata}}
+      </failure>{{end}}{{if eq $test.Status $.Errored }}      <error type="go.error" message="{{$test.FailureMessage | escape}}">
+        {{$test.Message | cdata}}
+      </error>{{end}}{{if or $test.Attachments (and $test.Flaky (eq $test.Status $.Passed) $test.Message)}}      <system-out>{{if and $test.Flaky (eq $test.Status $.Passed) $test.Message}}{{$test.Message | cdata}}{{end}}{{range $test.Attachments}}
+{{. | attachment | escape}}{{end}}</system-out>
+{{end}}    </testcase>
 {{end}}  </testsuite>
 {{end}}`
 
 	// XMLMultiTemplate is template when we have multiple suites
 	XMLMultiTemplate string = `
-<testsuites>` + XUnitTemplate + `</testsuites>
+<testsuites{{with .Namespace}} xmlns="{{. | escape}}"{{end}} tests="{{.Len}}" failures="{{.NumFailed}}" errors="{{.NumErrors}}" skipped="{{.NumSkipped}}"{{if .NumDisabled}} disabled="{{.NumDisabled}}"{{end}} time="{{.Time | duration}}"{{with .Summary}} time-mean="{{elapsed .MeanElapsed}}" time-median="{{elapsed .MedianElapsed}}" time-p95="{{elapsed .P95Elapsed}}" time-max="{{elapsed .MaxElapsed}}"{{end}}>{{if .Properties}}
+  <properties>
+{{range .Properties}}    <property name="{{.Name | escape}}" value="{{.Value | escape}}"/>
+{{end}}  </properties>{{end}}` + XUnitTemplate + `</testsuites>
 `
 
 	// XUnitNetTemplate is XML template for xunit.net
 	// see https://xunit.codeplex.com/wikipage?title=XmlFormat
 	XUnitNetTemplate string = `
-<assembly name="{{.Assembly | escape}}"
+<assembly{{with .Namespace}} xmlns="{{. | escape}}"{{end}} name="{{.Assembly | escape}}"
           run-date="{{.RunDate}}" run-time="{{.RunTime}}"
           configFile="none"
-          time="{{.Time}}"
+          time="{{.Time | duration}}"
           total="{{.Len}}"
           passed="{{.NumPassed}}"
-          failed="{{.NumFailed}}"
-          skipped="{{.NumSkipped}}"
+          failed="{{add .NumFailed .NumErrors}}"
+          skipped="{{add .NumSkipped .NumDisabled}}"
           environment="n/a"
           test-framework="golang">
 {{range $suite := .Suites}}
-    <class time="{{.Time}}" name="{{.Name | escape}}"
+    <class time="{{.Time | duration}}" name="{{.Name | escape}}"
   	     total="{{.Len}}"
   	     passed="{{.NumPassed}}"
-  	     failed="{{.NumFailed}}"
-  	     skipped="{{.NumSkipped}}">
+  	     failed="{{add .NumFailed .NumErrors}}"
+  	     skipped="{{add .NumSkipped .NumDisabled}}">
 {{range  $test := $suite.Tests}}
         <test name="{{$test.Name | escape}}"
           type="test"
           method="{{$test.Name | escape}}"
-          result={{if eq $test.Status $.Skipped }}"Skip"{{else if eq $test.Status $.Failed }}"Fail"{{else if eq $test.Status $.Passed }}"Pass"{{end}}
-          time="{{$test.Time}}">
-        {{if eq $test.Status $.Failed }}  <failure exception-type="go.error">
-             <message><![CDATA[{{$test.Message}}]]></message>
+          result={{if eq $test.Status $.Skipped }}"Skip"{{else if or (eq $test.Status $.Failed) (eq $test.Status $.Errored) }}"Fail"{{else if eq $test.Status $.Passed }}"Pass"{{end}}
+          time="{{$test.Time | duration}}">
+        {{if or (eq $test.Status $.Failed) (eq $test.Status $.Errored) }}  <failure exception-type="go.error">
+             <message>{{$test.Message | cdata}}</message>
       	  </failure>
       	{{end}}</test>
 {{end}}
@@ -66,19 +89,32 @@ const (
 
 // TestResults is passed to XML template
 type TestResults struct {
-	Suites     []*Suite
-	Assembly   string
-	RunDate    string
-	RunTime    string
-	Time       string
-	Len        int
-	NumPassed  int
-	NumFailed  int
-	NumSkipped int
+	Suites      []*Suite
+	Assembly    string
+	RunDate     string
+	RunTime     string
+	Time        string
+	Len         int
+	NumPassed   int
+	NumFailed   int
+	NumErrors   int
+	NumSkipped  int
+	NumDisabled int
+	NumOther    int
+	Summary     TestSummary
+	Namespace   string
+	Properties  []Property
 
 	Skipped Status
 	Passed  Status
 	Failed  Status
+	Errored Status
+}
+
+// TotalTimeFormats are the known Options.TotalTime values
+var TotalTimeFormats = map[string]bool{
+	"sum":  true,
+	"wall": true,
 }
 
 // calcTotals calculates grand total for all suites
@@ -87,13 +123,60 @@ func (r *TestResults) calcTotals() {
 	for _, suite := range r.Suites {
 		r.NumPassed += suite.NumPassed()
 		r.NumFailed += suite.NumFailed()
+		r.NumErrors += suite.NumErrors()
 		r.NumSkipped += suite.NumSkipped()
+		r.NumDisabled += suite.NumDisabled()
+		r.NumOther += suite.NumOther()
 
 		suiteTime, _ := strconv.ParseFloat(suite.Time, 64)
 		totalTime += suiteTime
-		r.Time = fmt.Sprintf("%.3f", totalTime)
+		r.Time = FormatSeconds(durationOf(totalTime))
+	}
+	r.Len = r.NumPassed + r.NumSkipped + r.NumDisabled + r.NumFailed + r.NumErrors + r.NumOther
+
+	if Options.TotalTime == "wall" {
+		if wall, ok := wallTime(r.Suites); ok {
+			r.Time = FormatSeconds(wall)
+		}
+	}
+}
+
+// wallTime returns the time from the earliest start to the latest finish of
+// suites, ok is false if some suite has no start or finish time
+func wallTime(suites []*Suite) (wall time.Duration, ok bool) {
+	var start, end time.Time
+	for _, suite := range suites {
+		if suite.Started.IsZero() || suite.Finished.IsZero() {
+			return 0, false
+		}
+		if start.IsZero() || suite.Started.Before(start) {
+			start = suite.Started
+		}
+		if suite.Finished.After(end) {
+			end = suite.Finished
+		}
 	}
-	r.Len = r.NumPassed + r.NumSkipped + r.NumFailed
+	return end.Sub(start), !start.IsZero()
+}
+
+// durationForXML returns the time in seconds (as reported by go test)
+// formatted with Options.DurationFormat. It's returned as is if the time isn't
+// a number.
+func durationForXML(seconds string) string {
+	value, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return seconds
+	}
+	return FormatDuration(durationOf(value), Options.DurationFormat)
+}
+
+// cdataForXML returns in as a CDATA section, "]]>" in in is split between two
+// sections. If Options.EscapeOutput is set, in is XML escaped instead.
+func cdataForXML(in string) (string, error) {
+	if Options.EscapeOutput {
+		return escapeForXML(in)
+	}
+	return "<![CDATA[" + strings.Replace(in, "]]>", "]]]]><![CDATA[>", -1) + "]]>", nil
 }
 
 func escapeForXML(in string) (string, error) {
@@ -104,30 +187,147 @@ func escapeForXML(in string) (string, error) {
 	return w.String(), nil
 }
 
-// WriteXML exits xunit XML of tests to out
+// WriteXML exits xunit XML of tests to out, errors are printed to stdout (see
+// WriteXMLTemplate)
 func WriteXML(suites []*Suite, out io.Writer, xmlTemplate string, testTime time.Time) {
-	testsResult := TestResults{
-		Suites:   suites,
-		Assembly: suites[len(suites)-1].Name,
-		RunDate:  testTime.Format("2006-01-02"),
-		RunTime:  testTime.Format("15:04:05"),
-		Skipped:  Skipped,
-		Passed:   Passed,
-		Failed:   Failed,
+	if err := writeXML(suites, out, xmlTemplate, testTime); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// WriteJUnit writes JUnit XML (with a <testsuites> root element) of suites to
+// w, testTime is the time of the test run
+func WriteJUnit(w io.Writer, suites Suites, testTime time.Time) error {
+	return writeXML(suites, w, XMLMultiTemplate, testTime)
+}
+
+// WriteXMLTemplate writes the XML of suites to w using xmlTemplate (e.g.
+// XUnitTemplate), testTime is the time of the test run
+func WriteXMLTemplate(w io.Writer, suites Suites, xmlTemplate string, testTime time.Time) error {
+	return writeXML(suites, w, xmlTemplate, testTime)
+}
+
+// writeXML writes XML of suites to out using xmlTemplate
+func writeXML(suites []*Suite, out io.Writer, xmlTemplate string, testTime time.Time) error {
+	if len(suites) == 0 {
+		return fmt.Errorf("no suites")
+	}
+	t, err := newXMLTemplate().Parse(xml.Header + xmlTemplate)
+	if err != nil {
+		return fmt.Errorf("error in parse: %v", err)
+	}
+	if err := t.Execute(out, newTestResults(suites, testTime)); err != nil {
+		return fmt.Errorf("error in execute: %v", err)
+	}
+	return nil
+}
+
+// newTestResults returns the template data of suites
+func newTestResults(suites []*Suite, testTime time.Time) *TestResults {
+	testsResult := &TestResults{
+		Suites:     suites,
+		Assembly:   suites[len(suites)-1].Name,
+		RunDate:    testTime.Format("2006-01-02"),
+		RunTime:    testTime.Format("15:04:05"),
+		Skipped:    Skipped,
+		Passed:     Passed,
+		Failed:     Failed,
+		Errored:    Errored,
+		Summary:    Summary(suites),
+		Namespace:  Options.XMLNamespace,
+		Properties: Options.Properties,
 	}
 	testsResult.calcTotals()
-	t := template.New("test template").Funcs(template.FuncMap{
-		"escape": escapeForXML,
+	return testsResult
+}
+
+// newXMLTemplate returns a template with the functions of the XML templates
+func newXMLTemplate() *template.Template {
+	return template.New("test template").Funcs(template.FuncMap{
+		"escape":     escapeForXML,
+		"cdata":      cdataForXML,
+		"add":        func(a, b int) int { return a + b },
+		"duration":   durationForXML,
+		"timestamp":  FormatTimestamp,
+		"attachment": AttachmentMarker,
+		"elapsed": func(d time.Duration) string {
+			return FormatDuration(d, Options.DurationFormat)
+		},
 	})
+}
 
-	t, err := t.Parse(xml.Header + xmlTemplate)
-	if err != nil {
-		fmt.Printf("Error in parse %v\n", err)
-		return
+// emptyElementRE matches an empty element written by xml.Encoder, which
+// doesn't write empty-element tags
+var emptyElementRE = regexp.MustCompile(`<([^\s<>/]+)([^<>]*)></([^\s<>]+)>`)
+
+// Reindent returns XML data re-encoded with xml.Encoder, indented with indent
+// for every nesting level, or without whitespace between elements if compact
+// is true. The XML declaration, CDATA sections and empty-element tags (e.g.
+// <skipped/>) are kept as is.
+func Reindent(data []byte, indent string, compact bool) ([]byte, error) {
+	var out, encoded bytes.Buffer
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	enc := xml.NewEncoder(&encoded)
+	if !compact {
+		enc.Indent("", indent)
 	}
-	err = t.Execute(out, testsResult)
-	if err != nil {
-		fmt.Printf("Error in execute %v\n", err)
-		return
+	// flush moves the encoded XML to out
+	flush := func() error {
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+		out.Write(emptyElementRE.ReplaceAllFunc(encoded.Bytes(), func(tag []byte) []byte {
+			m := emptyElementRE.FindSubmatch(tag)
+			if !bytes.Equal(m[1], m[3]) {
+				return tag
+			}
+			return append(append(append([]byte("<"), m[1]...), m[2]...), "/>"...)
+		}))
+		encoded.Reset()
+		return nil
+	}
+
+	for {
+		start := dec.InputOffset()
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw := data[start:dec.InputOffset()]
+
+		switch t := token.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				// The encoder doesn't end the declaration line
+				out.Write(raw)
+				if !compact {
+					out.WriteByte('\n')
+				}
+				continue
+			}
+		case xml.CharData:
+			if bytes.HasPrefix(raw, []byte("<![CDATA[")) {
+				// The encoder would escape it as text
+				if err := flush(); err != nil {
+					return nil, err
+				}
+				out.Write(raw)
+				continue
+			}
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+		}
+		if err := enc.EncodeToken(token); err != nil {
+			return nil, err
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
 	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
 }
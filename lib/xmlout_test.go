@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCDATA(t *testing.T) {
+	message := "want <nil> got <error>\nx[i]]>0 && y[j]]]>1"
+	suites := Suites{
+		{Name: "pkg", Tests: []*Test{{Name: "TestA", Status: Failed, Message: message}}},
+	}
+
+	for _, escape := range []bool{false, true} {
+		Options.EscapeOutput = escape
+		var buf bytes.Buffer
+		WriteXML(suites, &buf, XUnitTemplate, time.Now())
+		Options.EscapeOutput = false
+
+		if strings.Contains(buf.String(), "<![CDATA[") == escape {
+			t.Fatalf("escape=%v: bad output:\n%s", escape, buf.String())
+		}
+
+		// Check XML is well formed
+		dec := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("escape=%v: bad XML - %s\n%s", escape, err, buf.String())
+			}
+		}
+
+		parsed, err := ParseXUnit(&buf, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parsed[0].Tests[0].Message; got != message {
+			t.Fatalf("escape=%v: message is %q, expected %q", escape, got, message)
+		}
+	}
+}
+
+func TestReindent(t *testing.T) {
+	filename := "../_data/in/gotest-multi.out"
+	suites, err := loadGotest(filename, t)
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+	suites[0].Tests[0].Status = Failed
+	suites[0].Tests[0].Message = "  indented\n\toutput <here>"
+
+	var buf bytes.Buffer
+	WriteXML(suites, &buf, XMLMultiTemplate, time.Now())
+	indented, err := Reindent(buf.Bytes(), "    ", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, err := Reindent(buf.Bytes(), "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(compact) >= len(indented) {
+		t.Fatalf("compact output (%d bytes) not smaller than indented (%d bytes)", len(compact), len(indented))
+	}
+	if !bytes.Contains(indented, []byte("\n    <testsuite ")) {
+		t.Fatalf("bad indentation:\n%s", indented)
+	}
+	if !bytes.Contains(indented, []byte(`        <testcase classname="controllers" name="TestCode" time="0.000"/>`+"\n")) {
+		t.Fatalf("empty element not kept:\n%s", indented)
+	}
+	if _, err := Reindent([]byte("<a><b></a>"), "\t", false); err == nil {
+		t.Fatal("no error for bad XML")
+	}
+	// The only newline should be the one in the CDATA section
+	if strings.Count(strings.TrimSpace(string(compact)), "\n") != 1 {
+		t.Fatalf("newlines in compact output:\n%s", compact)
+	}
+
+	for _, data := range [][]byte{indented, compact} {
+		parsed, err := ParseXUnit(bytes.NewReader(data), "")
+		if err != nil {
+			t.Fatalf("bad XML - %s\n%s", err, data)
+		}
+		if len(parsed) != len(suites) || parsed[0].Len() != suites[0].Len() {
+			t.Fatalf("bad suites in:\n%s", data)
+		}
+		if !bytes.Contains(data, []byte("<![CDATA[  indented\n\toutput <here>]]>")) {
+			t.Fatalf("CDATA changed:\n%s", data)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlSuite is the YAML of a suite in WriteYAML
+type yamlSuite struct {
+	Package    string     `yaml:"package"`
+	Time       float64    `yaml:"time,omitempty"`
+	Started    time.Time  `yaml:"started,omitempty"`
+	Coverage   *float64   `yaml:"coverage,omitempty"`
+	Properties []Property `yaml:"properties,omitempty"`
+	Tests      []*Test    `yaml:"tests,omitempty"`
+}
+
+// WriteYAML writes suites as a YAML list of packages with their tests.
+// Fields with zero values are omitted and times are in seconds.
+func WriteYAML(w io.Writer, suites Suites) error {
+	packages := make([]yamlSuite, 0, len(suites))
+	for _, suite := range suites {
+		pkg := yamlSuite{
+			Package:    suite.Name,
+			Time:       suite.Elapsed().Seconds(),
+			Started:    yamlTime(suite.Started),
+			Properties: suite.Properties,
+			Tests:      suite.Tests,
+		}
+		if suite.HasCoverage {
+			coverage := suite.Coverage
+			pkg.Coverage = &coverage
+		}
+		packages = append(packages, pkg)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(packages); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlTest is Test without its methods, so yamlTestFields can embed it
+type yamlTest Test
+
+// yamlTestFields are the YAML fields of a test, Time is the test time in
+// seconds
+type yamlTestFields struct {
+	yamlTest `yaml:",inline"`
+	Time     float64 `yaml:"time,omitempty"`
+}
+
+// MarshalYAML implements yaml.Marshaler, the time is in seconds. Invalid
+// UTF-8 in the test output is replaced with U+FFFD (yaml encodes it as
+// binary otherwise).
+func (t *Test) MarshalYAML() (interface{}, error) {
+	fields := yamlTestFields{yamlTest: yamlTest(*t), Time: t.Elapsed().Seconds()}
+	fields.Started = yamlTime(t.Started)
+	fields.Message = strings.ToValidUTF8(t.Message, string(utf8.RuneError))
+	fields.FailureMessage = strings.ToValidUTF8(t.FailureMessage, string(utf8.RuneError))
+	return fields, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, it reads the YAML of
+// MarshalYAML
+func (t *Test) UnmarshalYAML(value *yaml.Node) error {
+	var fields yamlTestFields
+	if err := value.Decode(&fields); err != nil {
+		return err
+	}
+	*t = Test(fields.yamlTest)
+	if fields.Time != 0 {
+		t.Time = FormatSeconds(durationOf(fields.Time))
+	}
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler, the status is its Stats key (e.g.
+// "pass")
+func (status Status) MarshalYAML() (interface{}, error) {
+	return StatusName(status), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, it reads a Stats key
+func (status *Status) UnmarshalYAML(value *yaml.Node) error {
+	var name string
+	if err := value.Decode(&name); err != nil {
+		return err
+	}
+	if name == otherStatus {
+		*status = UnknownStatus
+		return nil
+	}
+	for s, sname := range statusNames {
+		if sname == name {
+			*status = s
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status: %q", name)
+}
+
+// yamlTime returns t in Options.Location (UTC if not set)
+func yamlTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	loc := Options.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc)
+}
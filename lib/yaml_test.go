@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteYAML(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/a", Time: "0.500", Coverage: 0.75, HasCoverage: true, Tests: []*Test{
+			{Name: "TestPass", Status: Passed, Time: "0.25"},
+			{Name: "TestFail", Status: Failed, Time: "0", FailureMessage: "boom", Message: "a.go:1: boom"},
+			{Name: "TestSkip", Status: Skipped, SkipReason: "later", Properties: []Property{{"owner", "me"}}},
+			{Name: "BenchmarkJoin", Status: Passed, Time: "1.5", Benchmark: &BenchmarkResult{
+				Metrics: map[string]float64{"ns/op": 243.5, "B/op": 56},
+			}},
+		}},
+		{Name: "example.com/b"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, suites); err != nil {
+		t.Fatal(err)
+	}
+	expected := `- package: example.com/a
+  time: 0.5
+  coverage: 0.75
+  tests:
+    - name: TestPass
+      status: pass
+      time: 0.25
+    - name: TestFail
+      message: 'a.go:1: boom'
+      status: fail
+      failure_message: boom
+    - name: TestSkip
+      status: skip
+      skip_reason: later
+      properties:
+        - name: owner
+          value: me
+    - name: BenchmarkJoin
+      status: pass
+      benchmark:
+        metrics:
+          B/op: 56
+          ns/op: 243.5
+      time: 1.5
+- package: example.com/b
+`
+	if out := buf.String(); out != expected {
+		t.Fatalf("bad YAML:\n%s\nexpected:\n%s", out, expected)
+	}
+
+	buf.Reset()
+	if err := WriteYAML(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != "[]\n" {
+		t.Fatalf("bad YAML for no suites: %q", out)
+	}
+}
+
+func TestWriteYAMLRoundTrip(t *testing.T) {
+	special := "- item: value # not a comment\n\t\"quoted\" \\ back\r\x00\x1b   é 😀 \xff"
+	tests := []*Test{
+		{
+			Name:           "TestA/- case: #1",
+			Status:         Failed,
+			Time:           "0.250",
+			Started:        time.Date(2024, 5, 1, 10, 30, 0, 500, time.UTC),
+			FailureMessage: "got: 1 # want 2",
+			Message:        special,
+			RunCount:       2,
+			Flaky:          true,
+			Artifacts:      []string{"out/a.png"},
+		},
+		{Name: "TestB", Status: Skipped, SkipReason: "'single' and \"double\"", Properties: []Property{{"owner", "@me"}}},
+		{Name: "BenchmarkC", Status: Passed, Benchmark: &BenchmarkResult{
+			N:       1000,
+			NsPerOp: 243.5,
+			Metrics: map[string]float64{"ns/op": 243.5, "x: y": 1},
+		}},
+		{Name: "TestD", Status: UnknownStatus, Incomplete: true},
+	}
+	suites := Suites{{Name: "example.com/a: b", Time: "0.5", Properties: []Property{{"key: #", "- value"}}, Tests: tests}}
+
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, suites); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []struct {
+		Package    string     `yaml:"package"`
+		Time       float64    `yaml:"time"`
+		Properties []Property `yaml:"properties"`
+		Tests      []*Test    `yaml:"tests"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s\n%s", err, buf.String())
+	}
+	if len(decoded) != 1 {
+		t.Fatalf("got %d packages instead of 1", len(decoded))
+	}
+	pkg := decoded[0]
+	if pkg.Package != suites[0].Name || pkg.Time != 0.5 || !reflect.DeepEqual(pkg.Properties, suites[0].Properties) {
+		t.Fatalf("bad package: %+v", pkg)
+	}
+
+	if len(pkg.Tests) != len(tests) {
+		t.Fatalf("got %d tests instead of %d", len(pkg.Tests), len(tests))
+	}
+	for i, test := range pkg.Tests {
+		expected := *tests[i]
+		expected.Message = strings.ToValidUTF8(expected.Message, "�")
+		if !test.Started.Equal(expected.Started) {
+			t.Fatalf("%s: started at %s instead of %s", test.Name, test.Started, expected.Started)
+		}
+		test.Started = expected.Started
+		if !reflect.DeepEqual(*test, expected) {
+			t.Fatalf("bad test:\n%#v\nexpected:\n%#v", *test, expected)
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"log"
+	"io/ioutil"
 	"os"
-	"time"
+	"unicode"
 
 	"github.com/tebeka/go2xunit/lib"
 )
@@ -15,94 +17,96 @@ const (
 	Version = "1.4.10"
 )
 
-// getInput return input io.File from file name, if file name is - it will
-// return os.Stdin
-func getInput(filename string) (*os.File, error) {
-	if filename == "-" || filename == "" {
-		return os.Stdin, nil
-	}
-
-	return os.Open(filename)
-}
-
-// getInput return output io.File from file name, if file name is - it will
-// return os.Stdout
-func getOutput(filename string) (*os.File, error) {
-	if filename == "-" || filename == "" {
-		return os.Stdout, nil
-	}
-
-	return os.Create(filename)
-}
+// Exit codes
+const (
+	exitOK          = 0 // All tests passed (or failures are not checked)
+	exitFailures    = 1 // Failed tests (unless -no-exit-code)
+	exitError       = 2 // Bad arguments or input
+	exitTooSlow     = 3 // Tests or suites took too long (-max-test-time, -max-suite-time)
+	exitInterrupted = 4 // SIGINT or SIGTERM, a partial report was written
+)
 
-// getIO returns input and output streams from file names
-func getIO(inFile, outFile string) (*os.File, io.Writer, error) {
-	input, err := getInput(inFile)
+// parseFile parses the file called name, which is either a go2xunit XML
+// report, "go test -json" output or test output parsed with parse
+func parseFile(parse lib.ParseFunc, name, suitePrefix string) (lib.Suites, error) {
+	data, err := ioutil.ReadFile(name)
 	if err != nil {
-		return nil, nil, fmt.Errorf("can't open %s for reading: %s", inFile, err)
+		return nil, fmt.Errorf("can't open %s for reading: %s", name, err)
 	}
 
-	output, err := getOutput(outFile)
-	if err != nil {
-		return nil, nil, fmt.Errorf("can't open %s for writing: %s", outFile, err)
+	trimmed := bytes.TrimSpace(data)
+	firstLine := trimmed
+	if i := bytes.IndexByte(trimmed, '\n'); i >= 0 {
+		firstLine = trimmed[:i]
 	}
-
-	return input, output, nil
-}
-
-func main() {
-	if args.showVersion {
-		fmt.Printf("go2xunit %s\n", Version)
-		os.Exit(0)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		parse = lib.ParseXUnit
+	case lib.IsTest2JSON(firstLine):
+		parse = lib.ParseTest2JSON
 	}
-
-	// No time ... prefix for error messages
-	log.SetFlags(0)
-
-	if err := validateArgs(); err != nil {
-		log.Fatalf("error: %s", err)
-	}
-
-	input, output, err := getIO(args.inFile, args.outFile)
+	suites, err := parse(bytes.NewReader(data), suitePrefix)
 	if err != nil {
-		log.Fatalf("error: %s", err)
+		return nil, fmt.Errorf("%s: %s", name, err)
 	}
+	return suites, nil
+}
 
-	// We'd like the test time to be the time of the generated file
-	var testTime time.Time
-	stat, err := input.Stat()
-	if err != nil {
-		testTime = time.Now()
-	} else {
-		testTime = stat.ModTime()
+// detectJSON returns true if input is "go test -json" output (its first non
+// empty line is a test2json event), and a reader of all of input. Lines are
+// read only up to the end of the first one, for streamed input.
+func detectJSON(input io.Reader) (bool, io.Reader) {
+	br := bufio.NewReader(input)
+	start := -1 // Start of the first line
+	for n := 1; ; n++ {
+		data, err := br.Peek(n)
+		if err != nil {
+			// Input (or the buffer) ended in the first line
+			return start >= 0 && lib.IsTest2JSON(data[start:]), br
+		}
+		switch c := data[n-1]; {
+		case start < 0 && !unicode.IsSpace(rune(c)):
+			if c != '{' {
+				return false, br
+			}
+			start = n - 1
+		case start >= 0 && c == '\n':
+			return lib.IsTest2JSON(data[start:n]), br
+		}
 	}
+}
 
-	var parse func(rd io.Reader, suiteName string) (lib.Suites, error)
-
-	if args.isGocheck {
-		parse = lib.ParseGocheck
-	} else {
-		parse = lib.ParseGotest
+// appendReport returns suites merged into the XML report in file, results in
+// suites replace results of the same tests in the report. If file doesn't
+// exist suites are returned as is.
+func appendReport(file string, suites lib.Suites) (lib.Suites, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return suites, nil
 	}
-
-	suites, err := parse(input, args.suitePrefix)
+	report, err := parseFile(lib.ParseXUnit, file, "")
 	if err != nil {
-		log.Fatalf("error: %s", err)
-	}
-	if len(suites) == 0 {
-		log.Fatalf("error: no tests found")
-		os.Exit(1)
+		return nil, err
 	}
+	return lib.MergeWithPolicy(report, suites, lib.MergeLatest)
+}
 
-	xmlTemplate := lib.XUnitTemplate
-	if args.xunitnetOut {
-		xmlTemplate = lib.XUnitNetTemplate
-	} else if args.bambooOut || (len(suites) > 1) {
-		xmlTemplate = lib.XMLMultiTemplate
+// markFlaky marks tests that changed status between the previous runs in
+// files and the current one as flaky
+func markFlaky(parse lib.ParseFunc, files []string, suitePrefix string, suites lib.Suites) error {
+	var runs []lib.Suites
+	for _, name := range files {
+		run, err := parseFile(parse, name, suitePrefix)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
 	}
+	runs = append(runs, suites)
 
-	lib.WriteXML(suites, output, xmlTemplate, testTime)
-	if args.fail && suites.HasFailures() {
-		os.Exit(1)
-	}
+	lib.DetectFlaky(runs)
+	return nil
+}
+
+func main() {
+	os.Exit(NewApp(WithInterrupt(notifyInterrupt())).Run(os.Args[1:]))
 }
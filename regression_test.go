@@ -14,10 +14,12 @@ import (
 var (
 	// FIXME
 	ignored = map[string]bool{
-		"gotest-buildfailed.out": true,
-		"gocheck-nofiles.out":    true,
+		"gocheck-nofiles.out": true,
 	}
 
+	// test2json fixtures are of test binaries from example.com/fz
+	test2jsonArgs = []string{"-json", "-package-name", "example.com/fz"}
+
 	xTimeRe = regexp.MustCompile(`run-date="[^"]+" run-time="[^"]+"`)
 	xTime   = []byte(`run-date="2015-06-05" run-time="18:34:41"`)
 )
@@ -37,7 +39,10 @@ func checkRegression(t *testing.T, inFile, outFile string, args []string, fixer
 	cmd := exec.Command("./go2xunit", args...)
 	cmd.Stdin = stdin
 	out, err := cmd.Output()
-	// TODO: Use -fail
+	// Failed tests in the input exit with 1
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == exitFailures {
+		err = nil
+	}
 	if err != nil {
 		t.Fatalf("error running on %s - %s", inFile, err)
 	}
@@ -88,6 +93,40 @@ func iterCheck(t *testing.T, inType, outType string, args []string, fixer fixFun
 	}
 }
 
+func TestExitCode(t *testing.T) {
+	cmd := exec.Command("go", "build")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("can't build - %s", err)
+	}
+
+	inFile := dataPath + "/in/gotest-fail.out"
+	cases := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"-input", inFile}, 1},
+		{[]string{"-input", inFile, "-exit-code"}, 1},
+		{[]string{"-input", inFile, "-no-exit-code"}, 0},
+		{[]string{"-input", inFile, "-exit-code", "-no-exit-code"}, 0},
+		{[]string{"-input", dataPath + "/in/gotest-pass.out"}, 0},
+		{[]string{"-input", dataPath + "/in/no-such-file.out"}, 2},
+	}
+
+	for _, tc := range cases {
+		cmd := exec.Command("./go2xunit", tc.args...)
+		err := cmd.Run()
+		code := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatalf("%v: can't run - %s", tc.args, err)
+		}
+		if code != tc.code {
+			t.Errorf("%v: exit code %d, expected %d", tc.args, code, tc.code)
+		}
+	}
+}
+
 func TestRegression(t *testing.T) {
 	cmd := exec.Command("go", "build")
 	if err := cmd.Run(); err != nil {
@@ -96,6 +135,8 @@ func TestRegression(t *testing.T) {
 
 	iterCheck(t, "gotest", "xunit", nil, nil)
 	iterCheck(t, "gocheck", "xunit", []string{"-gocheck"}, nil)
+	iterCheck(t, "test2json", "xunit", test2jsonArgs, nil)
 	iterCheck(t, "gotest", "xunit.net", []string{"-xunitnet"}, fixXUnit)
 	iterCheck(t, "gocheck", "xunit.net", []string{"-gocheck", "-xunitnet"}, fixXUnit)
+	iterCheck(t, "test2json", "xunit.net", append(test2jsonArgs, "-xunitnet"), fixXUnit)
 }
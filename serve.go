@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// serveShutdownTimeout is the time -serve waits for requests to finish when it
+// stops
+const serveShutdownTimeout = 5 * time.Second
+
+// apiSuite is a package in the /api/tests JSON of -serve
+type apiSuite struct {
+	Package string    `json:"package"`
+	Time    float64   `json:"time"`
+	Tests   []apiTest `json:"tests"`
+}
+
+// apiTest is a test in the /api/tests JSON of -serve
+type apiTest struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Time    float64 `json:"time"`
+	Message string  `json:"message,omitempty"`
+}
+
+// reportServer serves the report of suites over HTTP
+type reportServer struct {
+	mu       sync.RWMutex
+	suites   lib.Suites
+	testTime time.Time
+}
+
+// set replaces the served report
+func (rs *reportServer) set(suites lib.Suites, testTime time.Time) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.suites, rs.testTime = suites, testTime
+}
+
+// get returns the served report
+func (rs *reportServer) get() (lib.Suites, time.Time) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.suites, rs.testTime
+}
+
+// handler returns the HTTP handler of the report: the HTML report at /, the
+// JUnit XML at /junit.xml, the tests as JSON at /api/tests and the output of
+// a test at /test/<package>/<name>
+func (rs *reportServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", rs.serveIndex)
+	mux.HandleFunc("/junit.xml", rs.serveJUnit)
+	mux.HandleFunc("/api/tests", rs.serveAPI)
+	mux.HandleFunc("/test/", rs.serveTest)
+	return mux
+}
+
+func (rs *reportServer) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	suites, testTime := rs.get()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	lib.WriteHTML(w, suites, testTime)
+}
+
+func (rs *reportServer) serveJUnit(w http.ResponseWriter, r *http.Request) {
+	suites, testTime := rs.get()
+	w.Header().Set("Content-Type", "application/xml")
+	lib.WriteJUnit(w, suites, testTime)
+}
+
+func (rs *reportServer) serveAPI(w http.ResponseWriter, r *http.Request) {
+	suites, _ := rs.get()
+	tree := []apiSuite{}
+	for _, suite := range suites {
+		as := apiSuite{Package: suite.Name, Time: suite.Elapsed().Seconds(), Tests: []apiTest{}}
+		for _, test := range suite.Tests {
+			as.Tests = append(as.Tests, apiTest{
+				Name:    test.Name,
+				Status:  lib.StatusName(test.Status),
+				Time:    test.Elapsed().Seconds(),
+				Message: test.Message,
+			})
+		}
+		tree = append(tree, as)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+// serveTest serves the output of a test, package paths and test names can
+// have slashes so the longest package that matches the path is used
+func (rs *reportServer) serveTest(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/test/")
+	suites, _ := rs.get()
+	var found *lib.Test
+	pkgLen := -1
+	for _, suite := range suites {
+		if len(suite.Name) <= pkgLen || !strings.HasPrefix(path, suite.Name+"/") {
+			continue
+		}
+		name := path[len(suite.Name)+1:]
+		for _, test := range suite.Tests {
+			if test.Name == name {
+				found, pkgLen = test, len(suite.Name)
+				break
+			}
+		}
+	}
+	if found == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, found.Message)
+}
+
+// loadReport parses the input into rs, the tests are processed and named as
+// in the report run writes
+func (app *App) loadReport(rs *reportServer, args *cmdArgs) error {
+	input, err := app.getInput(args)
+	if err != nil {
+		return fmt.Errorf("can't open %s for reading: %s", args.inFile, err)
+	}
+	if closer, ok := input.(io.Closer); ok && input != app.stdin {
+		defer closer.Close()
+	}
+
+	parse := args.parseFunc()
+	inputParse, input := args.inputParseFunc(input)
+	suites, err := app.parse(inputParse, input, args)
+	if err != nil {
+		return err
+	}
+	if len(suites) == 0 {
+		return fmt.Errorf("no tests found")
+	}
+	if suites, err = app.process(args, parse, suites); err != nil {
+		return err
+	}
+
+	report := suites.TruncateLeaves(args.maxTests)
+	if args.format == "xunit" && !args.rawNames {
+		report.SanitizeNames(args.maxNameLength)
+	}
+	rs.set(report, inputTime(input).In(lib.Options.Location))
+	return nil
+}
+
+// serve parses the input and serves the report on the -serve address until
+// interrupted (or app.stop is closed), then it shuts the server down. With
+// -watch (or -follow) the input is parsed again whenever it changes.
+func (app *App) serve(args *cmdArgs) int {
+	setOptions(args)
+	rs := &reportServer{}
+	if err := app.loadReport(rs, args); err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+
+	listener, err := net.Listen("tcp", args.serve)
+	if err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+	server := &http.Server{Handler: rs.handler()}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(listener) }()
+	app.log.Printf("serving report on http://%s", listener.Addr())
+
+	var ticks <-chan time.Time
+	if args.watch {
+		ticker := time.NewTicker(app.watchInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	last, _ := os.Stat(args.inFile)
+
+loop:
+	for {
+		select {
+		case err := <-done:
+			app.log.Printf("error: %s", err)
+			return exitError
+		case <-app.stop:
+			break loop
+		case <-app.interrupt:
+			break loop
+		case <-ticks:
+			info, err := os.Stat(args.inFile)
+			if err != nil || (last != nil && info.Size() == last.Size() && info.ModTime().Equal(last.ModTime())) {
+				continue
+			}
+			last = info
+			if err := app.loadReport(rs, args); err != nil {
+				app.log.Printf("error: %s", err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		app.log.Printf("error: %s", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// httpGet returns the status code and body of url
+func httpGet(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestReportServer(t *testing.T) {
+	input := "=== RUN   TestA\n--- PASS: TestA (0.01s)\n=== RUN   TestB\n=== RUN   TestB/x/y\n" +
+		"--- FAIL: TestB (0.00s)\n    --- FAIL: TestB/x/y (0.00s)\n        b_test.go:3: oops\n" +
+		"FAIL\nFAIL\texample.com/pkg\t0.010s\n"
+	suites, err := lib.ParseGotest(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &reportServer{}
+	rs.set(suites, time.Now())
+	server := httptest.NewServer(rs.handler())
+	defer server.Close()
+
+	code, body := httpGet(t, server.URL+"/")
+	if code != http.StatusOK || !strings.Contains(body, "<h2>example.com/pkg</h2>") ||
+		!strings.Contains(body, "3 tests, 2 failed") {
+		t.Fatalf("/: %d\n%s", code, body)
+	}
+
+	code, body = httpGet(t, server.URL+"/junit.xml")
+	if code != http.StatusOK {
+		t.Fatalf("/junit.xml: %d\n%s", code, body)
+	}
+	parsed, err := lib.ParseXUnit(strings.NewReader(body), "")
+	if err != nil || len(parsed) != 1 || parsed[0].Len() != 3 {
+		t.Fatalf("/junit.xml: bad report (%v)\n%s", err, body)
+	}
+
+	code, body = httpGet(t, server.URL+"/api/tests")
+	var tree []apiSuite
+	if err := json.Unmarshal([]byte(body), &tree); err != nil || code != http.StatusOK {
+		t.Fatalf("/api/tests: %d %v\n%s", code, err, body)
+	}
+	if len(tree) != 1 || tree[0].Package != "example.com/pkg" || len(tree[0].Tests) != 3 ||
+		tree[0].Tests[0].Status != "pass" || tree[0].Tests[2].Status != "fail" {
+		t.Fatalf("/api/tests: bad tree %+v", tree)
+	}
+
+	code, body = httpGet(t, server.URL+"/test/example.com/pkg/TestB/x/y")
+	if code != http.StatusOK || !strings.Contains(body, "b_test.go:3: oops") {
+		t.Fatalf("/test/: %d\n%s", code, body)
+	}
+	for _, path := range []string{"/test/example.com/pkg/TestC", "/test/example.com/TestA", "/missing"} {
+		if code, _ = httpGet(t, server.URL+path); code != http.StatusNotFound {
+			t.Fatalf("%s: %d, expected %d", path, code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestAppServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "test.out")
+	const first = "=== RUN   TestA\n--- PASS: TestA (0.00s)\nPASS\nok  \tpkg\t0.01s\n"
+	if err := ioutil.WriteFile(input, []byte(first), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A free port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	var stderr bytes.Buffer
+	stop := make(chan struct{})
+	app := NewApp(WithIO(strings.NewReader(""), ioutil.Discard, &stderr))
+	app.watchInterval = 10 * time.Millisecond
+	app.stop = stop
+	done := make(chan int)
+	argv := []string{"-serve", addr, "-follow", "-input", input,
+		"-exclude-pkg", "pkg3$", "-suite-name-prefix", "ci/"}
+	go func() { done <- app.Run(argv) }()
+
+	// waitFor waits for the JUnit XML to contain text, and returns it
+	waitFor := func(text string) string {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if resp, err := http.Get("http://" + addr + "/junit.xml"); err == nil {
+				data, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if strings.Contains(string(data), text) {
+					return string(data)
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%q not served", text)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(`name="TestA"`)
+
+	file, err := os.OpenFile(input, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteString("=== RUN   TestB\n--- PASS: TestB (0.00s)\nPASS\nok  \tpkg2\t0.01s\n" +
+		"=== RUN   TestC\n--- PASS: TestC (0.00s)\nPASS\nok  \tpkg3\t0.01s\n")
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml := waitFor(`name="TestB"`)
+	if !strings.Contains(xml, `name="ci/pkg2"`) || strings.Contains(xml, "TestC") {
+		t.Fatalf("-suite-name-prefix or -exclude-pkg not applied:\n%s", xml)
+	}
+
+	close(stop)
+	select {
+	case code := <-done:
+		if code != exitOK {
+			t.Fatalf("exit code %d, expected %d", code, exitOK)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+	if _, err := http.Get("http://" + addr + "/"); err == nil {
+		t.Fatal("server still running")
+	}
+}
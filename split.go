@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// indexFile is the name of the report files index in -output-dir
+const indexFile = "index.json"
+
+// reportFile is an entry in the report files index
+type reportFile struct {
+	Package  string `json:"package"`
+	File     string `json:"file"`
+	Tests    int    `json:"tests"`
+	Failures int    `json:"failures"`
+	Errors   int    `json:"errors"`
+	Skipped  int    `json:"skipped"`
+}
+
+// reportFileName returns a file name for the report of package pkg that is
+// not in used (names are compared case insensitive), and adds it to used.
+func reportFileName(pkg string, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':':
+			return '_'
+		}
+		return r
+	}, pkg)
+	if base == "" {
+		base = "package"
+	}
+
+	name := base + ".xml"
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d.xml", base, i)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// writeSplit writes a report file per suite to args.outputDir, and an index
+// of the files
+func writeSplit(args *cmdArgs, suites lib.Suites, testTime time.Time) error {
+	if err := os.MkdirAll(args.outputDir, 0755); err != nil {
+		return err
+	}
+
+	used := map[string]bool{strings.ToLower(indexFile): true}
+	var index []reportFile
+	for _, suite := range suites {
+		var buf bytes.Buffer
+		if err := writeReport(&buf, args, lib.Suites{suite}, testTime); err != nil {
+			return err
+		}
+		name := reportFileName(suite.Name, used)
+		if err := writeFileAtomic(filepath.Join(args.outputDir, name), buf.Bytes()); err != nil {
+			return err
+		}
+		index = append(index, reportFile{
+			Package:  suite.Name,
+			File:     name,
+			Tests:    suite.Len(),
+			Failures: suite.NumFailed(),
+			Errors:   suite.NumErrors(),
+			Skipped:  suite.NumSkipped(),
+		})
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(args.outputDir, indexFile), append(data, '\n'))
+}
+
+// shardFileName returns the name of the n'th (1 based) shard of the report
+// file called name (e.g. report-001.xml for report.xml)
+func shardFileName(name string, n int) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// chunkSuites splits suites to chunks of at most maxCases tests and about
+// maxBytes as measured by size (0 means no limit). A suite split between
+// chunks is repeated in each of them with part of the tests, its time is
+// reported only in the first one.
+func chunkSuites(suites lib.Suites, maxCases, maxBytes int, size func(lib.Suites) int) []lib.Suites {
+	var chunks []lib.Suites
+	var chunk lib.Suites
+	nCases, nBytes := 0, 0
+
+	for _, suite := range suites {
+		var part *lib.Suite
+		started := false // Some of the suite tests are in previous chunks
+		suiteBytes := 0
+		if maxBytes > 0 {
+			header := *suite
+			header.Tests = nil
+			suiteBytes = size(lib.Suites{&header})
+		}
+
+		newPart := func() {
+			p := *suite
+			p.Tests = nil
+			if started {
+				p.Time = ""
+			}
+			part = &p
+			chunk = append(chunk, part)
+			nBytes += suiteBytes
+		}
+		flush := func() {
+			if len(part.Tests) == 0 {
+				chunk = chunk[:len(chunk)-1]
+			}
+			chunks = append(chunks, chunk)
+			chunk = nil
+			nCases, nBytes = 0, 0
+		}
+
+		newPart()
+		for _, test := range suite.Tests {
+			testBytes := 0
+			if maxBytes > 0 {
+				one := *suite
+				one.Tests = []*lib.Test{test}
+				testBytes = size(lib.Suites{&one}) - suiteBytes
+			}
+
+			full := (maxCases > 0 && nCases+1 > maxCases) ||
+				(maxBytes > 0 && nBytes+testBytes > maxBytes)
+			if full && nCases > 0 {
+				flush()
+				newPart()
+			}
+			part.Tests = append(part.Tests, test)
+			started = true
+			nCases++
+			nBytes += testBytes
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// writeShards writes the report of suites to numbered files named after
+// args.outFile, split by args.maxCases and args.maxBytes
+func writeShards(args *cmdArgs, suites lib.Suites, testTime time.Time) error {
+	size := func(suites lib.Suites) int {
+		var buf bytes.Buffer
+		writeReport(&buf, args, suites, testTime)
+		return buf.Len()
+	}
+
+	for i, chunk := range chunkSuites(suites, args.maxCases, args.maxBytes, size) {
+		var buf bytes.Buffer
+		if err := writeReport(&buf, args, chunk, testTime); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(shardFileName(args.outFile, i+1), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusFiles are the report files written by -split-by-status and the
+// statuses of the tests in each of them
+var statusFiles = []struct {
+	name     string
+	statuses []lib.Status
+}{
+	{"passed.xml", []lib.Status{lib.Passed}},
+	{"failed.xml", []lib.Status{lib.Failed, lib.Errored}},
+	{"skipped.xml", []lib.Status{lib.Skipped}},
+}
+
+// emptyReport is the report written when there are no tests
+const emptyReport = xml.Header + "<testsuites tests=\"0\"/>\n"
+
+// suitesWithStatus returns copies of suites with only tests in one of
+// statuses, suites with no such tests are dropped
+func suitesWithStatus(suites lib.Suites, statuses []lib.Status) lib.Suites {
+	var out lib.Suites
+	for _, suite := range suites {
+		var tests []*lib.Test
+		for _, test := range suite.Tests {
+			for _, status := range statuses {
+				if test.Status == status {
+					tests = append(tests, test)
+					break
+				}
+			}
+		}
+		if len(tests) == 0 {
+			continue
+		}
+		part := *suite
+		part.Tests = tests
+		out = append(out, &part)
+	}
+	return out
+}
+
+// writeByStatus writes passed, failed (and errored) and skipped tests to
+// separate report files in dir, each with a <testsuites> root
+func writeByStatus(dir string, args *cmdArgs, suites lib.Suites, testTime time.Time) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	multi := *args
+	multi.bambooOut, multi.xunitnetOut = true, false
+	for _, file := range statusFiles {
+		var buf bytes.Buffer
+		if selected := suitesWithStatus(suites, file.statuses); len(selected) > 0 {
+			if err := writeReport(&buf, &multi, selected, testTime); err != nil {
+				return err
+			}
+		} else {
+			buf.WriteString(emptyReport)
+		}
+		if err := writeFileAtomic(filepath.Join(dir, file.name), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+func TestReportFileName(t *testing.T) {
+	used := make(map[string]bool)
+	names := []string{
+		reportFileName("example.com/a/b", used),
+		reportFileName("example.com/a_b", used),
+		reportFileName("example.com_a_b", used),
+		reportFileName("EXAMPLE.com/a/b", used),
+	}
+	expected := []string{
+		"example.com_a_b.xml",
+		"example.com_a_b-2.xml",
+		"example.com_a_b-3.xml",
+		"EXAMPLE.com_a_b-4.xml",
+	}
+	for i, name := range names {
+		if name != expected[i] {
+			t.Fatalf("got %q, expected %q", names, expected)
+		}
+	}
+}
+
+func TestAppSplit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-last-suite.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, stderr := runApp(t, string(data), "-output-dir", dir, "-split", "package")
+	if code != exitFailures {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if out != "" {
+		t.Fatalf("output written to stdout:\n%s", out)
+	}
+
+	indexData, err := ioutil.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index []reportFile
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index) < 2 {
+		t.Fatalf("expected several packages, got %+v", index)
+	}
+
+	for _, entry := range index {
+		report, err := ioutil.ReadFile(filepath.Join(dir, entry.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(report), "<testsuite "); n != 1 {
+			t.Fatalf("%s: %d suites", entry.File, n)
+		}
+		if !strings.Contains(string(report), `name="`+entry.Package+`"`) {
+			t.Fatalf("%s: package %s not found", entry.File, entry.Package)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(index)+1 {
+		t.Fatalf("%d files in output directory, expected %d", len(files), len(index)+1)
+	}
+}
+
+func TestAppShards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := ioutil.ReadFile(dataPath + "/in/gotest-last-suite.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := lib.ParseGotest(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := source.Stats()
+
+	limits := [][]string{
+		{"-max-cases-per-file", "1"},
+		{"-max-cases-per-file", "2"},
+		{"-max-bytes-per-file", "600"},
+	}
+	for _, limit := range limits {
+		prefix := filepath.Join(dir, strings.TrimLeft(limit[0], "-")+limit[1])
+		args := append([]string{"-output", prefix + ".xml"}, limit...)
+		if code, _, stderr := runApp(t, string(data), args...); code != exitFailures {
+			t.Fatalf("%v: exit code %d: %s", limit, code, stderr)
+		}
+
+		files, err := filepath.Glob(prefix + "-*.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) < 2 {
+			t.Fatalf("%v: report not split: %v", limit, files)
+		}
+
+		stats := make(map[string]int)
+		for _, name := range files {
+			suites, err := parseFile(lib.ParseGotest, name, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for status, n := range suites.Stats() {
+				stats[status] += n
+			}
+		}
+		for status, n := range expected {
+			if stats[status] != n {
+				t.Fatalf("%v: %d %s tests in shards, expected %d", limit, stats[status], status, n)
+			}
+		}
+	}
+}
+
+func TestChunkSuites(t *testing.T) {
+	suites := lib.Suites{
+		{Name: "a", Time: "1.0", Tests: []*lib.Test{{Name: "A1"}, {Name: "A2"}, {Name: "A3"}}},
+		{Name: "b", Time: "2.0", Tests: []*lib.Test{{Name: "B1"}}},
+	}
+
+	chunks := chunkSuites(suites, 2, 0, nil)
+	if len(chunks) != 2 {
+		t.Fatalf("%d chunks, expected 2", len(chunks))
+	}
+	first, second := chunks[0], chunks[1]
+	if len(first) != 1 || first[0].Len() != 2 || first[0].Time != "1.0" {
+		t.Fatalf("bad first chunk: %+v", first)
+	}
+	if len(second) != 2 || second[0].Len() != 1 || second[0].Time != "" || second[1].Time != "2.0" {
+		t.Fatalf("bad second chunk: %+v", second)
+	}
+}
+
+func TestAppSplitByStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const input = "=== RUN   TestA\n--- PASS: TestA (0.00s)\n=== RUN   TestB\n--- FAIL: TestB (0.00s)\n=== RUN   TestC\n--- PASS: TestC (0.00s)\nFAIL\nFAIL\tpkg\t0.01s\n"
+	if code, _, stderr := runApp(t, input, "-split-by-status", dir); code != exitFailures {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	expected := map[string][]string{
+		"passed.xml":  {"TestA", "TestC"},
+		"failed.xml":  {"TestB"},
+		"skipped.xml": nil,
+	}
+	for name, tests := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report struct {
+			XMLName xml.Name `xml:"testsuites"`
+			Tests   int      `xml:"tests,attr"`
+			Cases   []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"testsuite>testcase"`
+		}
+		if err := xml.Unmarshal(data, &report); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if report.Tests != len(tests) || len(report.Cases) != len(tests) {
+			t.Fatalf("%s: bad tests:\n%s", name, data)
+		}
+		for i, tc := range report.Cases {
+			if tc.Name != tests[i] {
+				t.Fatalf("%s: got %s, expected %s", name, tc.Name, tests[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// sendRetries is the number of retries of a request (e.g. -upload-url PUT)
+// that failed with a 5xx status
+const sendRetries = 3
+
+// sendBackoff is the wait before the first retry of a request, it doubles
+// with every retry
+var sendBackoff = time.Second
+
+// uploadOutput is an output that is also written to a temporary file,
+// commitOutput commits w and then uploads the file. Close removes the file.
+type uploadOutput struct {
+	w     io.Writer
+	file  *os.File
+	url   string
+	token string
+}
+
+// newUploadOutput returns an output to w that is uploaded to url with token
+// (if not empty) as a Bearer token
+func newUploadOutput(w io.Writer, url, token string) (*uploadOutput, error) {
+	file, err := ioutil.TempFile("", "go2xunit-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	return &uploadOutput{w: w, file: file, url: url, token: token}, nil
+}
+
+// Write implements io.Writer
+func (u *uploadOutput) Write(data []byte) (int, error) {
+	if _, err := u.file.Write(data); err != nil {
+		return 0, err
+	}
+	return u.w.Write(data)
+}
+
+// Commit commits the underlying output and uploads the temporary file
+func (u *uploadOutput) Commit() error {
+	if err := commitOutput(u.w); err != nil {
+		return err
+	}
+	body, err := ioutil.ReadFile(u.file.Name())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postTimeout)
+	defer cancel()
+	return putXML(ctx, u.url, u.token, body)
+}
+
+// Close removes the temporary file
+func (u *uploadOutput) Close() error {
+	u.file.Close()
+	return os.Remove(u.file.Name())
+}
+
+// putXML puts an XML body to url, with token (if not empty) as a Bearer token
+func putXML(ctx context.Context, url, token string, body []byte) error {
+	header := http.Header{"Content-Type": {"application/xml"}}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return sendRetrying(ctx, http.MethodPut, url, header, body)
+}
+
+// sendRetrying sends a request with body to url, retrying with exponential
+// backoff on 5xx responses. Other responses than 2xx are errors.
+func sendRetrying(ctx context.Context, method, url string, header http.Header, body []byte) error {
+	backoff := sendBackoff
+	for retry := 0; ; retry++ {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("can't send to %s: %s", url, err)
+		}
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode < 500 || retry == sendRetries:
+			return fmt.Errorf("%s %s: %s %s", method, url, resp.Status, bytes.TrimSpace(msg))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("can't send to %s: %s", url, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
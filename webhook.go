@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// readWebhookTemplate reads the -webhook-template file
+func readWebhookTemplate(filename string) (*template.Template, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't open %s for reading: %s", filename, err)
+	}
+	tmpl, err := template.New(filename).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return tmpl, nil
+}
+
+// webhookPayload returns the -webhook payload of suites: the lib.RunSummary as
+// JSON, or executed with tmpl if it's not nil
+func webhookPayload(suites lib.Suites, args *cmdArgs, tmpl *template.Template) ([]byte, error) {
+	run := lib.NewRunSummary(suites, args.summaryFailures, args.props)
+	if tmpl == nil {
+		return json.Marshal(run)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, run); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// postWebhook posts the summary of suites to -webhook, retrying on 5xx
+// responses for up to -webhook-timeout
+func postWebhook(suites lib.Suites, args *cmdArgs, tmpl *template.Template) error {
+	body, err := webhookPayload(suites, args, tmpl)
+	if err != nil {
+		return fmt.Errorf("webhook payload: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), args.webhookTimeout)
+	defer cancel()
+	header := http.Header{"Content-Type": {"application/json"}}
+	return sendRetrying(ctx, http.MethodPost, args.webhook, header, body)
+}